@@ -0,0 +1,114 @@
+/*
+ * Copyright 2020 Aletheia Ware LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"github.com/AletheiaWareLLC/bcgo"
+	"github.com/AletheiaWareLLC/labfynego/ui/edit"
+	"github.com/AletheiaWareLLC/labgo"
+	"log"
+	"os"
+)
+
+// Replays the deltas of every file in the given experiments into sequence records.
+func main() {
+	if len(os.Args) < 2 {
+		log.Fatal("Usage: migrate <experiment-id>...")
+	}
+
+	// Load config files (if any)
+	err := bcgo.LoadConfig()
+	if err != nil {
+		log.Fatalf("Could not load config: %v", err)
+	}
+
+	// Get root directory
+	rootDir, err := bcgo.GetRootDirectory()
+	if err != nil {
+		log.Fatalf("Could not get root directory: %v", err)
+	}
+
+	// Get cache directory
+	cacheDir, err := bcgo.GetCacheDirectory(rootDir)
+	if err != nil {
+		log.Fatalf("Could not get cache directory: %v", err)
+	}
+
+	// Create file cache
+	cache, err := bcgo.NewFileCache(cacheDir)
+	if err != nil {
+		log.Fatalf("Could not create file cache: %v", err)
+	}
+
+	// Create network of peers
+	network := bcgo.NewTCPNetwork()
+
+	// Create node
+	node, err := bcgo.GetNode(rootDir, cache, network)
+	if err != nil {
+		log.Fatalf("Could not create node: %v", err)
+	}
+
+	// Create listener
+	listener := &bcgo.PrintingMiningListener{Output: os.Stdout}
+
+	for _, experimentId := range os.Args[1:] {
+		paths := open(node, labgo.OpenPathChannel(experimentId))
+		entries, err := edit.ReadPathEntries(paths, node.Cache, node.Network)
+		if err != nil {
+			log.Fatalf("Could not read paths: %v", err)
+		}
+		changes := open(node, edit.OpenChangeChannel(experimentId))
+		changeEntries, err := edit.ReadPathEntries(changes, node.Cache, node.Network)
+		if err != nil {
+			log.Fatalf("Could not read changes: %v", err)
+		}
+		files, _, err := edit.ResolvePathEntries(paths.Name, entries, changes.Name, changeEntries)
+		if err != nil {
+			log.Fatalf("Could not resolve paths: %v", err)
+		}
+		for fileId := range files {
+			count, err := edit.MigrateDeltas(node, listener, open(node, labgo.OpenFileChannel(fileId)))
+			if err != nil {
+				log.Fatalf("Could not migrate file: %v", err)
+			}
+			log.Println("Migrated", fileId, count)
+		}
+		// Record the format, so collaborators write sequence records to new files too
+		if format, latest := edit.ResolveFormat(paths.Name, entries, changes.Name, changeEntries); format != edit.FORMAT_SEQUENCE {
+			if err := edit.SetFormat(node, listener, changes, edit.FORMAT_SEQUENCE, latest); err != nil {
+				log.Fatalf("Could not set format: %v", err)
+			}
+		}
+	}
+}
+
+func open(node *bcgo.Node, channel *bcgo.Channel) *bcgo.Channel {
+	// Load channel
+	if err := channel.LoadCachedHead(node.Cache); err != nil {
+		log.Println("Could not load head from cache:", err)
+	}
+	if node.Network != nil {
+		// Pull channel from network
+		if err := channel.Pull(node.Cache, node.Network); err != nil {
+			log.Println("Could not load head from network:", err)
+		}
+	}
+	// Add channel to node
+	node.AddChannel(channel)
+	return channel
+}
@@ -6,9 +6,11 @@ require (
 	fyne.io/fyne v1.2.5-0.20200518160709-553c7a485345
 	github.com/AletheiaWareLLC/bcfynego v0.0.0-20200519172921-383c03aa34eb
 	github.com/AletheiaWareLLC/bcgo v0.0.0-20200516190548-459c1abf38b9
+	github.com/AletheiaWareLLC/cryptogo v0.0.0-20200516185501-ee82a4f19582
 	github.com/AletheiaWareLLC/labclientgo v0.0.0-20200519173038-3cf6195d267b
 	github.com/AletheiaWareLLC/labgo v0.0.0-20200517022000-55483edbae57
 	github.com/golang/protobuf v1.4.2
 	golang.org/x/net v0.0.0-20200519113804-d87ec0cfa476 // indirect
 	golang.org/x/sys v0.0.0-20200519105757-fe76b779f299 // indirect
+	google.golang.org/protobuf v1.23.0
 )
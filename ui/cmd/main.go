@@ -35,7 +35,7 @@ func main() {
 		fyne.NewContainerWithLayout(layout.NewCenterLayout(), account.NewSignUp().CanvasObject()),
 		fyne.NewContainerWithLayout(layout.NewCenterLayout(), edit.NewEditor()),
 		fyne.NewContainerWithLayout(layout.NewCenterLayout(), edit.NewDeltaEditor(nil)),
-		fyne.NewContainerWithLayout(layout.NewCenterLayout(), edit.NewChannelEditor(nil, nil, nil)),
+		fyne.NewContainerWithLayout(layout.NewCenterLayout(), edit.NewChannelEditor(nil, nil, nil, nil)),
 		fyne.NewContainerWithLayout(layout.NewCenterLayout(), experiment.NewExperiment(nil, nil, nil, nil, nil, nil).CanvasObject()),
 		fyne.NewContainerWithLayout(layout.NewCenterLayout(), experiment.NewCreateExperiment(w).CanvasObject()),
 		fyne.NewContainerWithLayout(layout.NewCenterLayout(), experiment.NewJoinExperiment().CanvasObject()),
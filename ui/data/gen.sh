@@ -19,6 +19,9 @@ set -x
 
 fyne bundle -name Logo -package data lab.svg > icon.go
 fyne bundle -append -name LogoUnmasked -package data lab-unmasked.svg >> icon.go
+fyne bundle -append -name FileCodeIcon -package data file-code.svg >> icon.go
+fyne bundle -append -name FileImageIcon -package data file-image.svg >> icon.go
+fyne bundle -append -name FileTextIcon -package data file-text.svg >> icon.go
 #fyne bundle -append -name FileNewIcon -package data file-new.svg >> icon.go
 #fyne bundle -append -name CloudSaveIcon -package data cloud-save.svg >> icon.go
 #fyne bundle -append -name PencilIcon -package data pencil.svg >> icon.go
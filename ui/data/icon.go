@@ -13,3 +13,18 @@ var LogoUnmasked = &fyne.StaticResource{
 	StaticName: "lab-unmasked.svg",
 	StaticContent: []byte{
 		60, 33, 68, 79, 67, 84, 89, 80, 69, 32, 115, 118, 103, 32, 80, 85, 66, 76, 73, 67, 32, 34, 45, 47, 47, 87, 51, 67, 47, 47, 68, 84, 68, 32, 83, 86, 71, 32, 49, 46, 49, 47, 47, 69, 78, 34, 32, 34, 104, 116, 116, 112, 58, 47, 47, 119, 119, 119, 46, 119, 51, 46, 111, 114, 103, 47, 71, 114, 97, 112, 104, 105, 99, 115, 47, 83, 86, 71, 47, 49, 46, 49, 47, 68, 84, 68, 47, 115, 118, 103, 49, 49, 46, 100, 116, 100, 34, 62, 10, 60, 115, 118, 103, 32, 118, 101, 114, 115, 105, 111, 110, 61, 34, 49, 46, 49, 34, 32, 120, 109, 108, 110, 115, 61, 34, 104, 116, 116, 112, 58, 47, 47, 119, 119, 119, 46, 119, 51, 46, 111, 114, 103, 47, 50, 48, 48, 48, 47, 115, 118, 103, 34, 32, 120, 109, 108, 110, 115, 58, 120, 108, 105, 110, 107, 61, 34, 104, 116, 116, 112, 58, 47, 47, 119, 119, 119, 46, 119, 51, 46, 111, 114, 103, 47, 49, 57, 57, 57, 47, 120, 108, 105, 110, 107, 34, 32, 120, 109, 108, 58, 115, 112, 97, 99, 101, 61, 34, 112, 114, 101, 115, 101, 114, 118, 101, 34, 32, 119, 105, 100, 116, 104, 61, 34, 52, 56, 48, 34, 32, 104, 101, 105, 103, 104, 116, 61, 34, 50, 52, 48, 34, 32, 118, 105, 101, 119, 66, 111, 120, 61, 34, 48, 32, 48, 32, 52, 56, 48, 32, 50, 52, 48, 34, 62, 10, 32, 32, 32, 32, 60, 33, 45, 45, 32, 66, 97, 99, 107, 103, 114, 111, 117, 110, 100, 32, 45, 45, 62, 10, 32, 32, 32, 32, 60, 114, 101, 99, 116, 32, 120, 61, 34, 48, 34, 32, 121, 61, 34, 48, 34, 32, 119, 105, 100, 116, 104, 61, 34, 52, 56, 48, 34, 32, 104, 101, 105, 103, 104, 116, 61, 34, 50, 52, 48, 34, 32, 102, 105, 108, 108, 61, 34, 98, 97, 99, 107, 103, 114, 111, 117, 110, 100, 34, 32, 47, 62, 10, 32, 32, 32, 32, 60, 33, 45, 45, 32, 71, 117, 105, 100, 101, 32, 45, 45, 62, 10, 32, 32, 32, 32, 60, 103, 32, 102, 105, 108, 108, 61, 34, 110, 111, 110, 101, 34, 32, 115, 116, 114, 111, 107, 101, 61, 34, 103, 114, 101, 121, 34, 32, 115, 116, 114, 111, 107, 101, 45, 119, 105, 100, 116, 104, 61, 34, 49, 34, 62, 10, 32, 32, 32, 32, 32, 32, 32, 32, 60, 112, 111, 108, 121, 108, 105, 110, 101, 32, 112, 111, 105, 110, 116, 115, 61, 34, 57, 48, 44, 48, 32, 57, 48, 44, 50, 52, 48, 34, 32, 47, 62, 10, 32, 32, 32, 32, 32, 32, 32, 32, 60, 112, 111, 108, 121, 108, 105, 110, 101, 32, 112, 111, 105, 110, 116, 115, 61, 34, 50, 52, 48, 44, 48, 32, 50, 52, 48, 44, 50, 52, 48, 34, 32, 47, 62, 10, 32, 32, 32, 32, 32, 32, 32, 32, 60, 112, 111, 108, 121, 108, 105, 110, 101, 32, 112, 111, 105, 110, 116, 115, 61, 34, 51, 57, 48, 44, 48, 32, 51, 57, 48, 44, 50, 52, 48, 34, 32, 47, 62, 10, 32, 32, 32, 32, 32, 32, 32, 32, 60, 112, 111, 108, 121, 108, 105, 110, 101, 32, 112, 111, 105, 110, 116, 115, 61, 34, 48, 44, 49, 50, 48, 32, 52, 56, 48, 44, 49, 50, 48, 34, 32, 47, 62, 10, 32, 32, 32, 32, 60, 47, 103, 62, 10, 32, 32, 32, 32, 60, 33, 45, 45, 32, 84, 101, 120, 116, 32, 45, 45, 62, 10, 32, 32, 32, 32, 60, 103, 32, 102, 105, 108, 108, 61, 34, 110, 111, 110, 101, 34, 32, 115, 116, 114, 111, 107, 101, 61, 34, 112, 114, 105, 109, 97, 114, 121, 34, 32, 115, 116, 114, 111, 107, 101, 45, 108, 105, 110, 101, 99, 97, 112, 61, 34, 114, 111, 117, 110, 100, 34, 32, 115, 116, 114, 111, 107, 101, 45, 108, 105, 110, 101, 106, 111, 105, 110, 61, 34, 114, 111, 117, 110, 100, 34, 32, 115, 116, 114, 111, 107, 101, 45, 119, 105, 100, 116, 104, 61, 34, 116, 101, 120, 116, 115, 105, 122, 101, 34, 62, 10, 32, 32, 32, 32, 32, 32, 32, 32, 60, 112, 97, 116, 104, 32, 100, 61, 34, 77, 32, 49, 48, 48, 44, 54, 48, 32, 76, 32, 49, 48, 48, 44, 49, 56, 48, 32, 76, 32, 49, 54, 48, 44, 49, 56, 48, 34, 32, 47, 62, 10, 32, 32, 32, 32, 32, 32, 32, 32, 60, 112, 97, 116, 104, 32, 100, 61, 34, 77, 32, 49, 57, 48, 44, 49, 56, 48, 32, 76, 32, 50, 52, 48, 44, 54, 48, 32, 76, 32, 50, 57, 48, 44, 49, 56, 48, 34, 32, 47, 62, 10, 32, 32, 32, 32, 32, 32, 32, 32, 60, 112, 97, 116, 104, 32, 100, 61, 34, 77, 32, 51, 50, 48, 44, 54, 48, 32, 76, 32, 51, 52, 50, 44, 54, 48, 32, 65, 32, 51, 48, 32, 51, 48, 32, 48, 32, 48, 32, 49, 32, 51, 52, 50, 32, 49, 50, 48, 32, 76, 32, 51, 50, 48, 44, 49, 50, 48, 32, 76, 32, 51, 53, 48, 44, 49, 50, 48, 32, 65, 32, 51, 48, 32, 51, 48, 32, 48, 32, 48, 32, 49, 32, 51, 53, 48, 32, 49, 56, 48, 32, 76, 32, 51, 50, 48, 44, 49, 56, 48, 32, 76, 32, 51, 50, 48, 44, 54, 48, 34, 32, 47, 62, 10, 32, 32, 32, 32, 60, 47, 103, 62, 10, 32, 32, 32, 32, 60, 33, 45, 45, 32, 69, 114, 108, 101, 110, 109, 101, 121, 101, 114, 32, 45, 45, 62, 10, 32, 32, 32, 32, 60, 112, 97, 116, 104, 32, 100, 61, 34, 77, 32, 50, 50, 54, 44, 57, 48, 32, 76, 32, 50, 53, 52, 44, 57, 48, 32, 77, 32, 50, 51, 48, 44, 57, 48, 32, 76, 32, 50, 51, 48, 44, 49, 50, 48, 32, 76, 32, 49, 57, 48, 44, 49, 57, 48, 32, 76, 32, 50, 57, 48, 44, 49, 57, 48, 32, 76, 32, 50, 53, 48, 44, 49, 50, 48, 32, 76, 32, 50, 53, 48, 44, 57, 48, 32, 90, 34, 32, 102, 105, 108, 108, 61, 34, 98, 97, 99, 107, 103, 114, 111, 117, 110, 100, 34, 32, 115, 116, 114, 111, 107, 101, 61, 34, 98, 97, 99, 107, 103, 114, 111, 117, 110, 100, 34, 32, 115, 116, 114, 111, 107, 101, 45, 108, 105, 110, 101, 99, 97, 112, 61, 34, 114, 111, 117, 110, 100, 34, 32, 115, 116, 114, 111, 107, 101, 45, 108, 105, 110, 101, 106, 111, 105, 110, 61, 34, 114, 111, 117, 110, 100, 34, 32, 115, 116, 114, 111, 107, 101, 45, 119, 105, 100, 116, 104, 61, 34, 102, 108, 97, 115, 107, 115, 105, 122, 101, 34, 32, 47, 62, 10, 60, 47, 115, 118, 103, 62, 10}}
+
+var FileCodeIcon = &fyne.StaticResource{
+	StaticName: "file-code.svg",
+	StaticContent: []byte{
+		60, 115, 118, 103, 32, 120, 109, 108, 110, 115, 61, 34, 104, 116, 116, 112, 58, 47, 47, 119, 119, 119, 46, 119, 51, 46, 111, 114, 103, 47, 50, 48, 48, 48, 47, 115, 118, 103, 34, 32, 119, 105, 100, 116, 104, 61, 34, 50, 52, 34, 32, 104, 101, 105, 103, 104, 116, 61, 34, 50, 52, 34, 32, 118, 105, 101, 119, 66, 111, 120, 61, 34, 48, 32, 48, 32, 50, 52, 32, 50, 52, 34, 62, 60, 112, 97, 116, 104, 32, 102, 105, 108, 108, 61, 34, 35, 102, 102, 102, 102, 102, 102, 34, 32, 100, 61, 34, 77, 49, 52, 32, 50, 72, 54, 99, 45, 49, 46, 49, 32, 48, 45, 50, 32, 46, 57, 45, 50, 32, 50, 118, 49, 54, 99, 48, 32, 49, 46, 49, 46, 57, 32, 50, 32, 50, 32, 50, 104, 49, 50, 99, 49, 46, 49, 32, 48, 32, 50, 45, 46, 57, 32, 50, 45, 50, 86, 56, 108, 45, 54, 45, 54, 122, 109, 45, 51, 46, 54, 32, 49, 53, 46, 54, 76, 54, 46, 56, 32, 49, 52, 108, 51, 46, 54, 45, 51, 46, 54, 32, 49, 46, 48, 53, 32, 49, 46, 48, 53, 76, 56, 46, 57, 32, 49, 52, 108, 50, 46, 53, 53, 32, 50, 46, 53, 53, 45, 49, 46, 48, 53, 32, 49, 46, 48, 53, 122, 109, 51, 46, 50, 32, 48, 108, 45, 49, 46, 48, 53, 45, 49, 46, 48, 53, 76, 49, 53, 46, 49, 32, 49, 52, 108, 45, 50, 46, 53, 53, 45, 50, 46, 53, 53, 32, 49, 46, 48, 53, 45, 49, 46, 48, 53, 76, 49, 55, 46, 50, 32, 49, 52, 108, 45, 51, 46, 54, 32, 51, 46, 54, 122, 77, 49, 51, 32, 57, 86, 51, 46, 53, 76, 49, 56, 46, 53, 32, 57, 72, 49, 51, 122, 34, 47, 62, 60, 47, 115, 118, 103, 62, 10}}
+
+var FileImageIcon = &fyne.StaticResource{
+	StaticName: "file-image.svg",
+	StaticContent: []byte{
+		60, 115, 118, 103, 32, 120, 109, 108, 110, 115, 61, 34, 104, 116, 116, 112, 58, 47, 47, 119, 119, 119, 46, 119, 51, 46, 111, 114, 103, 47, 50, 48, 48, 48, 47, 115, 118, 103, 34, 32, 119, 105, 100, 116, 104, 61, 34, 50, 52, 34, 32, 104, 101, 105, 103, 104, 116, 61, 34, 50, 52, 34, 32, 118, 105, 101, 119, 66, 111, 120, 61, 34, 48, 32, 48, 32, 50, 52, 32, 50, 52, 34, 62, 60, 112, 97, 116, 104, 32, 102, 105, 108, 108, 61, 34, 35, 102, 102, 102, 102, 102, 102, 34, 32, 100, 61, 34, 77, 49, 52, 32, 50, 72, 54, 99, 45, 49, 46, 49, 32, 48, 45, 50, 32, 46, 57, 45, 50, 32, 50, 118, 49, 54, 99, 48, 32, 49, 46, 49, 46, 57, 32, 50, 32, 50, 32, 50, 104, 49, 50, 99, 49, 46, 49, 32, 48, 32, 50, 45, 46, 57, 32, 50, 45, 50, 86, 56, 108, 45, 54, 45, 54, 122, 77, 55, 32, 49, 56, 108, 50, 46, 53, 45, 51, 46, 50, 32, 49, 46, 56, 32, 50, 46, 50, 32, 50, 46, 53, 45, 51, 46, 50, 76, 49, 55, 32, 49, 56, 72, 55, 122, 109, 54, 45, 57, 86, 51, 46, 53, 76, 49, 56, 46, 53, 32, 57, 72, 49, 51, 122, 34, 47, 62, 60, 47, 115, 118, 103, 62, 10}}
+
+var FileTextIcon = &fyne.StaticResource{
+	StaticName: "file-text.svg",
+	StaticContent: []byte{
+		60, 115, 118, 103, 32, 120, 109, 108, 110, 115, 61, 34, 104, 116, 116, 112, 58, 47, 47, 119, 119, 119, 46, 119, 51, 46, 111, 114, 103, 47, 50, 48, 48, 48, 47, 115, 118, 103, 34, 32, 119, 105, 100, 116, 104, 61, 34, 50, 52, 34, 32, 104, 101, 105, 103, 104, 116, 61, 34, 50, 52, 34, 32, 118, 105, 101, 119, 66, 111, 120, 61, 34, 48, 32, 48, 32, 50, 52, 32, 50, 52, 34, 62, 60, 112, 97, 116, 104, 32, 102, 105, 108, 108, 61, 34, 35, 102, 102, 102, 102, 102, 102, 34, 32, 100, 61, 34, 77, 49, 52, 32, 50, 72, 54, 99, 45, 49, 46, 49, 32, 48, 45, 49, 46, 57, 57, 46, 57, 45, 49, 46, 57, 57, 32, 50, 76, 52, 32, 50, 48, 99, 48, 32, 49, 46, 49, 46, 56, 57, 32, 50, 32, 49, 46, 57, 57, 32, 50, 72, 49, 56, 99, 49, 46, 49, 32, 48, 32, 50, 45, 46, 57, 32, 50, 45, 50, 86, 56, 108, 45, 54, 45, 54, 122, 109, 50, 32, 49, 54, 72, 56, 118, 45, 50, 104, 56, 118, 50, 122, 109, 48, 45, 52, 72, 56, 118, 45, 50, 104, 56, 118, 50, 122, 109, 45, 51, 45, 53, 86, 51, 46, 53, 76, 49, 56, 46, 53, 32, 57, 72, 49, 51, 122, 34, 47, 62, 60, 47, 115, 118, 103, 62, 10}}
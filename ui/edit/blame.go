@@ -0,0 +1,235 @@
+/*
+ * Copyright 2020 Aletheia Ware LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package edit
+
+import (
+	"encoding/base64"
+	"fmt"
+	"fyne.io/fyne"
+	"fyne.io/fyne/canvas"
+	"fyne.io/fyne/driver/desktop"
+	"fyne.io/fyne/widget"
+	"github.com/AletheiaWareLLC/bcgo"
+	"hash/fnv"
+	"image/color"
+	"sort"
+	"unicode/utf8"
+)
+
+// AUTHOR_COLORS are the colors of text in blame mode, each author is given one by their alias.
+var AUTHOR_COLORS = []color.Color{
+	color.NRGBA{R: 0xe6, G: 0x19, B: 0x4b, A: 0xff},
+	color.NRGBA{R: 0x3c, G: 0xb4, B: 0x4b, A: 0xff},
+	color.NRGBA{R: 0x43, G: 0x63, B: 0xd8, A: 0xff},
+	color.NRGBA{R: 0xf5, G: 0x82, B: 0x31, A: 0xff},
+	color.NRGBA{R: 0x91, G: 0x1e, B: 0xb4, A: 0xff},
+	color.NRGBA{R: 0x42, G: 0xd4, B: 0xf4, A: 0xff},
+	color.NRGBA{R: 0xf0, G: 0x32, B: 0xe6, A: 0xff},
+	color.NRGBA{R: 0x9a, G: 0x63, B: 0x24, A: 0xff},
+}
+
+// AuthorColor returns the color of text inserted by the given alias.
+func AuthorColor(alias string) color.Color {
+	h := fnv.New32a()
+	h.Write([]byte(alias))
+	return AUTHOR_COLORS[h.Sum32()%uint32(len(AUTHOR_COLORS))]
+}
+
+// Blame returns the id of the delta which inserted each byte of the buffer made by applying the given executed spans in order.
+func Blame(order []string, executed map[string][]*Span) []string {
+	var ids []string
+	for _, id := range order {
+		for _, s := range sortSpans(executed[id]) {
+			length := uint64(len(ids))
+			start := s.Offset
+			if start > length {
+				start = length
+			}
+			end := start + s.Remove
+			if end > length {
+				end = length
+			}
+			result := make([]string, 0, uint64(len(ids))-(end-start)+uint64(len(s.Add)))
+			result = append(result, ids[:start]...)
+			for range s.Add {
+				result = append(result, id)
+			}
+			ids = append(result, ids[end:]...)
+		}
+	}
+	return ids
+}
+
+// SetBlame turns blame mode on or off, in which text is colored by the alias of its author.
+func (e *ChannelEditor) SetBlame(blame bool) {
+	e.Lock()
+	e.Blame = blame
+	if blame {
+		e.updateBlame()
+		e.TextColors = e.blameColors
+	} else {
+		e.blame = nil
+		e.TextColors = nil
+		e.Tooltip = ""
+	}
+	e.Unlock()
+	e.Refresh()
+}
+
+// Authors returns the aliases of everyone who has written a delta or edit, in alphabetical order.
+func (e *ChannelEditor) Authors() []string {
+	e.Lock()
+	defer e.Unlock()
+	set := make(map[string]bool)
+	for _, entry := range e.Entries {
+		set[entry.Record.Creator] = true
+	}
+	for id := range e.Edits {
+		if entry, ok := e.EditEntry(id); ok {
+			set[entry.Record.Creator] = true
+		}
+	}
+	var authors []string
+	for a := range set {
+		authors = append(authors, a)
+	}
+	sort.Strings(authors)
+	return authors
+}
+
+func (e *ChannelEditor) MouseIn(event *desktop.MouseEvent) {
+	e.MouseMoved(event)
+}
+
+// MouseMoved shows the author, time, and record of the text under the mouse in blame mode.
+func (e *ChannelEditor) MouseMoved(event *desktop.MouseEvent) {
+	e.Lock()
+	tooltip := ""
+	if e.Blame {
+		if offset, ok := e.positionRune(event.Position); ok {
+			if ids := e.blameIds(); offset < uint64(len(ids)) {
+				if entry, ok := e.EditEntry(ids[offset]); ok {
+					tooltip = fmt.Sprintf("%s %s %s", entry.Record.Creator, bcgo.TimestampToString(entry.Record.Timestamp), ids[offset])
+				} else {
+					tooltip = "Not yet written"
+				}
+			}
+		}
+	}
+	changed := tooltip != e.Tooltip || (tooltip != "" && event.Position != e.TooltipPosition)
+	e.Tooltip = tooltip
+	e.TooltipPosition = event.Position
+	e.Unlock()
+	if changed {
+		e.Refresh()
+	}
+}
+
+func (e *ChannelEditor) MouseOut() {
+	e.Lock()
+	changed := e.Tooltip != ""
+	e.Tooltip = ""
+	e.Unlock()
+	if changed {
+		e.Refresh()
+	}
+}
+
+// updateBlame finds the delta, or sequence record, which inserted each rune of the buffer.
+// The caller must hold the lock.
+func (e *ChannelEditor) updateBlame() {
+	e.blame = nil
+	if e.Sequence.Len() > 0 {
+		for _, hash := range e.Sequence.Records() {
+			e.blame = append(e.blame, base64.RawURLEncoding.EncodeToString(hash))
+		}
+		return
+	}
+	order, parents := e.history()
+	buffer, executed := ReplayDeltas(order, parents, e.Deltas)
+	ids := Blame(order, executed)
+	for i := 0; i < len(buffer); {
+		_, size := utf8.DecodeRune(buffer[i:])
+		e.blame = append(e.blame, ids[i])
+		i += size
+	}
+}
+
+// blameIds returns the id of the delta, or sequence record, which inserted each rune of the buffer, pending edits have not been written so have none.
+// The caller must hold the lock.
+func (e *ChannelEditor) blameIds() []string {
+	if e.pending == nil {
+		return e.blame
+	}
+	length := uint64(len(e.blame))
+	start := e.pending.Offset
+	if start > length {
+		start = length
+	}
+	end := start + uint64(utf8.RuneCount(e.pending.Remove))
+	if end > length {
+		end = length
+	}
+	ids := make([]string, 0, length)
+	ids = append(ids, e.blame[:start]...)
+	ids = append(ids, make([]string, utf8.RuneCount(e.pending.Add))...)
+	return append(ids, e.blame[end:]...)
+}
+
+// blameColors returns the color of the author of each rune of the buffer, pending edits are shown in the color of this node.
+// The caller must hold the lock.
+func (e *ChannelEditor) blameColors() []color.Color {
+	ids := e.blameIds()
+	colors := make([]color.Color, len(ids))
+	for i, id := range ids {
+		if entry, ok := e.EditEntry(id); ok {
+			colors[i] = AuthorColor(entry.Record.Creator)
+		} else if e.Node != nil {
+			colors[i] = AuthorColor(e.Node.Alias)
+		}
+	}
+	return colors
+}
+
+// Legend shows the color of each author of a ChannelEditor in blame mode.
+type Legend struct {
+	Editor *ChannelEditor
+	Box    *widget.Box
+}
+
+func NewLegend(editor *ChannelEditor) *Legend {
+	l := &Legend{
+		Editor: editor,
+		Box:    widget.NewHBox(),
+	}
+	l.Update()
+	return l
+}
+
+// Update lists the authors of the editor.
+func (l *Legend) Update() {
+	var objects []fyne.CanvasObject
+	for _, alias := range l.Editor.Authors() {
+		objects = append(objects, canvas.NewText(alias, AuthorColor(alias)))
+	}
+	l.Box.Children = objects
+	l.Box.Refresh()
+}
+
+func (l *Legend) CanvasObject() fyne.CanvasObject {
+	return widget.NewHScrollContainer(l.Box)
+}
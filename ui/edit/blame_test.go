@@ -0,0 +1,124 @@
+/*
+ * Copyright 2020 Aletheia Ware LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package edit_test
+
+import (
+	"fyne.io/fyne/test"
+	"github.com/AletheiaWareLLC/bcgo"
+	"github.com/AletheiaWareLLC/labfynego/ui/edit"
+	"github.com/AletheiaWareLLC/labgo"
+	"image/color"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestBlame(t *testing.T) {
+	entries := make(map[string]*bcgo.BlockEntry)
+	deltas := make(map[string]*labgo.Delta)
+	makeDelta(t, entries, deltas, "a", "alice", 10, &labgo.Delta{Add: []byte("Hello World")})
+	makeDelta(t, entries, deltas, "b", "alice", 20, &labgo.Delta{Offset: 5, Add: []byte(",")}, "a")
+	makeDelta(t, entries, deltas, "c", "bob", 20, &labgo.Delta{Offset: 6, Remove: []byte("World"), Add: []byte("Bob")}, "a")
+	order := edit.OrderDeltas(testChannel, entries)
+	buffer, executed := edit.ReplayDeltas(order, edit.Parents(testChannel, entries), deltas)
+	if got, want := string(buffer), "Hello, Bob"; got != want {
+		t.Fatalf("Incorrect buffer; expected '%s', got '%s'", want, got)
+	}
+	var want []string
+	for _, s := range []struct {
+		hash  string
+		count int
+	}{
+		{"a", 5},
+		{"b", 1},
+		{"a", 1},
+		{"c", 3},
+	} {
+		for i := 0; i < s.count; i++ {
+			want = append(want, id(s.hash))
+		}
+	}
+	if got := edit.Blame(order, executed); !reflect.DeepEqual(got, want) {
+		t.Errorf("Incorrect blame; expected '%v', got '%v'", want, got)
+	}
+}
+
+func TestChannelEditor_Blame(t *testing.T) {
+	test.NewApp()
+	colors := func(authors string) []color.Color {
+		var colors []color.Color
+		for _, author := range strings.Split(authors, "") {
+			switch author {
+			case "a":
+				colors = append(colors, edit.AuthorColor("alice"))
+			case "b":
+				colors = append(colors, edit.AuthorColor("bob"))
+			}
+		}
+		return colors
+	}
+	for _, format := range []string{edit.FORMAT_DELTA, edit.FORMAT_SEQUENCE} {
+		t.Run(format, func(t *testing.T) {
+			alice := makeNode(t, "alice")
+			channel := labgo.OpenFileChannel("Test")
+			alice.AddChannel(channel)
+			e := edit.NewChannelEditor(alice, nil, channel, nil)
+			e.Format = format
+			e.Timeout = 0
+			for _, r := range "Grüße" {
+				e.TypedRune(r)
+			}
+			if format == edit.FORMAT_SEQUENCE {
+				writeEdit(t, makeNode(t, "bob"), e, 5, 0, " 世界")
+			} else {
+				writeDelta(t, makeNode(t, "bob"), e, &labgo.Delta{Offset: 7, Add: []byte(" 世界")})
+			}
+			e.SetBlame(true)
+			// Pending edits are shown in the color of this node
+			e.Timeout = edit.DELTA_TIMEOUT
+			e.Cursor = 8
+			e.TypedRune('!')
+
+			if got, want := e.Authors(), []string{"alice", "bob"}; !reflect.DeepEqual(got, want) {
+				t.Errorf("Incorrect authors; expected '%v', got '%v'", want, got)
+			}
+			for _, tt := range []struct {
+				edit func()
+				want string
+			}{
+				{func() {}, "aaaaabbba"},
+				{e.Flush, "aaaaabbba"},
+				{e.Undo, "aaaaabbb"},
+				{e.Undo, "aaaabbb"},
+				{e.Redo, "aaaaabbb"},
+			} {
+				tt.edit()
+				e.Lock()
+				got := e.TextColors()
+				e.Unlock()
+				if want := colors(tt.want); !reflect.DeepEqual(got, want) {
+					t.Fatalf("Incorrect colors; expected '%v', got '%v'", want, got)
+				}
+			}
+
+			e.SetBlame(false)
+			if e.TextColors != nil {
+				t.Error("Expected colors to be removed")
+			}
+		})
+	}
+}
@@ -17,14 +17,29 @@
 package edit
 
 import (
+	"bytes"
+	"crypto/rsa"
 	"encoding/base64"
 	"fyne.io/fyne"
 	"fyne.io/fyne/theme"
 	"github.com/AletheiaWareLLC/bcgo"
+	"github.com/AletheiaWareLLC/cryptogo"
 	"github.com/AletheiaWareLLC/labgo"
 	"github.com/golang/protobuf/proto"
 	"log"
 	"sort"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	// Key of the record meta data naming the type of the payload, records without one hold a labgo.Delta
+	META_TYPE = "type"
+
+	// Formats of the records written to files, as set in the change channel of an experiment
+	FORMAT_DELTA    = "delta"
+	FORMAT_SEQUENCE = "sequence"
 )
 
 type ChannelEditor struct {
@@ -33,25 +48,102 @@ type ChannelEditor struct {
 	Listener bcgo.MiningListener
 	Channel  *bcgo.Channel
 	Entries  map[string]*bcgo.BlockEntry
+	Sequence *Sequence
+	// Records of type TYPE_SEQUENCE, by id
+	Edits   map[string]*bcgo.BlockEntry
+	Parents map[string][]string
+
+	// Checkpoints written to the channel, by record id
+	Checkpoints map[string]*Checkpoint
+
+	// Snapshots, if set, keeps the buffer every SNAPSHOT_INTERVAL deltas so the file can be opened without applying them all
+	Snapshots SnapshotStore
+
+	// Blame, if set, colors text by the alias of the author who inserted it
+	Blame bool
+
+	// OnRead, if set, is called after records are read from the channel
+	OnRead func()
+
+	// PresenceChannel, if set, carries the cursors of those editing the file
+	PresenceChannel *bcgo.Channel
+	// PresenceCache holds the blocks of PresenceChannel, which are not kept once replaced
+	PresenceCache *bcgo.MemoryCache
+	// PresenceLimit is the minimum duration between presence blocks written by this node
+	PresenceLimit time.Duration
+	// Collaborators editing the file, by alias
+	Collaborators map[string]*Collaborator
+
+	// Format of the records written to a channel without any, once a channel holds records of type TYPE_SEQUENCE they are used instead of deltas
+	Format string
+
+	blocks         map[string]bool         // Hashes of the blocks which have been read
+	written        map[string]bool         // Ids of the deltas written by this editor which have not been read
+	ids            map[*labgo.Delta]string // Ids of the records written for the deltas emitted by this editor
+	reading        sync.Mutex
+	replay         *Replay
+	snapshotLength int // Number of deltas in the last snapshot put in Snapshots
+
+	checkpointHeads  map[string][]string // Ids of the deltas each checkpoint was written after
+	checkpointLength int                 // Number of deltas covered by the latest checkpoint
+	unverified       string              // Id of the checkpoint the replay resumed from, until it is verified
+	readOnly         bool                // Whether the editor was read only before resuming from a checkpoint not yet verified
+	divergent        map[string]bool     // Ids of the checkpoints which do not hold the deltas they cover
+
+	blame []string // Id of the delta which inserted each rune of the buffer, without the pending delta
+
+	presenceBlocks  map[string]bool             // Hashes of the blocks which have been read from PresenceChannel
+	presenceTimer   *time.Timer                 // Writes the cursor once it stops moving
+	presenceEntries map[string]*bcgo.BlockEntry // Latest presence record of each collaborator, carried into the blocks written to PresenceChannel
+	presenceWritten *Presence                   // Presence last written to PresenceChannel
+	presenceHeads   []string                    // Ids of the deltas the last presence was written after
+	presenceTime    time.Time                   // When the last presence was written
+	fader           *time.Timer                 // Refreshes while carets fade
+
+	closed bool // Whether the editor has been closed, after which the triggers it added to its channels do nothing
 }
 
-func NewChannelEditor(node *bcgo.Node, listener bcgo.MiningListener, channel *bcgo.Channel) *ChannelEditor {
+func NewChannelEditor(node *bcgo.Node, listener bcgo.MiningListener, channel *bcgo.Channel, snapshots SnapshotStore) *ChannelEditor {
 	e := &ChannelEditor{
 		DeltaEditor: DeltaEditor{
 			Editor: Editor{
+				Buffer: &Rope{},
 				//TextAlign: fyne.TextAlignTrailing,
 				TextColor: theme.TextColor(),
 				TextSize:  theme.TextSize(),
 				TextStyle: fyne.TextStyle{},
 				TextWrap:  fyne.TextWrapWord,
 			},
-			Deltas: make(map[string]*labgo.Delta),
+			Deltas:  make(map[string]*labgo.Delta),
+			Timeout: DELTA_TIMEOUT,
+			emitted: make(map[*labgo.Delta][]string),
+			reverts: make(map[*labgo.Delta][]*labgo.Delta),
 		},
-		Node:     node,
-		Listener: listener,
-		Channel:  channel,
-		Entries:  make(map[string]*bcgo.BlockEntry),
+		Node:      node,
+		Listener:  listener,
+		Channel:   channel,
+		Entries:   make(map[string]*bcgo.BlockEntry),
+		Sequence:  NewSequence(),
+		Edits:     make(map[string]*bcgo.BlockEntry),
+		Parents:   make(map[string][]string),
+		Snapshots: snapshots,
+		Format:    FORMAT_DELTA,
+
+		PresenceLimit: PRESENCE_LIMIT,
+
+		Checkpoints:     make(map[string]*Checkpoint),
+		blocks:          make(map[string]bool),
+		written:         make(map[string]bool),
+		ids:             make(map[*labgo.Delta]string),
+		checkpointHeads: make(map[string][]string),
+		divergent:       make(map[string]bool),
+		Collaborators:   make(map[string]*Collaborator),
+		presenceBlocks:  make(map[string]bool),
+		presenceEntries: make(map[string]*bcgo.BlockEntry),
 	}
+	e.Carets = e.carets
+	e.OnCursorChanged = e.cursorChanged
+	e.reverting = e.revertDeltas
 	e.ExtendBaseWidget(e)
 	e.AddShortcuts()
 	if channel != nil {
@@ -62,74 +154,710 @@ func NewChannelEditor(node *bcgo.Node, listener bcgo.MiningListener, channel *bc
 	return e
 }
 
+// Close writes any pending edits, then stops the editor reading its channels and writing presence, as when its file is deleted.
+// Channels cannot drop a trigger, so the triggers the editor added return at once when it is closed.
+func (e *ChannelEditor) Close() {
+	e.Flush()
+	// Held so a read in progress finishes before the editor is closed
+	e.reading.Lock()
+	defer e.reading.Unlock()
+	e.Lock()
+	defer e.Unlock()
+	e.closed = true
+	e.ReadOnly = true
+	if e.presenceTimer != nil {
+		e.presenceTimer.Stop()
+	}
+	if e.fader != nil {
+		e.fader.Stop()
+		e.fader = nil
+	}
+}
+
+// isClosed returns whether the editor has been closed.
+func (e *ChannelEditor) isClosed() bool {
+	e.Lock()
+	defer e.Unlock()
+	return e.closed
+}
+
 func (e *ChannelEditor) Read() {
 	log.Println("Read")
+	// One read at a time, so each block is read once
+	e.reading.Lock()
+	defer e.reading.Unlock()
+	if e.isClosed() {
+		return
+	}
+
+	// Collect the blocks which have not been read, without holding the lock
+	var hashes []string
+	var blocks []*bcgo.Block
+	if err := bcgo.Iterate(e.Channel.Name, e.Channel.Head, nil, e.Node.Cache, e.Node.Network, func(hash []byte, block *bcgo.Block) error {
+		h := base64.RawURLEncoding.EncodeToString(hash)
+		if e.blocks[h] {
+			// Every block before one which has been read has also been read
+			return bcgo.StopIterationError{}
+		}
+		hashes = append(hashes, h)
+		blocks = append(blocks, block)
+		return nil
+	}); err != nil {
+		if _, ok := err.(bcgo.StopIterationError); !ok {
+			log.Println(err)
+			return
+		}
+	}
+	for _, h := range hashes {
+		e.blocks[h] = true
+	}
+
 	e.Lock()
-	if err := bcgo.Read(e.Channel.Name, e.Channel.Head, nil, e.Node.Cache, e.Node.Network, "", nil, nil, func(entry *bcgo.BlockEntry, key, data []byte) error {
-		id := base64.RawURLEncoding.EncodeToString(entry.RecordHash)
-		e.Entries[id] = entry
-		// Unmarshal as Delta
-		delta := &labgo.Delta{}
-		if err := proto.Unmarshal(data, delta); err != nil {
-			return err
+	var ids []string
+	var edits []*SequenceEdit
+	var editEntries []*bcgo.BlockEntry
+	checkpoints := false
+	readFileBlocks(blocks, func(id string, entry *bcgo.BlockEntry) bool {
+		switch entry.Record.Meta[META_TYPE] {
+		case TYPE_SEQUENCE:
+			return e.Sequence.Has(entry.RecordHash)
+		case TYPE_CHECKPOINT:
+			_, ok := e.Checkpoints[id]
+			return ok
 		}
 		_, ok := e.Deltas[id]
-		if ok {
-			return nil
+		return ok
+	}, func(id string, entry *bcgo.BlockEntry, message proto.Message) {
+		switch m := message.(type) {
+		case *SequenceEdit:
+			editEntries = append(editEntries, entry)
+			edits = append(edits, m)
+		case *Checkpoint:
+			e.Checkpoints[id] = m
+			e.checkpointHeads[id] = ParentIds(e.Channel.Name, entry.Record)
+			checkpoints = true
+		case *labgo.Delta:
+			e.Deltas[id] = m
+			e.Entries[id] = entry
+			ids = append(ids, id)
 		}
-		e.Deltas[id] = delta
-		e.Entries[id] = entry
-		e.Order = append(e.Order, id)
-		sort.Slice(e.Order, func(i, j int) bool {
-			return e.Entries[e.Order[i]].Record.Timestamp < e.Entries[e.Order[j]].Record.Timestamp //.Record.Creator
+	})
+	if len(edits) > 0 || (len(ids) > 0 && e.Sequence.Len() > 0) {
+		e.readSequence(editEntries, edits)
+	} else if len(ids) > 0 {
+		e.readDeltas(ids)
+	}
+	if checkpoints && e.replay != nil {
+		// Count the deltas covered by the latest checkpoint, so the next is written after enough more
+		e.latestCheckpoint()
+	}
+	e.Unlock()
+	e.Refresh()
+	if e.OnRead != nil {
+		e.OnRead()
+	}
+}
+
+// readFileBlocks passes each record of the given blocks of a file channel, oldest first, to the given function, unmarshalled as a SequenceEdit, Checkpoint, or Delta according to its type, unless it is known.
+// Blocks are given newest first, as iterated from the head of the channel, so deltas which extend the order can be appended to it.
+func readFileBlocks(blocks []*bcgo.Block, known func(string, *bcgo.BlockEntry) bool, f func(string, *bcgo.BlockEntry, proto.Message)) {
+	for i := len(blocks) - 1; i >= 0; i-- {
+		for _, entry := range blocks[i].Entry {
+			if len(entry.Record.Access) > 0 {
+				// File records are not encrypted
+				continue
+			}
+			id := base64.RawURLEncoding.EncodeToString(entry.RecordHash)
+			if known != nil && known(id, entry) {
+				continue
+			}
+			var message proto.Message
+			switch entry.Record.Meta[META_TYPE] {
+			case TYPE_SEQUENCE:
+				message = &SequenceEdit{}
+			case TYPE_CHECKPOINT:
+				message = &Checkpoint{}
+			default:
+				message = &labgo.Delta{}
+			}
+			if err := proto.Unmarshal(entry.Record.Payload, message); err != nil {
+				log.Println(err)
+				continue
+			}
+			f(id, entry, message)
+		}
+	}
+}
+
+// readDeltas applies the given new deltas to the buffer, moving the cursor, selection, and pending delta with them.
+// The caller must hold the lock.
+func (e *ChannelEditor) readDeltas(ids []string) {
+	first := len(e.Order) == 0
+	var pending []*Span
+	if e.pending != nil {
+		// Text before the offset is unchanged by the pending delta
+		pending = append(pending, &Span{
+			Offset: uint64(e.Buffer.RuneToByte(int(e.pending.Offset))),
+			Remove: uint64(len(e.pending.Remove)),
+			Add:    e.pending.Add,
 		})
-		buffer := []byte{}
-		for _, id := range e.Order {
-			delta := e.Deltas[id]
-			log.Println("Edit:", id, e.Entries[id].Record.Creator, delta)
-			buffer = labgo.DeltaToBuffer(delta, buffer)
+	}
+	if !e.extend(ids) {
+		// A delta arrived out of order, so order them all again
+		e.Parents = Parents(e.Channel.Name, e.Entries)
+		e.Order = OrderDeltas(e.Channel.Name, e.Entries)
+		e.Heads = Heads(e.Order, e.Parents)
+	}
+	if e.replay == nil {
+		e.replay = e.newReplay()
+	}
+	start := e.replay.Apply(e.Order, e.Parents, e.Deltas)
+	buffer, executed := e.replay.Buffer, e.replay.Executed
+	added := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		added[id] = true
+	}
+	// Move the cursor and selection with the new deltas, or on first read, to the end of the last edit by this node
+	cursor := uint64(e.Buffer.RuneToByte(int(e.Cursor)))
+	selection := uint64(e.Buffer.RuneToByte(int(e.Selection)))
+	for _, id := range e.Order[start:] {
+		spans := executed[id]
+		if first || added[id] {
+			e.moveCollaborators(id, spans)
+		}
+		if first {
 			if e.Entries[id].Record.Creator == e.Node.Alias {
-				e.Cursor = delta.Offset + uint64(len(delta.Add))
+				for _, s := range spans {
+					if len(s.Add) > 0 {
+						cursor = TransformOffset(s.Offset+s.Remove, spans)
+					}
+				}
+			} else {
+				cursor = TransformOffset(cursor, spans)
+			}
+		} else if added[id] {
+			log.Println("Edit:", id, e.Entries[id].Record.Creator, spans)
+			if e.written[id] {
+				// Already applied to the buffer when it was written
+				delete(e.written, id)
+				continue
 			}
+			cursor = TransformOffset(cursor, spans)
+			selection = TransformOffset(selection, spans)
+			// Keep pending edits where they were made
+			pending = TransformSpans(pending, spans)
 		}
-		e.Buffer = []rune(string(buffer))
-		log.Println("Buffer:", string(e.Buffer))
-		return nil
-	}); err != nil {
-		log.Println(err)
 	}
+	e.Cursor = uint64(buffer.ByteToRune(int(cursor)))
+	e.Selection = uint64(buffer.ByteToRune(int(selection)))
+	e.pending = nil
+	for _, s := range pending {
+		// The pending delta can only hold one span, so keep the one that adds text, or failing that the first
+		if e.pending == nil || len(s.Add) > 0 {
+			start := buffer.ByteToRune(int(s.Offset))
+			e.pending = &labgo.Delta{
+				Offset: uint64(start),
+				Remove: []byte(string(buffer.Slice(start, buffer.ByteToRune(int(s.Offset+s.Remove))))),
+				Add:    s.Add,
+			}
+		}
+	}
+	// Keep showing edits that have not been emitted yet
+	e.Buffer = e.applyPending(buffer.Copy())
+	if e.pending != nil {
+		e.Cursor = e.pendingEnd()
+	}
+	if e.Blame {
+		e.updateBlame()
+	}
+	// Only keep buffers known to hold the deltas they were replayed from
+	if e.Snapshots != nil && e.unverified == "" && len(e.Order)-e.snapshotLength >= SNAPSHOT_INTERVAL {
+		if err := e.Snapshots.PutSnapshot(e.Channel.Name, e.replay.Snapshot()); err != nil {
+			log.Println(err)
+		}
+		e.snapshotLength = len(e.Order)
+	}
+}
+
+// newReplay returns a Replay resuming from the snapshot in Snapshots, or from the latest checkpoint if it covers more deltas.
+// A checkpoint may have been written by anyone, so it is verified in the background, and if it does not hold the deltas it covers, they are all replayed instead.
+// The caller must hold the lock.
+func (e *ChannelEditor) newReplay() *Replay {
+	var snapshot *Snapshot
+	if e.Snapshots != nil {
+		if s, err := e.Snapshots.GetSnapshot(e.Channel.Name); err != nil {
+			log.Println(err)
+		} else {
+			snapshot = s
+			e.snapshotLength = len(s.Order)
+		}
+	}
+	if id, order := e.latestCheckpoint(); id != "" && (snapshot == nil || len(order) > len(snapshot.Order)) {
+		checkpoint := e.Checkpoints[id]
+		// The local snapshot is trusted, so only the deltas after it are replayed to verify the checkpoint
+		trusted := snapshot
+		snapshot = &Snapshot{
+			Order:  order,
+			Buffer: checkpoint.Body,
+		}
+		if e.unverified == "" {
+			e.readOnly = e.ReadOnly
+		}
+		e.unverified = id
+		// Not edited until verified, as edits made to divergent content would be written after the deltas
+		e.ReadOnly = true
+		// Copy the covered deltas, as the maps are changed by later reads
+		parents := make(map[string][]string, len(order))
+		deltas := make(map[string]*labgo.Delta, len(order))
+		for _, d := range order {
+			parents[d] = e.Parents[d]
+			deltas[d] = e.Deltas[d]
+		}
+		go e.verify(id, checkpoint, trusted, order, parents, deltas)
+	}
+	if snapshot == nil {
+		return NewReplay()
+	}
+	return NewReplayFromSnapshot(snapshot, e.Parents)
+}
+
+// latestCheckpoint returns the id and order of the checkpoint which covers the most deltas, and updates the number of deltas covered by the latest checkpoint.
+// The caller must hold the lock.
+func (e *ChannelEditor) latestCheckpoint() (string, []string) {
+	var latest string
+	var order []string
+	for id, checkpoint := range e.Checkpoints {
+		if e.divergent[id] {
+			continue
+		}
+		o, ok := CheckpointOrder(e.Channel.Name, e.Entries, e.Parents, e.checkpointHeads[id])
+		if !ok || len(o) < len(order) || (len(o) == len(order) && id > latest) {
+			continue
+		}
+		if !bytes.Equal(checkpoint.Hash, cryptogo.Hash(checkpoint.Body)) {
+			log.Println("Corrupt checkpoint:", id)
+			e.divergent[id] = true
+			continue
+		}
+		latest, order = id, o
+	}
+	if len(order) > e.checkpointLength {
+		e.checkpointLength = len(order)
+	}
+	return latest, order
+}
+
+// verify replays the deltas covered by the given checkpoint after the given trusted snapshot, and if the result is not held by the checkpoint, replays every delta without it.
+// A verified checkpoint is kept as the snapshot, so the next checkpoint is verified from it.
+func (e *ChannelEditor) verify(id string, checkpoint *Checkpoint, trusted *Snapshot, order []string, parents map[string][]string, deltas map[string]*labgo.Delta) {
+	verified := VerifyCheckpoint(checkpoint, trusted, order, parents, deltas)
+	e.Lock()
+	if e.unverified == id {
+		e.unverified = ""
+		e.ReadOnly = e.readOnly
+	}
+	if verified {
+		log.Println("Verified checkpoint:", id)
+		if e.Snapshots != nil && len(order) > e.snapshotLength {
+			if err := e.Snapshots.PutSnapshot(e.Channel.Name, &Snapshot{
+				Order:  order,
+				Buffer: checkpoint.Body,
+			}); err != nil {
+				log.Println(err)
+			}
+			e.snapshotLength = len(order)
+		}
+		e.Unlock()
+		e.Refresh()
+		return
+	}
+	log.Println("Divergent checkpoint:", id)
+	e.divergent[id] = true
+	e.replay = nil
+	e.readDeltas(nil)
 	e.Unlock()
 	e.Refresh()
 }
 
+// extend appends the given deltas to the order if each was written after all the deltas before it, and returns false if the order must be rebuilt instead.
+// The caller must hold the lock.
+func (e *ChannelEditor) extend(ids []string) bool {
+	for _, id := range ids {
+		var parents []string
+		for _, p := range ParentIds(e.Channel.Name, e.Entries[id].Record) {
+			if _, ok := e.Entries[p]; ok {
+				parents = append(parents, p)
+			}
+		}
+		if len(parents) == 0 || len(parents) != len(e.Heads) {
+			return false
+		}
+		for _, p := range parents {
+			found := false
+			for _, h := range e.Heads {
+				found = found || h == p
+			}
+			if !found {
+				return false
+			}
+		}
+		e.Parents[id] = parents
+		e.Order = append(e.Order, id)
+		e.Heads = []string{id}
+	}
+	return true
+}
+
+// readSequence integrates the given edits, keeping the cursor, selection, and pending delta beside the runes they were beside.
+// The caller must hold the lock.
+func (e *ChannelEditor) readSequence(entries []*bcgo.BlockEntry, edits []*SequenceEdit) {
+	cursor := e.Sequence.anchor(e.Cursor)
+	selection := e.Sequence.anchor(e.Selection)
+	var pending elementKey
+	if e.pending != nil {
+		pending = e.Sequence.anchor(e.pending.Offset)
+	}
+	for i, edit := range edits {
+		id := base64.RawURLEncoding.EncodeToString(entries[i].RecordHash)
+		log.Println("Edit:", id, edit)
+		e.Sequence.Integrate(entries[i].RecordHash, edit)
+		e.Edits[id] = entries[i]
+		e.moveAuthor(id, edit)
+	}
+	// Deltas written by editors which had not seen the sequence records are converted, rather than ignored
+	order, parents := e.history()
+	for _, id := range e.Sequence.IntegrateDeltas(order, parents, e.Deltas) {
+		log.Println("Delta:", id, e.Entries[id].Record.Creator)
+	}
+	text := e.Sequence.Text()
+	e.Cursor = e.Sequence.offset(cursor)
+	e.Selection = e.Sequence.offset(selection)
+	if e.pending != nil {
+		length := uint64(text.Len())
+		start := e.Sequence.offset(pending)
+		end := start + uint64(utf8.RuneCount(e.pending.Remove))
+		if end > length {
+			end = length
+		}
+		e.pending.Offset = start
+		e.pending.Remove = []byte(string(text.Slice(int(start), int(end))))
+	}
+	// Keep showing edits that have not been emitted yet
+	e.Buffer = e.applyPending(text.Copy())
+	if e.pending != nil {
+		e.Cursor = e.pendingEnd()
+	}
+	if e.Blame {
+		e.updateBlame()
+	}
+}
+
 func (e *ChannelEditor) Write(parent string, delta *labgo.Delta) {
 	log.Println("Write:", parent, delta)
+	e.Lock()
+	// Deltas emitted by the editor were written after every head, not only the last
+	parents, ok := e.emitted[delta]
+	if !ok && parent != "" {
+		parents = []string{parent}
+	}
+	if e.Sequence.Len() > 0 || (len(e.Deltas) == 0 && e.Format == FORMAT_SEQUENCE) {
+		e.writeSequence(delta)
+		return
+	}
+	references := e.references(parents)
+	var checkpoint *Checkpoint
+	var checkpointReferences []*bcgo.Reference
+	// Write a checkpoint alongside the delta once enough deltas follow the last, unless the buffer came from a checkpoint not yet verified
+	if e.replay != nil && e.unverified == "" && len(e.Order)-e.checkpointLength >= CHECKPOINT_INTERVAL {
+		checkpoint = NewCheckpoint([]byte(e.replay.Buffer.String()))
+		checkpointReferences = e.references(e.Heads)
+		e.checkpointLength = len(e.Order)
+	}
+	e.Unlock()
+
 	// Create protobuf record
-	hash, record, err := labgo.ProtoToRecord(e.Node.Alias, e.Node.Key, bcgo.Timestamp(), delta)
+	hash, record, err := ProtoToRecord(e.Node.Alias, e.Node.Key, bcgo.Timestamp(), references, nil, delta)
 	if err != nil {
 		log.Println(err)
 		return
 	}
-	//h := base64.RawURLEncoding.EncodeToString(hash)
-
-	// Create entries
 	entries := []*bcgo.BlockEntry{
-		&bcgo.BlockEntry{
+		{
 			RecordHash: hash,
 			Record:     record,
 		},
 	}
-	// Mine Channel
-	if _, _, err := e.Node.MineEntries(e.Channel, labgo.CHANNEL_THRESHOLD, e.Listener, entries); err != nil {
+	id := base64.RawURLEncoding.EncodeToString(hash)
+	e.Lock()
+	e.written[id] = true
+	e.ids[delta] = id
+	// Read the delta now, rather than once it is mined, so the next delta is written after it
+	e.Deltas[id] = delta
+	e.Entries[id] = entries[0]
+	e.readDeltas([]string{id})
+	e.Unlock()
+
+	if checkpoint != nil {
+		hash, record, err := ProtoToRecord(e.Node.Alias, e.Node.Key, bcgo.Timestamp(), checkpointReferences, map[string]string{
+			META_TYPE: TYPE_CHECKPOINT,
+		}, checkpoint)
+		if err != nil {
+			log.Println(err)
+		} else {
+			entries = append(entries, &bcgo.BlockEntry{
+				RecordHash: hash,
+				Record:     record,
+			})
+		}
+	}
+
+	if err := Mine(e.Node, e.Listener, e.Channel, entries...); err != nil {
+		log.Println(err)
+	}
+}
+
+// EditEntry returns the entry of the record which made the edit with the given id, that of the delta a migrated edit was made from, as its author is the author of the edit, or that of the edit itself.
+// Deltas converted after the migration are held by the sequence under their own id.
+// The caller must hold the lock.
+func (e *ChannelEditor) EditEntry(id string) (*bcgo.BlockEntry, bool) {
+	entry, ok := e.Edits[id]
+	if !ok {
+		entry, ok = e.Entries[id]
+		return entry, ok
+	}
+	if source, ok := e.Entries[e.Sequence.Source(entry.RecordHash)]; ok {
+		return source, true
+	}
+	return entry, true
+}
+
+// revertDeltas returns the spans which revert the given deltas emitted by this editor, once they have been written, in the text of the replay, or of the sequence if the deltas were migrated or written to it.
+// The caller must hold the lock.
+func (e *ChannelEditor) revertDeltas(deltas []*labgo.Delta) ([]*Span, bool) {
+	var ids []string
+	for _, d := range deltas {
+		id, ok := e.ids[d]
+		if !ok {
+			return nil, false
+		}
+		ids = append(ids, id)
+	}
+	if e.Sequence.Len() > 0 {
+		// Reverted by element ids, so edits made by others since are left in place
+		hashes, ok := e.recordHashes(ids)
+		if !ok {
+			return nil, false
+		}
+		return e.Sequence.Revert(hashes), true
+	}
+	if e.replay == nil {
+		return nil, false
+	}
+	return e.replay.Revert(ids)
+}
+
+// recordHashes returns the hashes of the sequence records holding the deltas with the given ids, or false if any is not in the sequence.
+// The caller must hold the lock.
+func (e *ChannelEditor) recordHashes(ids []string) ([][]byte, bool) {
+	var hashes [][]byte
+	for _, id := range ids {
+		if entry, ok := e.Edits[id]; ok {
+			hashes = append(hashes, entry.RecordHash)
+		} else if hash, ok := e.Sequence.DeltaRecord(id); ok {
+			hashes = append(hashes, hash)
+		} else {
+			return nil, false
+		}
+	}
+	return hashes, true
+}
+
+// references returns references to the records of the given deltas.
+// The caller must hold the lock.
+func (e *ChannelEditor) references(ids []string) []*bcgo.Reference {
+	var references []*bcgo.Reference
+	for _, id := range ids {
+		if entry, ok := e.Entries[id]; ok {
+			references = append(references, &bcgo.Reference{
+				Timestamp:   entry.Record.Timestamp,
+				ChannelName: e.Channel.Name,
+				RecordHash:  entry.RecordHash,
+			})
+		}
+	}
+	return references
+}
+
+// writeSequence converts the given delta into a SequenceEdit, and writes it to the channel.
+// The caller must hold the lock, which is released before mining.
+func (e *ChannelEditor) writeSequence(delta *labgo.Delta) {
+	// The sequence does not yet hold the delta, so the offset is in bytes of its text
+	offset := uint64(e.Sequence.Text().ByteToRune(int(delta.Offset)))
+	edit := e.Sequence.Edit(offset, uint64(utf8.RuneCount(delta.Remove)), string(delta.Add))
+	if reverted, ok := e.reverts[delta]; ok {
+		// Show the runes removed by the reverted deltas again, rather than inserting copies of them
+		var ids []string
+		for _, d := range reverted {
+			if id, ok := e.ids[d]; ok {
+				ids = append(ids, id)
+			}
+		}
+		if hashes, ok := e.recordHashes(ids); ok {
+			e.Sequence.Restore(edit, hashes)
+		}
+	}
+
+	// Create protobuf record
+	hash, record, err := ProtoToRecord(e.Node.Alias, e.Node.Key, bcgo.Timestamp(), nil, map[string]string{
+		META_TYPE: TYPE_SEQUENCE,
+	}, edit)
+	if err != nil {
+		e.Unlock()
 		log.Println(err)
 		return
 	}
+	// Integrate now so later edits can refer to the runes this one inserted
+	e.Sequence.Integrate(hash, edit)
+	id := base64.RawURLEncoding.EncodeToString(hash)
+	e.ids[delta] = id
+	e.Edits[id] = &bcgo.BlockEntry{
+		RecordHash: hash,
+		Record:     record,
+	}
+	if e.Blame {
+		e.updateBlame()
+	}
+	e.Unlock()
+
+	if err := Mine(e.Node, e.Listener, e.Channel, &bcgo.BlockEntry{
+		RecordHash: hash,
+		Record:     record,
+	}); err != nil {
+		log.Println(err)
+	}
+}
+
+// Mine mines the given entries onto the given channel, and pushes it to peers.
+func Mine(node *bcgo.Node, listener bcgo.MiningListener, channel *bcgo.Channel, entries ...*bcgo.BlockEntry) error {
+	// Mine Channel
+	if _, _, err := node.MineEntries(channel, labgo.CHANNEL_THRESHOLD, listener, entries); err != nil {
+		return err
+	}
 
-	if e.Node.Network != nil {
+	if node.Network != nil {
 		// Push Update to Peers
-		if err := e.Channel.Push(e.Node.Cache, e.Node.Network); err != nil {
-			log.Println(err)
-			return
+		if err := channel.Push(node.Cache, node.Network); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ProtoToRecord is like labgo.ProtoToRecord, but also adds the given references and meta data to the record.
+func ProtoToRecord(alias string, key *rsa.PrivateKey, timestamp uint64, references []*bcgo.Reference, meta map[string]string, protobuf proto.Message) ([]byte, *bcgo.Record, error) {
+	// Marshal Protobuf
+	data, err := proto.Marshal(protobuf)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Create Record
+	_, record, err := bcgo.CreateRecord(timestamp, alias, key, nil, references, data)
+	if err != nil {
+		return nil, nil, err
+	}
+	// Meta data is covered by the record hash
+	record.Meta = meta
+
+	hash, err := cryptogo.HashProtobuf(record)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return hash, record, nil
+}
+
+// ParentIds returns the ids of the records in the given channel which are referenced by the given record.
+func ParentIds(channel string, record *bcgo.Record) []string {
+	var ids []string
+	for _, r := range record.Reference {
+		if r.ChannelName == channel && len(r.RecordHash) > 0 {
+			ids = append(ids, base64.RawURLEncoding.EncodeToString(r.RecordHash))
+		}
+	}
+	return ids
+}
+
+// Parents returns the ids of the known entries each of the given entries was written after.
+// Entries without any parent reference, as those written before parents were recorded, are chained in order of timestamp, creator alias, then record hash.
+// Entries whose parents are not known are left without any, so they are ordered by creator alias then record hash, and not by a timestamp set by their writer.
+func Parents(channel string, entries map[string]*bcgo.BlockEntry) map[string][]string {
+	parents := make(map[string][]string)
+	var roots []string
+	for id, entry := range entries {
+		references := ParentIds(channel, entry.Record)
+		for _, p := range references {
+			if _, ok := entries[p]; ok && p != id {
+				parents[id] = append(parents[id], p)
+			}
+		}
+		if len(references) == 0 {
+			roots = append(roots, id)
+		}
+	}
+	sort.Slice(roots, func(i, j int) bool {
+		a, b := entries[roots[i]], entries[roots[j]]
+		if a.Record.Timestamp != b.Record.Timestamp {
+			return a.Record.Timestamp < b.Record.Timestamp
 		}
+		return lessEntry(a, b)
+	})
+	for i := 1; i < len(roots); i++ {
+		parents[roots[i]] = []string{roots[i-1]}
+	}
+	return parents
+}
+
+// OrderDeltas returns the ids of the given entries ordered so that each delta follows the deltas it was written after.
+// When several deltas could come next, the first by creator alias, then record hash, is chosen, so every replica with the same entries produces the same order.
+func OrderDeltas(channel string, entries map[string]*bcgo.BlockEntry) []string {
+	parents := Parents(channel, entries)
+	children := make(map[string][]string)
+	waiting := make(map[string]int)
+	var ready []string
+	for id := range entries {
+		waiting[id] = len(parents[id])
+		for _, p := range parents[id] {
+			children[p] = append(children[p], id)
+		}
+		if waiting[id] == 0 {
+			ready = append(ready, id)
+		}
+	}
+	order := make([]string, 0, len(entries))
+	for len(ready) > 0 {
+		next := 0
+		for i := range ready {
+			if lessEntry(entries[ready[i]], entries[ready[next]]) {
+				next = i
+			}
+		}
+		id := ready[next]
+		ready = append(ready[:next], ready[next+1:]...)
+		order = append(order, id)
+		for _, child := range children[id] {
+			waiting[child]--
+			if waiting[child] == 0 {
+				ready = append(ready, child)
+			}
+		}
+	}
+	return order
+}
+
+func lessEntry(a, b *bcgo.BlockEntry) bool {
+	if a.Record.Creator != b.Record.Creator {
+		return a.Record.Creator < b.Record.Creator
 	}
+	return bytes.Compare(a.RecordHash, b.RecordHash) < 0
 }
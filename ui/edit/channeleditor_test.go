@@ -0,0 +1,443 @@
+/*
+ * Copyright 2020 Aletheia Ware LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package edit_test
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"fyne.io/fyne"
+	"fyne.io/fyne/test"
+	"github.com/AletheiaWareLLC/bcgo"
+	"github.com/AletheiaWareLLC/labfynego/ui/edit"
+	"github.com/AletheiaWareLLC/labgo"
+	"reflect"
+	"testing"
+	"time"
+)
+
+const testChannel = "Lab-File-Test"
+
+func makeEntry(t *testing.T, entries map[string]*bcgo.BlockEntry, hash string, creator string, timestamp uint64, parents ...string) {
+	t.Helper()
+	entry := &bcgo.BlockEntry{
+		RecordHash: []byte(hash),
+		Record: &bcgo.Record{
+			Timestamp: timestamp,
+			Creator:   creator,
+		},
+	}
+	for _, parent := range parents {
+		entry.Record.Reference = append(entry.Record.Reference, &bcgo.Reference{
+			ChannelName: testChannel,
+			RecordHash:  []byte(parent),
+		})
+	}
+	entries[id(hash)] = entry
+}
+
+func id(hash string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(hash))
+}
+
+func TestOrderDeltas(t *testing.T) {
+	for name, tt := range map[string]struct {
+		entries func(*testing.T, map[string]*bcgo.BlockEntry)
+		want    []string
+	}{
+		"chain_ignores_timestamps": {
+			entries: func(t *testing.T, e map[string]*bcgo.BlockEntry) {
+				makeEntry(t, e, "a", "alice", 30)
+				makeEntry(t, e, "b", "bob", 10, "a")
+				makeEntry(t, e, "c", "alice", 20, "b")
+			},
+			want: []string{id("a"), id("b"), id("c")},
+		},
+		"siblings_by_creator": {
+			entries: func(t *testing.T, e map[string]*bcgo.BlockEntry) {
+				makeEntry(t, e, "a", "alice", 10)
+				makeEntry(t, e, "z", "bob", 20, "a")
+				makeEntry(t, e, "y", "alice", 20, "a")
+			},
+			want: []string{id("a"), id("y"), id("z")},
+		},
+		"siblings_by_hash": {
+			entries: func(t *testing.T, e map[string]*bcgo.BlockEntry) {
+				makeEntry(t, e, "a", "alice", 10)
+				makeEntry(t, e, "c", "bob", 20, "a")
+				makeEntry(t, e, "b", "bob", 20, "a")
+			},
+			want: []string{id("a"), id("b"), id("c")},
+		},
+		"descendants_before_siblings": {
+			entries: func(t *testing.T, e map[string]*bcgo.BlockEntry) {
+				makeEntry(t, e, "a", "alice", 10)
+				makeEntry(t, e, "b", "alice", 20, "a")
+				makeEntry(t, e, "c", "bob", 20, "a")
+				makeEntry(t, e, "d", "alice", 30, "b")
+			},
+			want: []string{id("a"), id("b"), id("d"), id("c")},
+		},
+		"legacy_by_timestamp": {
+			entries: func(t *testing.T, e map[string]*bcgo.BlockEntry) {
+				makeEntry(t, e, "c", "alice", 10)
+				makeEntry(t, e, "a", "alice", 30)
+				makeEntry(t, e, "b", "alice", 20)
+			},
+			want: []string{id("c"), id("b"), id("a")},
+		},
+		"merge_after_all_parents": {
+			entries: func(t *testing.T, e map[string]*bcgo.BlockEntry) {
+				makeEntry(t, e, "a", "alice", 10)
+				makeEntry(t, e, "b", "alice", 20, "a")
+				makeEntry(t, e, "c", "bob", 20, "a")
+				makeEntry(t, e, "d", "alice", 30, "b", "c")
+				makeEntry(t, e, "e", "bob", 30, "c")
+			},
+			want: []string{id("a"), id("b"), id("c"), id("d"), id("e")},
+		},
+		"unknown_parent_by_creator": {
+			entries: func(t *testing.T, e map[string]*bcgo.BlockEntry) {
+				makeEntry(t, e, "a", "alice", 20)
+				makeEntry(t, e, "b", "bob", 10, "missing")
+			},
+			want: []string{id("a"), id("b")},
+		},
+		"unknown_parent_not_chained": {
+			entries: func(t *testing.T, e map[string]*bcgo.BlockEntry) {
+				makeEntry(t, e, "c", "alice", 10)
+				makeEntry(t, e, "d", "alice", 20)
+				makeEntry(t, e, "e", "aaron", 15, "missing")
+			},
+			want: []string{id("e"), id("c"), id("d")},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			// Map iteration order is random, so repeat to check the order is deterministic
+			for i := 0; i < 10; i++ {
+				entries := make(map[string]*bcgo.BlockEntry)
+				tt.entries(t, entries)
+				if got := edit.OrderDeltas(testChannel, entries); !reflect.DeepEqual(got, tt.want) {
+					t.Fatalf("Incorrect order; expected '%v', got '%v'", tt.want, got)
+				}
+			}
+		})
+	}
+}
+
+func makeNode(t *testing.T, alias string) *bcgo.Node {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &bcgo.Node{
+		Alias:    alias,
+		Key:      key,
+		Cache:    bcgo.NewMemoryCache(10),
+		Channels: make(map[string]*bcgo.Channel),
+	}
+}
+
+// writeDelta writes the given delta to the editor's channel as another node, after the deltas the editor has read.
+func writeDelta(t *testing.T, node *bcgo.Node, e *edit.ChannelEditor, delta *labgo.Delta) {
+	t.Helper()
+	var references []*bcgo.Reference
+	for _, h := range e.Heads {
+		entry := e.Entries[h]
+		references = append(references, &bcgo.Reference{
+			Timestamp:   entry.Record.Timestamp,
+			ChannelName: e.Channel.Name,
+			RecordHash:  entry.RecordHash,
+		})
+	}
+	hash, record, err := edit.ProtoToRecord(node.Alias, node.Key, bcgo.Timestamp(), references, nil, delta)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := edit.Mine(e.Node, nil, e.Channel, &bcgo.BlockEntry{
+		RecordHash: hash,
+		Record:     record,
+	}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// writeEdit mines a sequence record, written by the given node, which removes the given number of runes at the given offset in the text of the editor, and inserts the given text in their place.
+func writeEdit(t *testing.T, node *bcgo.Node, e *edit.ChannelEditor, offset, remove uint64, add string) {
+	t.Helper()
+	e.Lock()
+	sequenceEdit := e.Sequence.Edit(offset, remove, add)
+	e.Unlock()
+	hash, record, err := edit.ProtoToRecord(node.Alias, node.Key, bcgo.Timestamp(), nil, map[string]string{
+		edit.META_TYPE: edit.TYPE_SEQUENCE,
+	}, sequenceEdit)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := edit.Mine(e.Node, nil, e.Channel, &bcgo.BlockEntry{
+		RecordHash: hash,
+		Record:     record,
+	}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestChannelEditor_Undo(t *testing.T) {
+	test.NewApp()
+	alice := makeNode(t, "alice")
+	channel := labgo.OpenFileChannel("Test")
+	alice.AddChannel(channel)
+	e := edit.NewChannelEditor(alice, nil, channel, nil)
+	e.Timeout = 0
+	for _, r := range "Hello" {
+		e.TypedRune(r)
+	}
+	e.TypedKey(&fyne.KeyEvent{Name: fyne.KeyLeft})
+	e.TypedKey(&fyne.KeyEvent{Name: fyne.KeyLeft})
+	e.TypedRune('_')
+	if want, got := uint64(4), e.Cursor; got != want {
+		t.Errorf("Incorrect cursor; expected '%d', got '%d'", want, got)
+	}
+
+	writeDelta(t, makeNode(t, "bob"), e, &labgo.Delta{Offset: 6, Add: []byte(" World")})
+	for _, tt := range []struct {
+		edit func()
+		want string
+	}{
+		{e.Undo, "Hello World"},
+		{e.Undo, "Hell World"},
+		{e.Redo, "Hello World"},
+		{e.Undo, "Hell World"},
+		{e.Undo, "Hel World"},
+		{e.Undo, "He World"},
+		{e.Undo, "H World"},
+		{e.Undo, " World"},
+		// Nothing left to undo
+		{e.Undo, " World"},
+		{e.Redo, "H World"},
+	} {
+		tt.edit()
+		if got := e.Buffer.String(); got != tt.want {
+			t.Fatalf("Incorrect buffer; expected '%s', got '%s'", tt.want, got)
+		}
+		// The deltas written must produce the same buffer
+		if got, _ := edit.ReplayDeltas(e.Order, e.Parents, e.Deltas); string(got) != tt.want {
+			t.Fatalf("Incorrect replayed buffer; expected '%s', got '%s'", tt.want, got)
+		}
+	}
+	if want, got := 16, len(e.Order); got != want {
+		t.Errorf("Incorrect number of deltas; expected '%d', got '%d'", want, got)
+	}
+}
+
+func TestChannelEditor_LateDelta(t *testing.T) {
+	test.NewApp()
+	alice := makeNode(t, "alice")
+	channel := labgo.OpenFileChannel("Test")
+	alice.AddChannel(channel)
+	e := edit.NewChannelEditor(alice, nil, channel, nil)
+	e.Timeout = 0
+	for _, r := range "Hello" {
+		e.TypedRune(r)
+	}
+	if _, err := edit.MigrateDeltas(alice, nil, channel); err != nil {
+		t.Fatal(err)
+	}
+	// Bob has not seen the migration
+	writeDelta(t, makeNode(t, "bob"), e, &labgo.Delta{Offset: 5, Add: []byte(" World")})
+	if want, got := "Hello World", e.Buffer.String(); got != want {
+		t.Errorf("Incorrect buffer; expected '%s', got '%s'", want, got)
+	}
+	// Searches read the same text without an editor
+	text, err := edit.ReadText(alice, channel)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, got := "Hello World", string(text); got != want {
+		t.Errorf("Incorrect text; expected '%s', got '%s'", want, got)
+	}
+	e.Lock()
+	defer e.Unlock()
+	if want, got := 5, len(e.Edits); got != want {
+		t.Fatalf("Incorrect number of edits; expected '%d', got '%d'", want, got)
+	}
+	// Migrated edits are made by the author of the delta they came from
+	for id := range e.Edits {
+		entry, ok := e.EditEntry(id)
+		if !ok {
+			t.Fatalf("Missing entry for edit '%s'", id)
+		}
+		if _, ok := e.Deltas[base64.RawURLEncoding.EncodeToString(entry.RecordHash)]; !ok {
+			t.Errorf("Incorrect entry for edit '%s'; expected a delta", id)
+		}
+	}
+}
+
+func TestChannelEditor_Close(t *testing.T) {
+	test.NewApp()
+	alice := makeNode(t, "alice")
+	channel := labgo.OpenFileChannel("Test")
+	alice.AddChannel(channel)
+	e := edit.NewChannelEditor(alice, nil, channel, nil)
+	e.Timeout = time.Hour
+	for _, r := range "Hello" {
+		e.TypedRune(r)
+	}
+	e.Close()
+	// Pending edits are written as the editor is closed
+	text, err := edit.ReadText(alice, channel)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, got := "Hello", string(text); got != want {
+		t.Errorf("Incorrect text; expected '%s', got '%s'", want, got)
+	}
+	// Records written after are not read
+	writeDelta(t, makeNode(t, "bob"), e, &labgo.Delta{Offset: 5, Add: []byte(" World")})
+	e.TypedRune('!')
+	if want, got := "Hello", e.Buffer.String(); got != want {
+		t.Errorf("Incorrect buffer; expected '%s', got '%s'", want, got)
+	}
+}
+
+func TestChannelEditor_DivergentCheckpoint(t *testing.T) {
+	test.NewApp()
+	alice := makeNode(t, "alice")
+	channel := labgo.OpenFileChannel("Test")
+	alice.AddChannel(channel)
+	a := edit.NewChannelEditor(alice, nil, channel, nil)
+	a.Timeout = 0
+	for _, r := range "Hello" {
+		a.TypedRune(r)
+	}
+	// Mallory checkpoints different content
+	hash, record, err := edit.ProtoToRecord("mallory", alice.Key, bcgo.Timestamp(), []*bcgo.Reference{{
+		ChannelName: channel.Name,
+		RecordHash:  a.Entries[a.Heads[0]].RecordHash,
+	}}, map[string]string{
+		edit.META_TYPE: edit.TYPE_CHECKPOINT,
+	}, edit.NewCheckpoint([]byte("Evil")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := edit.Mine(alice, nil, channel, &bcgo.BlockEntry{
+		RecordHash: hash,
+		Record:     record,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	b := edit.NewChannelEditor(alice, nil, channel, nil)
+	// Read only until the checkpoint is found to be divergent, and the deltas replayed instead
+	for i := 0; i < 100; i++ {
+		b.Lock()
+		buffer, readOnly := b.Buffer.String(), b.ReadOnly
+		b.Unlock()
+		if buffer == "Hello" && !readOnly {
+			return
+		}
+		if buffer != "Hello" && !readOnly {
+			t.Fatalf("Incorrect buffer; expected '%s' or read only, got '%s'", "Hello", buffer)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Error("Expected divergent checkpoint to be replaced")
+}
+
+func TestChannelEditor_UndoConcurrent(t *testing.T) {
+	test.NewApp()
+	zed := makeNode(t, "zed")
+	channel := labgo.OpenFileChannel("Test")
+	zed.AddChannel(channel)
+	e := edit.NewChannelEditor(zed, nil, channel, nil)
+	e.Timeout = 0
+	writeDelta(t, makeNode(t, "bob"), e, &labgo.Delta{Add: []byte("Hello World")})
+	base := e.Entries[e.Heads[0]]
+	e.Cursor = 5
+	e.TypedRune('!')
+	// Alice removes the text around the exclamation mark without seeing it, and is ordered before it
+	alice := makeNode(t, "alice")
+	hash, record, err := edit.ProtoToRecord(alice.Alias, alice.Key, bcgo.Timestamp(), []*bcgo.Reference{{
+		ChannelName: channel.Name,
+		RecordHash:  base.RecordHash,
+	}}, nil, &labgo.Delta{Offset: 3, Remove: []byte("lo Wo")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := edit.Mine(zed, nil, channel, &bcgo.BlockEntry{
+		RecordHash: hash,
+		Record:     record,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range []struct {
+		edit func()
+		want string
+	}{
+		{func() {}, "Hel!rld"},
+		{e.Undo, "Helrld"},
+		{e.Redo, "Hel!rld"},
+		{e.Undo, "Helrld"},
+	} {
+		tt.edit()
+		if got := e.Buffer.String(); got != tt.want {
+			t.Fatalf("Incorrect buffer; expected '%s', got '%s'", tt.want, got)
+		}
+		if got, _ := edit.ReplayDeltas(e.Order, e.Parents, e.Deltas); string(got) != tt.want {
+			t.Fatalf("Incorrect replayed buffer; expected '%s', got '%s'", tt.want, got)
+		}
+	}
+}
+
+func TestChannelEditor_UndoSequence(t *testing.T) {
+	test.NewApp()
+	alice := makeNode(t, "alice")
+	channel := labgo.OpenFileChannel("Test")
+	alice.AddChannel(channel)
+	e := edit.NewChannelEditor(alice, nil, channel, nil)
+	e.Format = edit.FORMAT_SEQUENCE
+	e.Timeout = 0
+	for _, r := range "Hello" {
+		e.TypedRune(r)
+	}
+	// Bob adds to the text
+	writeEdit(t, makeNode(t, "bob"), e, 5, 0, " World")
+	e.Cursor = 2
+	e.Selection = 4
+	e.IsSelecting = true
+	e.TypedRune('L')
+	for _, tt := range []struct {
+		edit func()
+		want string
+	}{
+		{func() {}, "HeLo World"},
+		{e.Undo, "Hello World"},
+		{e.Undo, "Hell World"},
+		{e.Undo, "Hel World"},
+		{e.Redo, "Hell World"},
+		{e.Redo, "Hello World"},
+		{e.Redo, "HeLo World"},
+	} {
+		tt.edit()
+		if got := e.Buffer.String(); got != tt.want {
+			t.Fatalf("Incorrect buffer; expected '%s', got '%s'", tt.want, got)
+		}
+		if got := string(e.Sequence.Runes()); got != tt.want {
+			t.Fatalf("Incorrect sequence; expected '%s', got '%s'", tt.want, got)
+		}
+	}
+}
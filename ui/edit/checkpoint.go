@@ -0,0 +1,75 @@
+/*
+ * Copyright 2020 Aletheia Ware LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package edit
+
+import (
+	"bytes"
+	"github.com/AletheiaWareLLC/bcgo"
+	"github.com/AletheiaWareLLC/cryptogo"
+	"github.com/AletheiaWareLLC/labgo"
+)
+
+const (
+	// Value of the record meta data type of a Checkpoint
+	TYPE_CHECKPOINT = "checkpoint"
+
+	// Number of deltas written between checkpoints
+	CHECKPOINT_INTERVAL = 1000
+)
+
+func NewCheckpoint(buffer []byte) *Checkpoint {
+	return &Checkpoint{
+		Hash: cryptogo.Hash(buffer),
+		Body: buffer,
+	}
+}
+
+// Verify returns true if the checkpoint holds the given buffer.
+func (c *Checkpoint) Verify(buffer []byte) bool {
+	return bytes.Equal(c.Hash, cryptogo.Hash(buffer)) && bytes.Equal(c.Body, buffer)
+}
+
+// CheckpointOrder returns the order of the deltas covered by a checkpoint written after the given heads, or false if any head is not known.
+// Each replica orders the same deltas the same way, so this is the order the writer applied to reach the checkpoint.
+func CheckpointOrder(channel string, entries map[string]*bcgo.BlockEntry, parents map[string][]string, heads []string) ([]string, bool) {
+	if len(heads) == 0 {
+		return nil, false
+	}
+	covered := make(map[string]*bcgo.BlockEntry)
+	for _, h := range heads {
+		if _, ok := entries[h]; !ok {
+			return nil, false
+		}
+		covered[h] = entries[h]
+		for id := range Ancestors(parents, h) {
+			covered[id] = entries[id]
+		}
+	}
+	return OrderDeltas(channel, covered), true
+}
+
+// VerifyCheckpoint replays the given deltas in order, and returns true if the result is held by the given checkpoint.
+// If the given trusted snapshot, such as one kept locally, holds the start of the order, only the deltas after it are replayed.
+func VerifyCheckpoint(checkpoint *Checkpoint, trusted *Snapshot, order []string, parents map[string][]string, deltas map[string]*labgo.Delta) bool {
+	replay := NewReplay()
+	if trusted != nil {
+		// Rewinds to the start if the order does not start with the snapshot
+		replay = NewReplayFromSnapshot(trusted, parents)
+	}
+	replay.Apply(order, parents, deltas)
+	return checkpoint.Verify([]byte(replay.Buffer.String()))
+}
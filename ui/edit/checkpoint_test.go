@@ -0,0 +1,133 @@
+/*
+ * Copyright 2020 Aletheia Ware LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package edit_test
+
+import (
+	"github.com/AletheiaWareLLC/bcgo"
+	"github.com/AletheiaWareLLC/labfynego/ui/edit"
+	"github.com/AletheiaWareLLC/labgo"
+	"github.com/golang/protobuf/proto"
+	"reflect"
+	"testing"
+)
+
+func TestCheckpoint_Verify(t *testing.T) {
+	want := edit.NewCheckpoint([]byte("Hello World"))
+	data, err := proto.Marshal(want)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := &edit.Checkpoint{}
+	if err := proto.Unmarshal(data, got); err != nil {
+		t.Fatal(err)
+	}
+	if !got.Verify([]byte("Hello World")) {
+		t.Error("Expected checkpoint to hold buffer")
+	}
+	if got.Verify([]byte("Hello")) {
+		t.Error("Expected checkpoint not to hold different buffer")
+	}
+	got.Body = []byte("Goodbye World")
+	if got.Verify([]byte("Goodbye World")) {
+		t.Error("Expected checkpoint with changed body not to hold buffer")
+	}
+}
+
+func TestCheckpointOrder(t *testing.T) {
+	entries := make(map[string]*bcgo.BlockEntry)
+	deltas := make(map[string]*labgo.Delta)
+	makeDelta(t, entries, deltas, "a", "alice", 10, &labgo.Delta{Add: []byte("Hello World")})
+	makeDelta(t, entries, deltas, "b", "alice", 20, &labgo.Delta{Offset: 5, Add: []byte(",")}, "a")
+	makeDelta(t, entries, deltas, "c", "bob", 20, &labgo.Delta{Offset: 11, Add: []byte("!")}, "a")
+	makeDelta(t, entries, deltas, "d", "bob", 30, &labgo.Delta{Offset: 0, Remove: []byte("Hello"), Add: []byte("Hi")}, "b", "c")
+	parents := edit.Parents(testChannel, entries)
+
+	for name, tt := range map[string]struct {
+		heads  []string
+		want   []string
+		buffer string
+	}{
+		"before_concurrent": {
+			heads:  []string{id("c")},
+			want:   []string{id("a"), id("c")},
+			buffer: "Hello World!",
+		},
+		"concurrent": {
+			heads:  []string{id("b"), id("c")},
+			want:   []string{id("a"), id("b"), id("c")},
+			buffer: "Hello, World!",
+		},
+		"merge": {
+			heads:  []string{id("d")},
+			want:   []string{id("a"), id("b"), id("c"), id("d")},
+			buffer: "Hi, World!",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			got, ok := edit.CheckpointOrder(testChannel, entries, parents, tt.heads)
+			if !ok {
+				t.Fatal("Expected heads to be known")
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("Incorrect order; expected '%v', got '%v'", tt.want, got)
+			}
+			if !edit.VerifyCheckpoint(edit.NewCheckpoint([]byte(tt.buffer)), nil, got, parents, deltas) {
+				t.Error("Expected checkpoint to be verified")
+			}
+			if edit.VerifyCheckpoint(edit.NewCheckpoint([]byte("Divergent")), nil, got, parents, deltas) {
+				t.Error("Expected divergent checkpoint not to be verified")
+			}
+			// Snapshots which do not start the order are not used
+			if !edit.VerifyCheckpoint(edit.NewCheckpoint([]byte(tt.buffer)), &edit.Snapshot{Order: []string{id("x")}, Buffer: []byte("Other")}, got, parents, deltas) {
+				t.Error("Expected checkpoint to be verified")
+			}
+		})
+	}
+
+	// Only the deltas after a trusted snapshot are replayed
+	trusted := &edit.Snapshot{
+		Order:  []string{id("a")},
+		Buffer: []byte("Howdy World"),
+	}
+	for name, tt := range map[string]struct {
+		heads  []string
+		buffer string
+	}{
+		"before_concurrent": {
+			heads:  []string{id("c")},
+			buffer: "Howdy World!",
+		},
+		"concurrent": {
+			heads:  []string{id("b"), id("c")},
+			buffer: "Howdy, World!",
+		},
+	} {
+		t.Run("trusted_"+name, func(t *testing.T) {
+			order, ok := edit.CheckpointOrder(testChannel, entries, parents, tt.heads)
+			if !ok {
+				t.Fatal("Expected heads to be known")
+			}
+			if !edit.VerifyCheckpoint(edit.NewCheckpoint([]byte(tt.buffer)), trusted, order, parents, deltas) {
+				t.Error("Expected checkpoint to be verified from the trusted snapshot")
+			}
+		})
+	}
+
+	if _, ok := edit.CheckpointOrder(testChannel, entries, parents, []string{id("e")}); ok {
+		t.Error("Expected unknown head not to be known")
+	}
+}
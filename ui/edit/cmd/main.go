@@ -83,7 +83,7 @@ func main() {
 	app := app.New()
 
 	// Create editor
-	editor := edit.NewChannelEditor(node, listener, channel)
+	editor := edit.NewChannelEditor(node, listener, channel, nil)
 
 	// Create window
 	window := app.NewWindow("LAB")
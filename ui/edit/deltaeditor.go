@@ -18,23 +18,56 @@ package edit
 
 import (
 	"fyne.io/fyne"
+	"fyne.io/fyne/driver/desktop"
 	"fyne.io/fyne/theme"
 	"github.com/AletheiaWareLLC/labgo"
 	"log"
+	"time"
+	"unicode/utf8"
 )
 
+const (
+	// Duration without typing after which pending edits are emitted as a delta
+	DELTA_TIMEOUT = 2 * time.Second
+)
+
+// DeltaEditor emits edits as labgo.Deltas, whose offsets are in bytes of UTF-8 encoded text, unlike the rune offsets used by Editor.
 type DeltaEditor struct {
 	Editor
 
 	Deltas map[string]*labgo.Delta
 	Order  []string
+	Heads  []string // Ids of the deltas in Order which no other delta was written after
 
+	// Timeout is how long to wait without typing before emitting pending edits, if not positive every edit is emitted immediately
+	Timeout time.Duration
+
+	// OnDelta is passed each delta emitted, and the id of the last delta it was written after, or an empty string if there were none
 	OnDelta func(string, *labgo.Delta)
+
+	pending *labgo.Delta                    // Unlike emitted deltas, the offset of the pending delta is in runes
+	emitted map[*labgo.Delta][]string       // Ids of every delta each delta being passed to OnDelta was written after
+	reverts map[*labgo.Delta][]*labgo.Delta // Deltas reverted by each delta being passed to OnDelta by Undo or Redo
+	timer   *time.Timer
+	timerId uint64    // Id of the timer, so one which fires after being replaced does nothing
+	undos   []*change // Edits emitted by this editor which can be undone, most recent last
+	redos   []*change // Undone edits which can be redone, most recent last
+
+	// reverting, if set, returns the spans which revert the given deltas emitted by this editor in the buffer as it is, or false if it does not know them, in which case the spans kept when they were emitted are used.
+	// It is called with the lock held.
+	reverting func([]*labgo.Delta) ([]*Span, bool)
+}
+
+// change is an edit emitted by the editor, which can be undone or redone.
+type change struct {
+	deltas []*labgo.Delta // Deltas which made the edit
+	spans  []*Span        // Spans reverting the edit in the buffer it was made to
 }
 
 func NewDeltaEditor(callback func(parent string, delta *labgo.Delta)) *DeltaEditor {
 	e := &DeltaEditor{
 		Editor: Editor{
+			Buffer:    &Rope{},
 			TextAlign: fyne.TextAlignLeading,
 			TextColor: theme.TextColor(),
 			TextSize:  theme.TextSize(),
@@ -43,59 +76,418 @@ func NewDeltaEditor(callback func(parent string, delta *labgo.Delta)) *DeltaEdit
 		},
 		OnDelta: callback,
 		Deltas:  make(map[string]*labgo.Delta),
+		Timeout: DELTA_TIMEOUT,
+		emitted: make(map[*labgo.Delta][]string),
+		reverts: make(map[*labgo.Delta][]*labgo.Delta),
 	}
 	e.ExtendBaseWidget(e)
 	e.AddShortcuts()
 	return e
 }
 
+func (e *DeltaEditor) AddShortcuts() {
+	e.Editor.AddShortcuts()
+	e.shortcut.AddShortcut(&desktop.CustomShortcut{KeyName: fyne.KeyZ, Modifier: desktop.ControlModifier}, func(se fyne.Shortcut) {
+		e.Undo()
+	})
+	e.shortcut.AddShortcut(&desktop.CustomShortcut{KeyName: fyne.KeyZ, Modifier: desktop.ControlModifier | desktop.ShiftModifier}, func(se fyne.Shortcut) {
+		e.Redo()
+	})
+}
+
+func (e *DeltaEditor) FocusLost() {
+	e.Flush()
+	e.Editor.FocusLost()
+}
+
+func (e *DeltaEditor) MouseDown(event *desktop.MouseEvent) {
+	e.Editor.MouseDown(event)
+	e.flushIfMoved()
+}
+
+func (e *DeltaEditor) Tapped(event *fyne.PointEvent) {
+	e.Editor.Tapped(event)
+	e.flushIfMoved()
+}
+
+func (e *DeltaEditor) TypedKey(event *fyne.KeyEvent) {
+	log.Println("DeltaEditor.TypedKey:", event)
+	e.Lock()
+	length := uint64(e.Buffer.Len())
+	if e.Cursor > length {
+		e.Cursor = length
+	}
+	selecting := e.IsSelecting
+	e.Unlock()
+	switch event.Name {
+	case fyne.KeyBackspace:
+		if selecting {
+			e.EraseSelection()
+			return
+		}
+		e.edit(func(pending *labgo.Delta) {
+			if e.Cursor == 0 {
+				return
+			}
+			e.Cursor--
+			removed := e.Buffer.RuneAt(int(e.Cursor))
+			e.Buffer.Remove(int(e.Cursor), int(e.Cursor)+1)
+			if len(pending.Add) > 0 {
+				// Take back the last rune added
+				_, size := utf8.DecodeLastRune(pending.Add)
+				pending.Add = pending.Add[:len(pending.Add)-size]
+			} else {
+				// Extend removal backwards
+				pending.Offset--
+				pending.Remove = append([]byte(string(removed)), pending.Remove...)
+			}
+		})
+	case fyne.KeyDelete:
+		if selecting {
+			e.EraseSelection()
+			return
+		}
+		e.edit(func(pending *labgo.Delta) {
+			if e.Cursor >= uint64(e.Buffer.Len()) {
+				return
+			}
+			// Extend removal forwards
+			pending.Remove = append(pending.Remove, []byte(string(e.Buffer.RuneAt(int(e.Cursor))))...)
+			e.Buffer.Remove(int(e.Cursor), int(e.Cursor)+1)
+		})
+	case fyne.KeyReturn, fyne.KeyEnter:
+		e.add('\n')
+	case fyne.KeyTab:
+		e.add('\t')
+	default:
+		// Not an edit, let Editor move the cursor
+		e.Editor.TypedKey(event)
+		e.flushIfMoved()
+	}
+}
+
 func (e *DeltaEditor) TypedRune(r rune) {
 	log.Println("DeltaEditor.TypedRune:", r)
-	// TODO add runes to list until timeout or cursor is moved elsewhere, then create file delta
-	var parentRecordId string
+	e.add(r)
+}
+
+func (e *DeltaEditor) PasteFromClipboard(clipboard fyne.Clipboard) {
+	log.Println("DeltaEditor.PasteFromClipboard:", clipboard)
+	content := clipboard.Content()
+	if content == "" {
+		return
+	}
+	e.add([]rune(content)...)
+}
+
+func (e *DeltaEditor) EraseSelection() {
+	log.Println("DeltaEditor.EraseSelection")
+	e.Lock()
+	selecting := e.IsSelecting
+	e.Unlock()
+	if selecting {
+		// Starting a new edit removes the selection
+		e.edit(func(*labgo.Delta) {})
+	}
+}
+
+// Flush emits any pending edits as a delta.
+func (e *DeltaEditor) Flush() {
 	e.Lock()
-	delta := &labgo.Delta{
-		Offset: e.Cursor,
-		Add:    []byte(string(r)),
+	delta, parents := e.takePending()
+	e.Unlock()
+	if delta != nil {
+		e.emit(parents, delta)
 	}
-	if len(e.Order) > 0 {
-		parentRecordId = e.Order[len(e.Order)-1]
+}
+
+// flushTimer emits any pending edits once the timer with the given id fires, unless it has been stopped, or replaced by a later edit, since.
+// The pending delta is taken under the lock, so it cannot change as it is emitted, and the editor is refreshed by emit, which the renderer serializes with the refreshes made as the user types.
+func (e *DeltaEditor) flushTimer(id uint64) {
+	e.Lock()
+	if e.timer == nil || e.timerId != id {
+		e.Unlock()
+		return
 	}
+	delta, parents := e.takePending()
 	e.Unlock()
-	if e.OnDelta != nil {
-		e.OnDelta(parentRecordId, delta)
+	if delta != nil {
+		e.emit(parents, delta)
 	}
 }
 
-func (e *DeltaEditor) PasteFromClipboard(clipboard fyne.Clipboard) {
-	log.Println("DeltaEditor.PasteFromClipboard:", clipboard)
-	var parentRecordId string
+// Undo emits deltas reverting the last edit emitted by this editor which has not been undone, leaving edits made by others unchanged, whether made before or since.
+func (e *DeltaEditor) Undo() {
+	log.Println("DeltaEditor.Undo")
+	if e.ReadOnly {
+		return
+	}
+	e.Flush()
+	e.Lock()
+	if len(e.undos) == 0 {
+		e.Unlock()
+		return
+	}
+	c := e.undos[len(e.undos)-1]
+	e.undos = e.undos[:len(e.undos)-1]
+	deltas, inverse := e.revert(e.reverse(c))
+	e.redos = append(e.redos, &change{
+		deltas: deltas,
+		spans:  inverse,
+	})
+	e.Unlock()
+	e.emitReverts(c.deltas, deltas)
+}
+
+// Redo emits deltas reverting the last undo, unless an edit has been made since.
+func (e *DeltaEditor) Redo() {
+	log.Println("DeltaEditor.Redo")
+	if e.ReadOnly {
+		return
+	}
+	e.Flush()
 	e.Lock()
-	delta := &labgo.Delta{
-		Offset: e.Cursor,
-		Add:    []byte(clipboard.Content()),
+	if len(e.redos) == 0 {
+		e.Unlock()
+		return
+	}
+	c := e.redos[len(e.redos)-1]
+	e.redos = e.redos[:len(e.redos)-1]
+	deltas, inverse := e.revert(e.reverse(c))
+	e.undos = append(e.undos, &change{
+		deltas: deltas,
+		spans:  inverse,
+	})
+	e.Unlock()
+	e.emitReverts(c.deltas, deltas)
+}
+
+// add inserts the given runes at the cursor, replacing the selection, if any.
+func (e *DeltaEditor) add(runes ...rune) {
+	e.edit(func(pending *labgo.Delta) {
+		e.insert(runes...)
+		pending.Add = append(pending.Add, []byte(string(runes))...)
+	})
+}
+
+// edit applies the given change to the buffer, and records it in the pending delta.
+// If the cursor has moved away from the end of the pending delta, or there is a selection, the pending delta is emitted and a new one started.
+// The change is called with the lock held.
+func (e *DeltaEditor) edit(change func(*labgo.Delta)) {
+	if e.ReadOnly {
+		return
 	}
-	if e.IsSelecting {
-		if e.Selection < delta.Offset {
-			delta.Offset = e.Selection
+	e.Lock()
+	if length := uint64(e.Buffer.Len()); e.Cursor > length {
+		e.Cursor = length
+	}
+	var flushed, ready *labgo.Delta
+	var flushedParents, readyParents []string
+	if e.pending != nil && (e.IsSelecting || e.Cursor != e.pendingEnd()) {
+		flushed, flushedParents = e.takePending()
+	}
+	if e.pending == nil {
+		e.pending = &labgo.Delta{
+			Offset: e.Cursor,
+		}
+		if e.IsSelecting {
+			start, _ := e.selectionBounds()
+			e.pending.Offset = start
+			e.pending.Remove = []byte(e.selectedText())
+			e.eraseSelection()
 		}
-		delta.Remove = []byte(e.SelectedText())
-		e.IsSelecting = false
 	}
-	if len(e.Order) > 0 {
-		parentRecordId = e.Order[len(e.Order)-1]
+	change(e.pending)
+	if e.Timeout > 0 {
+		if e.timer != nil {
+			e.timer.Stop()
+		}
+		// A new timer for each edit, so one which fired as it was replaced cannot flush the edit extending the pending delta
+		e.timerId++
+		id := e.timerId
+		e.timer = time.AfterFunc(e.Timeout, func() {
+			e.flushTimer(id)
+		})
+	} else {
+		ready, readyParents = e.takePending()
 	}
 	e.Unlock()
-	if e.OnDelta != nil {
-		e.OnDelta(parentRecordId, delta)
+	e.Refresh()
+	if flushed != nil {
+		e.emit(flushedParents, flushed)
+	}
+	if ready != nil {
+		e.emit(readyParents, ready)
 	}
 }
 
-func (e *DeltaEditor) EraseSelection() {
-	log.Println("DeltaEditor.EraseSelection")
-	/* TODO
-	if e.OnDelta != nil {
-		e.OnDelta
+// flushIfMoved emits the pending delta if the cursor is no longer at its end.
+func (e *DeltaEditor) flushIfMoved() {
+	e.Lock()
+	moved := e.pending != nil && (e.IsSelecting || e.Cursor != e.pendingEnd())
+	e.Unlock()
+	if moved {
+		e.Flush()
+	}
+}
+
+// takePending removes and returns the pending delta with its offset converted from runes to bytes, or nil if it would have no effect, along with the ids of the deltas it was written after.
+// The caller must hold the lock.
+func (e *DeltaEditor) takePending() (*labgo.Delta, []string) {
+	if e.timer != nil {
+		e.timer.Stop()
+		e.timer = nil
+	}
+	delta := e.pending
+	e.pending = nil
+	if delta == nil || (len(delta.Add) == 0 && len(delta.Remove) == 0) {
+		return nil, nil
 	}
-	*/
+	// Text before the offset is unchanged by the delta
+	delta.Offset = uint64(e.Buffer.RuneToByte(int(delta.Offset)))
+	e.undos = append(e.undos, &change{
+		deltas: []*labgo.Delta{delta},
+		spans: []*Span{
+			{
+				Offset: delta.Offset,
+				Remove: uint64(len(delta.Add)),
+				Add:    delta.Remove,
+			},
+		},
+	})
+	// Undone deltas cannot be redone once another edit is made
+	e.redos = nil
+	return delta, append([]string{}, e.Heads...)
+}
+
+// revert applies the given spans to the buffer, and returns the deltas which apply them, and the spans which revert those.
+// The caller must hold the lock.
+func (e *DeltaEditor) revert(spans []*Span) ([]*labgo.Delta, []*Span) {
+	var deltas []*labgo.Delta
+	var inverse []*Span
+	// From the start of the buffer to the end, so each delta applies after the one before
+	sorted := sortSpans(spans)
+	var shift int64
+	for i := len(sorted) - 1; i >= 0; i-- {
+		s := sorted[i]
+		offset := int64(s.Offset) + shift
+		start := e.Buffer.ByteToRune(int(offset))
+		end := e.Buffer.ByteToRune(int(offset + int64(s.Remove)))
+		if start == end && len(s.Add) == 0 {
+			// Reverts text since removed by others
+			continue
+		}
+		delta := &labgo.Delta{
+			Offset: uint64(e.Buffer.RuneToByte(start)),
+			Remove: []byte(string(e.Buffer.Slice(start, end))),
+			Add:    s.Add,
+		}
+		deltas = append(deltas, delta)
+		inverse = append(inverse, &Span{
+			Offset: delta.Offset,
+			Remove: uint64(len(delta.Add)),
+			Add:    delta.Remove,
+		})
+		added := []rune(string(delta.Add))
+		e.Buffer.Remove(start, end)
+		e.Buffer.Insert(start, added)
+		shift += int64(len(delta.Add)) - int64(len(delta.Remove))
+		e.Cursor = uint64(start + len(added))
+	}
+	e.IsSelecting = false
+	return deltas, inverse
+}
+
+// reverse returns the spans which revert the given change in the buffer as it is.
+// The caller must hold the lock.
+func (e *DeltaEditor) reverse(c *change) []*Span {
+	if e.reverting != nil {
+		if spans, ok := e.reverting(c.deltas); ok {
+			return spans
+		}
+	}
+	return c.spans
+}
+
+// pendingEnd returns the cursor at the end of the text added by the pending delta.
+// The caller must hold the lock.
+func (e *DeltaEditor) pendingEnd() uint64 {
+	return e.pending.Offset + uint64(utf8.RuneCount(e.pending.Add))
+}
+
+// applyPending applies the pending delta to the given buffer, and returns it.
+// The caller must hold the lock.
+func (e *DeltaEditor) applyPending(buffer *Rope) *Rope {
+	if e.pending == nil {
+		return buffer
+	}
+	start := int(e.pending.Offset)
+	buffer.Remove(start, start+utf8.RuneCount(e.pending.Remove))
+	buffer.Insert(start, []rune(string(e.pending.Add)))
+	return buffer
+}
+
+// RuneToByteOffset returns the number of bytes used to encode the given number of runes from the start of the text.
+func RuneToByteOffset(text []rune, offset uint64) uint64 {
+	if length := uint64(len(text)); offset > length {
+		offset = length
+	}
+	var count uint64
+	for _, r := range text[:offset] {
+		count += uint64(utf8.RuneLen(r))
+	}
+	return count
+}
+
+// ByteToRuneOffset returns the number of runes encoded in the given number of bytes from the start of the buffer.
+func ByteToRuneOffset(buffer []byte, offset uint64) uint64 {
+	if length := uint64(len(buffer)); offset > length {
+		offset = length
+	}
+	return uint64(utf8.RuneCount(buffer[:offset]))
+}
+
+// emitAll emits each of the given deltas after the one before.
+func (e *DeltaEditor) emitAll(deltas []*labgo.Delta) {
+	for _, delta := range deltas {
+		e.Lock()
+		parents := append([]string{}, e.Heads...)
+		e.Unlock()
+		e.emit(parents, delta)
+	}
+}
+
+// emitReverts emits each of the given deltas, keeping the deltas they revert in reverts until all have been emitted.
+func (e *DeltaEditor) emitReverts(reverted, deltas []*labgo.Delta) {
+	e.Lock()
+	for _, d := range deltas {
+		e.reverts[d] = reverted
+	}
+	e.Unlock()
+	e.emitAll(deltas)
+	e.Lock()
+	for _, d := range deltas {
+		delete(e.reverts, d)
+	}
+	e.Unlock()
+}
+
+// emit passes the given delta, and the id of the last delta it was written after, to OnDelta.
+// The ids of every delta it was written after are kept in emitted until OnDelta returns.
+func (e *DeltaEditor) emit(parents []string, delta *labgo.Delta) {
+	e.Refresh()
+	if e.OnDelta == nil {
+		return
+	}
+	var parent string
+	if len(parents) > 0 {
+		parent = parents[len(parents)-1]
+	}
+	e.Lock()
+	e.emitted[delta] = parents
+	e.Unlock()
+	e.OnDelta(parent, delta)
+	e.Lock()
+	delete(e.emitted, delta)
+	e.Unlock()
 }
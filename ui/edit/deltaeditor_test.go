@@ -0,0 +1,361 @@
+/*
+ * Copyright 2020 Aletheia Ware LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package edit_test
+
+import (
+	"fyne.io/fyne"
+	"fyne.io/fyne/test"
+	"github.com/AletheiaWareLLC/labfynego/ui/edit"
+	"github.com/AletheiaWareLLC/labgo"
+	"testing"
+	"time"
+)
+
+func TestRuneToByteOffset(t *testing.T) {
+	for name, tt := range map[string]struct {
+		text   string
+		offset uint64
+		want   uint64
+	}{
+		"ascii":  {"Test", 2, 2},
+		"accent": {"Grüße", 4, 6},
+		"cjk":    {"世界", 1, 3},
+		"emoji":  {"a😀b", 2, 5},
+		"end":    {"世界", 5, 6},
+	} {
+		t.Run(name, func(t *testing.T) {
+			if got := edit.RuneToByteOffset([]rune(tt.text), tt.offset); got != tt.want {
+				t.Errorf("Incorrect offset; expected '%d', got '%d'", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestByteToRuneOffset(t *testing.T) {
+	for name, tt := range map[string]struct {
+		text   string
+		offset uint64
+		want   uint64
+	}{
+		"ascii":  {"Test", 2, 2},
+		"accent": {"Grüße", 6, 4},
+		"cjk":    {"世界", 3, 1},
+		"emoji":  {"a😀b", 5, 2},
+		"end":    {"世界", 9, 2},
+	} {
+		t.Run(name, func(t *testing.T) {
+			if got := edit.ByteToRuneOffset([]byte(tt.text), tt.offset); got != tt.want {
+				t.Errorf("Incorrect offset; expected '%d', got '%d'", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestDeltaEditor_Multilingual(t *testing.T) {
+	test.NewApp()
+	for name, tt := range map[string]struct {
+		text string
+		edit func(*edit.DeltaEditor)
+		want string
+	}{
+		"insert_after_accent": {
+			text: "Grüße",
+			edit: func(e *edit.DeltaEditor) {
+				e.Cursor = 3
+				e.TypedRune('ü')
+			},
+			want: "Grüüße",
+		},
+		"insert_after_cjk": {
+			text: "世界",
+			edit: func(e *edit.DeltaEditor) {
+				e.Cursor = 1
+				for _, r := range "の新しい" {
+					e.TypedRune(r)
+				}
+			},
+			want: "世の新しい界",
+		},
+		"backspace_emoji": {
+			text: "a😀b",
+			edit: func(e *edit.DeltaEditor) {
+				e.Cursor = 2
+				e.TypedKey(&fyne.KeyEvent{Name: fyne.KeyBackspace})
+			},
+			want: "ab",
+		},
+		"delete_cjk": {
+			text: "世界",
+			edit: func(e *edit.DeltaEditor) {
+				e.Cursor = 0
+				e.TypedKey(&fyne.KeyEvent{Name: fyne.KeyDelete})
+			},
+			want: "界",
+		},
+		"erase_selection": {
+			text: "Grüße, 世界! 😀",
+			edit: func(e *edit.DeltaEditor) {
+				e.Selection = 3
+				e.Cursor = 9
+				e.IsSelecting = true
+				e.EraseSelection()
+			},
+			want: "Grü! 😀",
+		},
+		"replace_selection": {
+			text: "Grüße, 世界! 😀",
+			edit: func(e *edit.DeltaEditor) {
+				e.Selection = 7
+				e.Cursor = 9
+				e.IsSelecting = true
+				for _, r := range "мир" {
+					e.TypedRune(r)
+				}
+			},
+			want: "Grüße, мир! 😀",
+		},
+	} {
+		for _, coalesce := range []bool{true, false} {
+			n := name
+			if !coalesce {
+				n += "_immediate"
+			}
+			t.Run(n, func(t *testing.T) {
+				buffer := []byte(tt.text)
+				e := edit.NewDeltaEditor(func(parent string, delta *labgo.Delta) {
+					buffer = labgo.DeltaToBuffer(delta, buffer)
+				})
+				if !coalesce {
+					e.Timeout = 0
+				}
+				e.SetText(tt.text)
+				tt.edit(e)
+				e.Flush()
+				if got := e.Buffer.String(); got != tt.want {
+					t.Errorf("Incorrect editor buffer; expected '%s', got '%s'", tt.want, got)
+				}
+				if got := string(buffer); got != tt.want {
+					t.Errorf("Incorrect delta buffer; expected '%s', got '%s'", tt.want, got)
+				}
+			})
+		}
+	}
+}
+
+func TestDeltaEditor_Undo(t *testing.T) {
+	test.NewApp()
+	buffer := []byte("Grüße")
+	e := edit.NewDeltaEditor(func(parent string, delta *labgo.Delta) {
+		buffer = labgo.DeltaToBuffer(delta, buffer)
+	})
+	e.SetText("Grüße")
+	e.Cursor = 5
+	for _, r := range ", 世界" {
+		e.TypedRune(r)
+	}
+	e.Selection = 0
+	e.Cursor = 2
+	e.IsSelecting = true
+	e.TypedRune('H')
+	for _, tt := range []struct {
+		edit func()
+		want string
+	}{
+		{e.Undo, "Grüße, 世界"},
+		{e.Undo, "Grüße"},
+		{e.Undo, "Grüße"},
+		{e.Redo, "Grüße, 世界"},
+		{e.Redo, "Hüße, 世界"},
+		{e.Redo, "Hüße, 世界"},
+		{e.Undo, "Grüße, 世界"},
+		// Typed after the text restored by undo
+		{func() { e.TypedRune('!') }, "Gr!üße, 世界"},
+		// Redo is lost once another edit is made
+		{e.Redo, "Gr!üße, 世界"},
+	} {
+		tt.edit()
+		e.Flush()
+		if got := e.Buffer.String(); got != tt.want {
+			t.Fatalf("Incorrect editor buffer; expected '%s', got '%s'", tt.want, got)
+		}
+		if got := string(buffer); got != tt.want {
+			t.Fatalf("Incorrect delta buffer; expected '%s', got '%s'", tt.want, got)
+		}
+	}
+}
+
+func TestDeltaEditor_EraseSelection(t *testing.T) {
+	test.NewApp()
+	text := "Grüße, 世界! 😀"
+	for name, tt := range map[string]struct {
+		edit   func(*edit.DeltaEditor)
+		offset uint64
+		remove string
+		want   string
+	}{
+		"select_all": {
+			edit: func(e *edit.DeltaEditor) {
+				e.SelectAll()
+				e.EraseSelection()
+			},
+			offset: 0,
+			remove: text,
+			want:   "",
+		},
+		"forward": {
+			edit: func(e *edit.DeltaEditor) {
+				e.Selection = 3
+				e.Cursor = 9
+				e.IsSelecting = true
+				e.EraseSelection()
+			},
+			offset: uint64(len("Grü")),
+			remove: "ße, 世界",
+			want:   "Grü! 😀",
+		},
+		"backward": {
+			edit: func(e *edit.DeltaEditor) {
+				e.Selection = 13
+				e.Cursor = 10
+				e.IsSelecting = true
+				e.EraseSelection()
+			},
+			offset: uint64(len("Grüße, 世界!")),
+			remove: " 😀",
+			want:   "Grüße, 世界!",
+		},
+	} {
+		for _, coalesce := range []bool{true, false} {
+			n := name
+			if !coalesce {
+				n += "_immediate"
+			}
+			t.Run(n, func(t *testing.T) {
+				var deltas []*labgo.Delta
+				e := edit.NewDeltaEditor(func(parent string, delta *labgo.Delta) {
+					deltas = append(deltas, delta)
+				})
+				if !coalesce {
+					e.Timeout = 0
+				}
+				e.SetText(text)
+				tt.edit(e)
+				e.Flush()
+				if got := e.Buffer.String(); got != tt.want {
+					t.Errorf("Incorrect editor buffer; expected '%s', got '%s'", tt.want, got)
+				}
+				if len(deltas) != 1 {
+					t.Fatalf("Incorrect deltas; expected '1', got '%d'", len(deltas))
+				}
+				d := deltas[0]
+				if d.Offset != tt.offset {
+					t.Errorf("Incorrect offset; expected '%d', got '%d'", tt.offset, d.Offset)
+				}
+				if got := string(d.Remove); got != tt.remove {
+					t.Errorf("Incorrect remove; expected '%s', got '%s'", tt.remove, got)
+				}
+				if len(d.Add) != 0 {
+					t.Errorf("Incorrect add; expected '', got '%s'", d.Add)
+				}
+			})
+		}
+	}
+}
+
+func TestDeltaEditor_Coalesce(t *testing.T) {
+	test.NewApp()
+	typeTest := func(e *edit.DeltaEditor) {
+		for _, r := range "Tesst" {
+			e.TypedRune(r)
+		}
+		e.TypedKey(&fyne.KeyEvent{Name: fyne.KeyBackspace})
+		e.TypedKey(&fyne.KeyEvent{Name: fyne.KeyBackspace})
+		e.TypedRune('t')
+	}
+	t.Run("Flush", func(t *testing.T) {
+		var deltas []*labgo.Delta
+		e := edit.NewDeltaEditor(func(parent string, delta *labgo.Delta) {
+			deltas = append(deltas, delta)
+		})
+		e.Timeout = time.Hour
+		typeTest(e)
+		if len(deltas) != 0 {
+			t.Fatalf("Expected pending edits to be coalesced, got %d deltas", len(deltas))
+		}
+		e.Flush()
+		if len(deltas) != 1 || string(deltas[0].Add) != "Test" {
+			t.Fatalf("Expected one delta adding \"Test\", got %v", deltas)
+		}
+		// Nothing is pending after a flush
+		e.Flush()
+		if len(deltas) != 1 {
+			t.Errorf("Incorrect deltas; expected '1', got '%d'", len(deltas))
+		}
+	})
+	t.Run("Timeout", func(t *testing.T) {
+		deltas := make(chan *labgo.Delta, 2)
+		e := edit.NewDeltaEditor(func(parent string, delta *labgo.Delta) {
+			deltas <- delta
+		})
+		e.Timeout = 50 * time.Millisecond
+		typeTest(e)
+		select {
+		case delta := <-deltas:
+			if got := string(delta.Add); got != "Test" {
+				t.Errorf("Incorrect add; expected 'Test', got '%s'", got)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("Expected pending edits to be flushed after the timeout")
+		}
+		select {
+		case delta := <-deltas:
+			t.Errorf("Expected one delta, got another %v", delta)
+		case <-time.After(2 * e.Timeout):
+		}
+	})
+	t.Run("CursorJump", func(t *testing.T) {
+		var deltas []*labgo.Delta
+		e := edit.NewDeltaEditor(func(parent string, delta *labgo.Delta) {
+			deltas = append(deltas, delta)
+		})
+		e.Timeout = time.Hour
+		e.SetText("世界")
+		e.Cursor = 2
+		e.TypedRune('!')
+		// Moving the cursor away from the pending edit emits it
+		e.TypedKey(&fyne.KeyEvent{Name: fyne.KeyHome})
+		if len(deltas) != 1 {
+			t.Fatalf("Incorrect deltas; expected '1', got '%d'", len(deltas))
+		}
+		e.TypedRune('¡')
+		e.Flush()
+		if len(deltas) != 2 {
+			t.Fatalf("Incorrect deltas; expected '2', got '%d'", len(deltas))
+		}
+		for i, want := range []*labgo.Delta{
+			{Offset: 6, Add: []byte("!")},
+			{Offset: 0, Add: []byte("¡")},
+		} {
+			if got := deltas[i]; got.Offset != want.Offset || string(got.Add) != string(want.Add) || len(got.Remove) != 0 {
+				t.Errorf("Incorrect delta %d; expected '%v', got '%v'", i, want, got)
+			}
+		}
+		if want, got := "¡世界!", e.Buffer.String(); got != want {
+			t.Errorf("Incorrect editor buffer; expected '%s', got '%s'", want, got)
+		}
+	})
+}
@@ -0,0 +1,792 @@
+//
+// Copyright 2020 Aletheia Ware LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.23.0
+// 	protoc        (unknown)
+// source: edit.proto
+
+package edit
+
+import (
+	proto "github.com/golang/protobuf/proto"
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// This is a compile-time assertion that a sufficiently up-to-date version
+// of the legacy proto package is being used.
+const _ = proto.ProtoPackageIsVersion4
+
+// ElementId identifies a rune in a Sequence by the hash of the record which inserted it, and its index among the runes inserted by that record.
+type ElementId struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// Empty refers to the record holding the id
+	Record []byte `protobuf:"bytes,1,opt,name=record,proto3" json:"record,omitempty"`
+	Index  uint32 `protobuf:"varint,2,opt,name=index,proto3" json:"index,omitempty"`
+}
+
+func (x *ElementId) Reset() {
+	*x = ElementId{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_edit_proto_msgTypes[0]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ElementId) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ElementId) ProtoMessage() {}
+
+func (x *ElementId) ProtoReflect() protoreflect.Message {
+	mi := &file_edit_proto_msgTypes[0]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ElementId.ProtoReflect.Descriptor instead.
+func (*ElementId) Descriptor() ([]byte, []int) {
+	return file_edit_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *ElementId) GetRecord() []byte {
+	if x != nil {
+		return x.Record
+	}
+	return nil
+}
+
+func (x *ElementId) GetIndex() uint32 {
+	if x != nil {
+		return x.Index
+	}
+	return 0
+}
+
+// SequenceInsert inserts text after the given rune, or at the start of the sequence if there is none.
+type SequenceInsert struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	After *ElementId `protobuf:"bytes,1,opt,name=after,proto3" json:"after,omitempty"`
+	Text  string     `protobuf:"bytes,2,opt,name=text,proto3" json:"text,omitempty"`
+}
+
+func (x *SequenceInsert) Reset() {
+	*x = SequenceInsert{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_edit_proto_msgTypes[1]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *SequenceInsert) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SequenceInsert) ProtoMessage() {}
+
+func (x *SequenceInsert) ProtoReflect() protoreflect.Message {
+	mi := &file_edit_proto_msgTypes[1]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SequenceInsert.ProtoReflect.Descriptor instead.
+func (*SequenceInsert) Descriptor() ([]byte, []int) {
+	return file_edit_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *SequenceInsert) GetAfter() *ElementId {
+	if x != nil {
+		return x.After
+	}
+	return nil
+}
+
+func (x *SequenceInsert) GetText() string {
+	if x != nil {
+		return x.Text
+	}
+	return ""
+}
+
+// SequenceRemove removes the runes inserted by the given record from Start up to, but not including, End.
+type SequenceRemove struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// Empty refers to the record holding the removal
+	Record []byte `protobuf:"bytes,1,opt,name=record,proto3" json:"record,omitempty"`
+	Start  uint32 `protobuf:"varint,2,opt,name=start,proto3" json:"start,omitempty"`
+	End    uint32 `protobuf:"varint,3,opt,name=end,proto3" json:"end,omitempty"`
+}
+
+func (x *SequenceRemove) Reset() {
+	*x = SequenceRemove{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_edit_proto_msgTypes[2]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *SequenceRemove) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SequenceRemove) ProtoMessage() {}
+
+func (x *SequenceRemove) ProtoReflect() protoreflect.Message {
+	mi := &file_edit_proto_msgTypes[2]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SequenceRemove.ProtoReflect.Descriptor instead.
+func (*SequenceRemove) Descriptor() ([]byte, []int) {
+	return file_edit_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *SequenceRemove) GetRecord() []byte {
+	if x != nil {
+		return x.Record
+	}
+	return nil
+}
+
+func (x *SequenceRemove) GetStart() uint32 {
+	if x != nil {
+		return x.Start
+	}
+	return 0
+}
+
+func (x *SequenceRemove) GetEnd() uint32 {
+	if x != nil {
+		return x.End
+	}
+	return 0
+}
+
+// SequenceRestore cancels the removal, by the given remover, of the runes inserted by the given record from Start up to, but not including, End.
+// The runes are shown again once every removal of them has been cancelled.
+type SequenceRestore struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// Empty refers to the record holding the restore
+	Record  []byte `protobuf:"bytes,1,opt,name=record,proto3" json:"record,omitempty"`
+	Start   uint32 `protobuf:"varint,2,opt,name=start,proto3" json:"start,omitempty"`
+	End     uint32 `protobuf:"varint,3,opt,name=end,proto3" json:"end,omitempty"`
+	Remover []byte `protobuf:"bytes,4,opt,name=remover,proto3" json:"remover,omitempty"`
+}
+
+func (x *SequenceRestore) Reset() {
+	*x = SequenceRestore{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_edit_proto_msgTypes[3]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *SequenceRestore) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SequenceRestore) ProtoMessage() {}
+
+func (x *SequenceRestore) ProtoReflect() protoreflect.Message {
+	mi := &file_edit_proto_msgTypes[3]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SequenceRestore.ProtoReflect.Descriptor instead.
+func (*SequenceRestore) Descriptor() ([]byte, []int) {
+	return file_edit_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *SequenceRestore) GetRecord() []byte {
+	if x != nil {
+		return x.Record
+	}
+	return nil
+}
+
+func (x *SequenceRestore) GetStart() uint32 {
+	if x != nil {
+		return x.Start
+	}
+	return 0
+}
+
+func (x *SequenceRestore) GetEnd() uint32 {
+	if x != nil {
+		return x.End
+	}
+	return 0
+}
+
+func (x *SequenceRestore) GetRemover() []byte {
+	if x != nil {
+		return x.Remover
+	}
+	return nil
+}
+
+// SequenceEdit is the payload of a record of type TYPE_SEQUENCE.
+// The runes inserted are indexed in the order they appear in the edit.
+type SequenceEdit struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// Greater than the clock of every edit known to the writer
+	Clock  uint64            `protobuf:"varint,1,opt,name=clock,proto3" json:"clock,omitempty"`
+	Insert []*SequenceInsert `protobuf:"bytes,2,rep,name=insert,proto3" json:"insert,omitempty"`
+	Remove []*SequenceRemove `protobuf:"bytes,3,rep,name=remove,proto3" json:"remove,omitempty"`
+	// Hash of the record holding the delta the edit was migrated from, whose creator made the edit
+	Delta   []byte             `protobuf:"bytes,4,opt,name=delta,proto3" json:"delta,omitempty"`
+	Restore []*SequenceRestore `protobuf:"bytes,5,rep,name=restore,proto3" json:"restore,omitempty"`
+}
+
+func (x *SequenceEdit) Reset() {
+	*x = SequenceEdit{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_edit_proto_msgTypes[4]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *SequenceEdit) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SequenceEdit) ProtoMessage() {}
+
+func (x *SequenceEdit) ProtoReflect() protoreflect.Message {
+	mi := &file_edit_proto_msgTypes[4]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SequenceEdit.ProtoReflect.Descriptor instead.
+func (*SequenceEdit) Descriptor() ([]byte, []int) {
+	return file_edit_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *SequenceEdit) GetClock() uint64 {
+	if x != nil {
+		return x.Clock
+	}
+	return 0
+}
+
+func (x *SequenceEdit) GetInsert() []*SequenceInsert {
+	if x != nil {
+		return x.Insert
+	}
+	return nil
+}
+
+func (x *SequenceEdit) GetRemove() []*SequenceRemove {
+	if x != nil {
+		return x.Remove
+	}
+	return nil
+}
+
+func (x *SequenceEdit) GetDelta() []byte {
+	if x != nil {
+		return x.Delta
+	}
+	return nil
+}
+
+func (x *SequenceEdit) GetRestore() []*SequenceRestore {
+	if x != nil {
+		return x.Restore
+	}
+	return nil
+}
+
+// Snapshot is the buffer resulting from applying the deltas in Order.
+type Snapshot struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Order  []string `protobuf:"bytes,1,rep,name=order,proto3" json:"order,omitempty"`
+	Buffer []byte   `protobuf:"bytes,2,opt,name=buffer,proto3" json:"buffer,omitempty"`
+}
+
+func (x *Snapshot) Reset() {
+	*x = Snapshot{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_edit_proto_msgTypes[5]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Snapshot) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Snapshot) ProtoMessage() {}
+
+func (x *Snapshot) ProtoReflect() protoreflect.Message {
+	mi := &file_edit_proto_msgTypes[5]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Snapshot.ProtoReflect.Descriptor instead.
+func (*Snapshot) Descriptor() ([]byte, []int) {
+	return file_edit_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *Snapshot) GetOrder() []string {
+	if x != nil {
+		return x.Order
+	}
+	return nil
+}
+
+func (x *Snapshot) GetBuffer() []byte {
+	if x != nil {
+		return x.Buffer
+	}
+	return nil
+}
+
+// Checkpoint holds the content of a file after the deltas referenced by its record, and all those they were written after.
+type Checkpoint struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Hash []byte `protobuf:"bytes,1,opt,name=hash,proto3" json:"hash,omitempty"`
+	Body []byte `protobuf:"bytes,2,opt,name=body,proto3" json:"body,omitempty"`
+}
+
+func (x *Checkpoint) Reset() {
+	*x = Checkpoint{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_edit_proto_msgTypes[6]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Checkpoint) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Checkpoint) ProtoMessage() {}
+
+func (x *Checkpoint) ProtoReflect() protoreflect.Message {
+	mi := &file_edit_proto_msgTypes[6]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Checkpoint.ProtoReflect.Descriptor instead.
+func (*Checkpoint) Descriptor() ([]byte, []int) {
+	return file_edit_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *Checkpoint) GetHash() []byte {
+	if x != nil {
+		return x.Hash
+	}
+	return nil
+}
+
+func (x *Checkpoint) GetBody() []byte {
+	if x != nil {
+		return x.Body
+	}
+	return nil
+}
+
+// Presence holds the cursor and selection of a collaborator, in bytes of the buffer made by the deltas referenced by its record.
+// In files holding sequence records they are instead held as the runes they follow, unset for the start of the text.
+type Presence struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Cursor         uint64     `protobuf:"varint,1,opt,name=cursor,proto3" json:"cursor,omitempty"`
+	Selection      uint64     `protobuf:"varint,2,opt,name=selection,proto3" json:"selection,omitempty"`
+	IsSelecting    bool       `protobuf:"varint,3,opt,name=is_selecting,json=isSelecting,proto3" json:"is_selecting,omitempty"`
+	CursorAfter    *ElementId `protobuf:"bytes,4,opt,name=cursor_after,json=cursorAfter,proto3" json:"cursor_after,omitempty"`
+	SelectionAfter *ElementId `protobuf:"bytes,5,opt,name=selection_after,json=selectionAfter,proto3" json:"selection_after,omitempty"`
+}
+
+func (x *Presence) Reset() {
+	*x = Presence{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_edit_proto_msgTypes[7]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Presence) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Presence) ProtoMessage() {}
+
+func (x *Presence) ProtoReflect() protoreflect.Message {
+	mi := &file_edit_proto_msgTypes[7]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Presence.ProtoReflect.Descriptor instead.
+func (*Presence) Descriptor() ([]byte, []int) {
+	return file_edit_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *Presence) GetCursor() uint64 {
+	if x != nil {
+		return x.Cursor
+	}
+	return 0
+}
+
+func (x *Presence) GetSelection() uint64 {
+	if x != nil {
+		return x.Selection
+	}
+	return 0
+}
+
+func (x *Presence) GetIsSelecting() bool {
+	if x != nil {
+		return x.IsSelecting
+	}
+	return false
+}
+
+func (x *Presence) GetCursorAfter() *ElementId {
+	if x != nil {
+		return x.CursorAfter
+	}
+	return nil
+}
+
+func (x *Presence) GetSelectionAfter() *ElementId {
+	if x != nil {
+		return x.SelectionAfter
+	}
+	return nil
+}
+
+var File_edit_proto protoreflect.FileDescriptor
+
+var file_edit_proto_rawDesc = []byte{
+	0x0a, 0x0a, 0x65, 0x64, 0x69, 0x74, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x12, 0x04, 0x65, 0x64,
+	0x69, 0x74, 0x22, 0x39, 0x0a, 0x09, 0x45, 0x6c, 0x65, 0x6d, 0x65, 0x6e, 0x74, 0x49, 0x64, 0x12,
+	0x16, 0x0a, 0x06, 0x72, 0x65, 0x63, 0x6f, 0x72, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0c, 0x52,
+	0x06, 0x72, 0x65, 0x63, 0x6f, 0x72, 0x64, 0x12, 0x14, 0x0a, 0x05, 0x69, 0x6e, 0x64, 0x65, 0x78,
+	0x18, 0x02, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x05, 0x69, 0x6e, 0x64, 0x65, 0x78, 0x22, 0x4b, 0x0a,
+	0x0e, 0x53, 0x65, 0x71, 0x75, 0x65, 0x6e, 0x63, 0x65, 0x49, 0x6e, 0x73, 0x65, 0x72, 0x74, 0x12,
+	0x25, 0x0a, 0x05, 0x61, 0x66, 0x74, 0x65, 0x72, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x0f,
+	0x2e, 0x65, 0x64, 0x69, 0x74, 0x2e, 0x45, 0x6c, 0x65, 0x6d, 0x65, 0x6e, 0x74, 0x49, 0x64, 0x52,
+	0x05, 0x61, 0x66, 0x74, 0x65, 0x72, 0x12, 0x12, 0x0a, 0x04, 0x74, 0x65, 0x78, 0x74, 0x18, 0x02,
+	0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x74, 0x65, 0x78, 0x74, 0x22, 0x50, 0x0a, 0x0e, 0x53, 0x65,
+	0x71, 0x75, 0x65, 0x6e, 0x63, 0x65, 0x52, 0x65, 0x6d, 0x6f, 0x76, 0x65, 0x12, 0x16, 0x0a, 0x06,
+	0x72, 0x65, 0x63, 0x6f, 0x72, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x06, 0x72, 0x65,
+	0x63, 0x6f, 0x72, 0x64, 0x12, 0x14, 0x0a, 0x05, 0x73, 0x74, 0x61, 0x72, 0x74, 0x18, 0x02, 0x20,
+	0x01, 0x28, 0x0d, 0x52, 0x05, 0x73, 0x74, 0x61, 0x72, 0x74, 0x12, 0x10, 0x0a, 0x03, 0x65, 0x6e,
+	0x64, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x03, 0x65, 0x6e, 0x64, 0x22, 0x6b, 0x0a, 0x0f,
+	0x53, 0x65, 0x71, 0x75, 0x65, 0x6e, 0x63, 0x65, 0x52, 0x65, 0x73, 0x74, 0x6f, 0x72, 0x65, 0x12,
+	0x16, 0x0a, 0x06, 0x72, 0x65, 0x63, 0x6f, 0x72, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0c, 0x52,
+	0x06, 0x72, 0x65, 0x63, 0x6f, 0x72, 0x64, 0x12, 0x14, 0x0a, 0x05, 0x73, 0x74, 0x61, 0x72, 0x74,
+	0x18, 0x02, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x05, 0x73, 0x74, 0x61, 0x72, 0x74, 0x12, 0x10, 0x0a,
+	0x03, 0x65, 0x6e, 0x64, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x03, 0x65, 0x6e, 0x64, 0x12,
+	0x18, 0x0a, 0x07, 0x72, 0x65, 0x6d, 0x6f, 0x76, 0x65, 0x72, 0x18, 0x04, 0x20, 0x01, 0x28, 0x0c,
+	0x52, 0x07, 0x72, 0x65, 0x6d, 0x6f, 0x76, 0x65, 0x72, 0x22, 0xc7, 0x01, 0x0a, 0x0c, 0x53, 0x65,
+	0x71, 0x75, 0x65, 0x6e, 0x63, 0x65, 0x45, 0x64, 0x69, 0x74, 0x12, 0x14, 0x0a, 0x05, 0x63, 0x6c,
+	0x6f, 0x63, 0x6b, 0x18, 0x01, 0x20, 0x01, 0x28, 0x04, 0x52, 0x05, 0x63, 0x6c, 0x6f, 0x63, 0x6b,
+	0x12, 0x2c, 0x0a, 0x06, 0x69, 0x6e, 0x73, 0x65, 0x72, 0x74, 0x18, 0x02, 0x20, 0x03, 0x28, 0x0b,
+	0x32, 0x14, 0x2e, 0x65, 0x64, 0x69, 0x74, 0x2e, 0x53, 0x65, 0x71, 0x75, 0x65, 0x6e, 0x63, 0x65,
+	0x49, 0x6e, 0x73, 0x65, 0x72, 0x74, 0x52, 0x06, 0x69, 0x6e, 0x73, 0x65, 0x72, 0x74, 0x12, 0x2c,
+	0x0a, 0x06, 0x72, 0x65, 0x6d, 0x6f, 0x76, 0x65, 0x18, 0x03, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x14,
+	0x2e, 0x65, 0x64, 0x69, 0x74, 0x2e, 0x53, 0x65, 0x71, 0x75, 0x65, 0x6e, 0x63, 0x65, 0x52, 0x65,
+	0x6d, 0x6f, 0x76, 0x65, 0x52, 0x06, 0x72, 0x65, 0x6d, 0x6f, 0x76, 0x65, 0x12, 0x14, 0x0a, 0x05,
+	0x64, 0x65, 0x6c, 0x74, 0x61, 0x18, 0x04, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x05, 0x64, 0x65, 0x6c,
+	0x74, 0x61, 0x12, 0x2f, 0x0a, 0x07, 0x72, 0x65, 0x73, 0x74, 0x6f, 0x72, 0x65, 0x18, 0x05, 0x20,
+	0x03, 0x28, 0x0b, 0x32, 0x15, 0x2e, 0x65, 0x64, 0x69, 0x74, 0x2e, 0x53, 0x65, 0x71, 0x75, 0x65,
+	0x6e, 0x63, 0x65, 0x52, 0x65, 0x73, 0x74, 0x6f, 0x72, 0x65, 0x52, 0x07, 0x72, 0x65, 0x73, 0x74,
+	0x6f, 0x72, 0x65, 0x22, 0x38, 0x0a, 0x08, 0x53, 0x6e, 0x61, 0x70, 0x73, 0x68, 0x6f, 0x74, 0x12,
+	0x14, 0x0a, 0x05, 0x6f, 0x72, 0x64, 0x65, 0x72, 0x18, 0x01, 0x20, 0x03, 0x28, 0x09, 0x52, 0x05,
+	0x6f, 0x72, 0x64, 0x65, 0x72, 0x12, 0x16, 0x0a, 0x06, 0x62, 0x75, 0x66, 0x66, 0x65, 0x72, 0x18,
+	0x02, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x06, 0x62, 0x75, 0x66, 0x66, 0x65, 0x72, 0x22, 0x34, 0x0a,
+	0x0a, 0x43, 0x68, 0x65, 0x63, 0x6b, 0x70, 0x6f, 0x69, 0x6e, 0x74, 0x12, 0x12, 0x0a, 0x04, 0x68,
+	0x61, 0x73, 0x68, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x04, 0x68, 0x61, 0x73, 0x68, 0x12,
+	0x12, 0x0a, 0x04, 0x62, 0x6f, 0x64, 0x79, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x04, 0x62,
+	0x6f, 0x64, 0x79, 0x22, 0xd1, 0x01, 0x0a, 0x08, 0x50, 0x72, 0x65, 0x73, 0x65, 0x6e, 0x63, 0x65,
+	0x12, 0x16, 0x0a, 0x06, 0x63, 0x75, 0x72, 0x73, 0x6f, 0x72, 0x18, 0x01, 0x20, 0x01, 0x28, 0x04,
+	0x52, 0x06, 0x63, 0x75, 0x72, 0x73, 0x6f, 0x72, 0x12, 0x1c, 0x0a, 0x09, 0x73, 0x65, 0x6c, 0x65,
+	0x63, 0x74, 0x69, 0x6f, 0x6e, 0x18, 0x02, 0x20, 0x01, 0x28, 0x04, 0x52, 0x09, 0x73, 0x65, 0x6c,
+	0x65, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x12, 0x21, 0x0a, 0x0c, 0x69, 0x73, 0x5f, 0x73, 0x65, 0x6c,
+	0x65, 0x63, 0x74, 0x69, 0x6e, 0x67, 0x18, 0x03, 0x20, 0x01, 0x28, 0x08, 0x52, 0x0b, 0x69, 0x73,
+	0x53, 0x65, 0x6c, 0x65, 0x63, 0x74, 0x69, 0x6e, 0x67, 0x12, 0x32, 0x0a, 0x0c, 0x63, 0x75, 0x72,
+	0x73, 0x6f, 0x72, 0x5f, 0x61, 0x66, 0x74, 0x65, 0x72, 0x18, 0x04, 0x20, 0x01, 0x28, 0x0b, 0x32,
+	0x0f, 0x2e, 0x65, 0x64, 0x69, 0x74, 0x2e, 0x45, 0x6c, 0x65, 0x6d, 0x65, 0x6e, 0x74, 0x49, 0x64,
+	0x52, 0x0b, 0x63, 0x75, 0x72, 0x73, 0x6f, 0x72, 0x41, 0x66, 0x74, 0x65, 0x72, 0x12, 0x38, 0x0a,
+	0x0f, 0x73, 0x65, 0x6c, 0x65, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x5f, 0x61, 0x66, 0x74, 0x65, 0x72,
+	0x18, 0x05, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x0f, 0x2e, 0x65, 0x64, 0x69, 0x74, 0x2e, 0x45, 0x6c,
+	0x65, 0x6d, 0x65, 0x6e, 0x74, 0x49, 0x64, 0x52, 0x0e, 0x73, 0x65, 0x6c, 0x65, 0x63, 0x74, 0x69,
+	0x6f, 0x6e, 0x41, 0x66, 0x74, 0x65, 0x72, 0x42, 0x2e, 0x5a, 0x2c, 0x67, 0x69, 0x74, 0x68, 0x75,
+	0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x41, 0x6c, 0x65, 0x74, 0x68, 0x65, 0x69, 0x61, 0x57, 0x61,
+	0x72, 0x65, 0x4c, 0x4c, 0x43, 0x2f, 0x6c, 0x61, 0x62, 0x66, 0x79, 0x6e, 0x65, 0x67, 0x6f, 0x2f,
+	0x75, 0x69, 0x2f, 0x65, 0x64, 0x69, 0x74, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+}
+
+var (
+	file_edit_proto_rawDescOnce sync.Once
+	file_edit_proto_rawDescData = file_edit_proto_rawDesc
+)
+
+func file_edit_proto_rawDescGZIP() []byte {
+	file_edit_proto_rawDescOnce.Do(func() {
+		file_edit_proto_rawDescData = protoimpl.X.CompressGZIP(file_edit_proto_rawDescData)
+	})
+	return file_edit_proto_rawDescData
+}
+
+var file_edit_proto_msgTypes = make([]protoimpl.MessageInfo, 8)
+var file_edit_proto_goTypes = []interface{}{
+	(*ElementId)(nil),       // 0: edit.ElementId
+	(*SequenceInsert)(nil),  // 1: edit.SequenceInsert
+	(*SequenceRemove)(nil),  // 2: edit.SequenceRemove
+	(*SequenceRestore)(nil), // 3: edit.SequenceRestore
+	(*SequenceEdit)(nil),    // 4: edit.SequenceEdit
+	(*Snapshot)(nil),        // 5: edit.Snapshot
+	(*Checkpoint)(nil),      // 6: edit.Checkpoint
+	(*Presence)(nil),        // 7: edit.Presence
+}
+var file_edit_proto_depIdxs = []int32{
+	0, // 0: edit.SequenceInsert.after:type_name -> edit.ElementId
+	1, // 1: edit.SequenceEdit.insert:type_name -> edit.SequenceInsert
+	2, // 2: edit.SequenceEdit.remove:type_name -> edit.SequenceRemove
+	3, // 3: edit.SequenceEdit.restore:type_name -> edit.SequenceRestore
+	0, // 4: edit.Presence.cursor_after:type_name -> edit.ElementId
+	0, // 5: edit.Presence.selection_after:type_name -> edit.ElementId
+	6, // [6:6] is the sub-list for method output_type
+	6, // [6:6] is the sub-list for method input_type
+	6, // [6:6] is the sub-list for extension type_name
+	6, // [6:6] is the sub-list for extension extendee
+	0, // [0:6] is the sub-list for field type_name
+}
+
+func init() { file_edit_proto_init() }
+func file_edit_proto_init() {
+	if File_edit_proto != nil {
+		return
+	}
+	if !protoimpl.UnsafeEnabled {
+		file_edit_proto_msgTypes[0].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ElementId); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_edit_proto_msgTypes[1].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*SequenceInsert); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_edit_proto_msgTypes[2].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*SequenceRemove); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_edit_proto_msgTypes[3].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*SequenceRestore); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_edit_proto_msgTypes[4].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*SequenceEdit); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_edit_proto_msgTypes[5].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Snapshot); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_edit_proto_msgTypes[6].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Checkpoint); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_edit_proto_msgTypes[7].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Presence); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: file_edit_proto_rawDesc,
+			NumEnums:      0,
+			NumMessages:   8,
+			NumExtensions: 0,
+			NumServices:   0,
+		},
+		GoTypes:           file_edit_proto_goTypes,
+		DependencyIndexes: file_edit_proto_depIdxs,
+		MessageInfos:      file_edit_proto_msgTypes,
+	}.Build()
+	File_edit_proto = out.File
+	file_edit_proto_rawDesc = nil
+	file_edit_proto_goTypes = nil
+	file_edit_proto_depIdxs = nil
+}
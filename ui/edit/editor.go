@@ -25,10 +25,33 @@ import (
 	"image/color"
 	"log"
 	"math"
+	"sort"
+	"strconv"
+	"strings"
 	"sync"
+	"time"
 	"unicode"
 )
 
+const (
+	// Maximum interval between taps for them to be counted in the same sequence
+	TAP_INTERVAL = 500 * time.Millisecond
+	// Number of rows drawn above and below those shown by the scroll container, so short scrolls do not need the editor to be redrawn
+	VIEWPORT_MARGIN = 50
+	// Number of times a rune is repeated when measured, so its width is known to a fraction of a pixel
+	ADVANCE_SAMPLE = 64
+)
+
+// TextEditor is implemented by Editor and the widgets which extend it.
+type TextEditor interface {
+	fyne.Widget
+	CutToClipboard(fyne.Clipboard)
+	CopyToClipboard(fyne.Clipboard)
+	PasteFromClipboard(fyne.Clipboard)
+	SelectAll()
+	EraseSelection()
+}
+
 type Editor struct {
 	widget.BaseWidget
 	sync.Mutex
@@ -36,19 +59,96 @@ type Editor struct {
 	Selection   uint64
 	IsSelecting bool
 	IsFocused   bool
+	ReadOnly    bool // Prevents the buffer being edited, while still allowing it to be navigated, selected, and copied
 	TextAlign   fyne.TextAlign
 	TextColor   color.Color
 	TextSize    int
 	TextStyle   fyne.TextStyle
 	TextWrap    fyne.TextWrap
-	Buffer      []rune
+	Buffer      *Rope
 	Lines       []*Line
+	OnReveal    func()
+
+	// Scroll, if set, is the container scrolling the editor, and only the rows it shows are drawn
+	Scroll *widget.ScrollContainer
+
+	// ShowLineNumbers shows the number of each line in a gutter beside the text, and highlights the line holding the cursor
+	ShowLineNumbers bool
+
+	// TextColors, if set, returns the color of each rune in the buffer, runes without one are drawn in TextColor.
+	// TextColors is called with the lock held.
+	TextColors func() []color.Color
+
+	// TextStyles, if set, returns the style of each rune of the given line of the buffer, runes without one are drawn in TextColor and TextStyle.
+	// TextStyles is called with the lock held, and the styles it returns for a line are not changed after.
+	TextStyles func(line int) []*Style
+
+	// Tooltip, if not empty, is shown at TooltipPosition
+	Tooltip         string
+	TooltipPosition fyne.Position
+
+	// Carets, if set, returns the cursors of others to draw beside this one.
+	// Carets is called with the lock held.
+	Carets func() []*Caret
+
+	// OnCursorChanged, if set, is called when the cursor or selection has moved since the last refresh
+	OnCursorChanged func()
+
+	// Matches, if set, are highlighted, such as those found by a FindBar, with the one at CurrentMatch highlighted more strongly
+	Matches      []*Match
+	CurrentMatch int
 
-	shortcut fyne.ShortcutHandler
+	advances           map[advanceKey]float64 // Width of each rune measured
+	advancesSize       int                    // Text size the advances were measured at
+	drawn              [2]int                 // First and last row drawn by the renderer
+	gutter             int                    // Width of the gutter, as of the last refresh
+	impl               TextEditor
+	refreshedCursor    uint64
+	refreshedSelection uint64
+	refreshedSelecting bool
+	lexer              Lexer
+	shift              bool
+	shortcut           fyne.ShortcutHandler
+	taps               int
+	tapped             time.Time
+	width              int                     // Width of the widest row
+	wrapped            map[string]*wrappedLine // Rows of each line, by its text
+	wrappedKey         wrapKey                 // What the rows in wrapped were measured with
+}
+
+// wrappedLine holds the rows a line is wrapped into, relative to its start, and the styles they were measured with.
+type wrappedLine struct {
+	styles []*Style
+	rows   []*Line
+	width  int
+}
+
+// advanceKey identifies a rune drawn in a style.
+type advanceKey struct {
+	r     rune
+	style fyne.TextStyle
+}
+
+// wrapKey holds what the width of a row depends on, other than its text and styles.
+type wrapKey struct {
+	wrap      fyne.TextWrap
+	maxWidth  int
+	textSize  int
+	textStyle fyne.TextStyle
+}
+
+// Caret is a cursor, and selection if IsSelecting, drawn in addition to the editor's own, labeled with whose it is.
+type Caret struct {
+	Label       string
+	Cursor      uint64
+	Selection   uint64
+	IsSelecting bool
+	Color       color.Color
 }
 
 func NewEditor() *Editor {
 	e := &Editor{
+		Buffer:    &Rope{},
 		TextAlign: fyne.TextAlignLeading,
 		TextColor: theme.TextColor(),
 		TextSize:  theme.TextSize(),
@@ -60,47 +160,264 @@ func NewEditor() *Editor {
 	return e
 }
 
+// ExtendBaseWidget records the widget extending Editor so that shortcuts and menus are handled by its methods.
+func (e *Editor) ExtendBaseWidget(w fyne.Widget) {
+	if t, ok := w.(TextEditor); ok && e.impl == nil {
+		e.impl = t
+	}
+	e.BaseWidget.ExtendBaseWidget(w)
+}
+
+func (e *Editor) super() TextEditor {
+	if e.impl == nil {
+		return e
+	}
+	return e.impl
+}
+
 func (e *Editor) AddShortcuts() {
 	e.shortcut.AddShortcut(&fyne.ShortcutCut{}, func(se fyne.Shortcut) {
 		cut := se.(*fyne.ShortcutCut)
-		e.CutToClipboard(cut.Clipboard)
+		e.super().CutToClipboard(cut.Clipboard)
 	})
 	e.shortcut.AddShortcut(&fyne.ShortcutCopy{}, func(se fyne.Shortcut) {
 		cpy := se.(*fyne.ShortcutCopy)
-		e.CopyToClipboard(cpy.Clipboard)
+		e.super().CopyToClipboard(cpy.Clipboard)
 	})
 	e.shortcut.AddShortcut(&fyne.ShortcutPaste{}, func(se fyne.Shortcut) {
 		paste := se.(*fyne.ShortcutPaste)
-		e.PasteFromClipboard(paste.Clipboard)
+		e.super().PasteFromClipboard(paste.Clipboard)
 	})
 	e.shortcut.AddShortcut(&fyne.ShortcutSelectAll{}, func(se fyne.Shortcut) {
-		e.SelectAll()
+		e.super().SelectAll()
 	})
+	for _, m := range []desktop.Modifier{
+		desktop.ControlModifier,
+		desktop.ControlModifier | desktop.ShiftModifier,
+	} {
+		extend := m&desktop.ShiftModifier != 0
+		e.shortcut.AddShortcut(&desktop.CustomShortcut{KeyName: fyne.KeyLeft, Modifier: m}, func(se fyne.Shortcut) {
+			e.moveWord(false, extend)
+		})
+		e.shortcut.AddShortcut(&desktop.CustomShortcut{KeyName: fyne.KeyRight, Modifier: m}, func(se fyne.Shortcut) {
+			e.moveWord(true, extend)
+		})
+	}
 }
 
 func (e *Editor) Refresh() {
 	log.Println("Editor.Refresh")
 	e.Lock()
 	textWrap := e.TextWrap
-	textStyle := e.TextStyle
-	textSize := e.TextSize
-	maxWidth := e.Size().Width - 2*theme.Padding()
 
-	// TODO only redo this if Buffer, textWrap, or maxWidth has changed
-	e.Lines = lineBounds(e.Buffer, textWrap, maxWidth, func(text []rune) int {
-		return fyne.MeasureText(string(text), textSize, textStyle).Width
-	})
+	e.gutter = 0
+	if e.ShowLineNumbers {
+		// Wide enough for the number of the last line
+		last := strconv.Itoa(e.Buffer.Lines())
+		e.gutter = fyne.MeasureText(last, e.TextSize, e.TextStyle).Width + theme.Padding()*2
+	}
+	maxWidth := e.Size().Width - 2*theme.Padding() - e.gutter
+
+	e.wrapLines(textWrap, maxWidth)
 
-	log.Println("Lines:", e.Lines)
+	moved := e.Cursor != e.refreshedCursor || e.Selection != e.refreshedSelection || e.IsSelecting != e.refreshedSelecting
+	e.refreshedCursor = e.Cursor
+	e.refreshedSelection = e.Selection
+	e.refreshedSelecting = e.IsSelecting
 	e.Unlock()
 	e.BaseWidget.Refresh()
+	if moved && e.OnCursorChanged != nil {
+		e.OnCursorChanged()
+	}
+}
+
+// Resize also wraps the text again if the width changed.
+func (e *Editor) Resize(size fyne.Size) {
+	width := e.Size().Width
+	e.BaseWidget.Resize(size)
+	if size.Width != width {
+		e.Refresh()
+	}
+}
+
+// Move also draws the rows scrolled into view, as Scroll moves the editor to show them.
+func (e *Editor) Move(position fyne.Position) {
+	e.BaseWidget.Move(position)
+	e.Lock()
+	first, last := e.visibleRows(0)
+	drawn := first >= e.drawn[0] && last <= e.drawn[1]
+	e.Unlock()
+	if !drawn {
+		e.BaseWidget.Refresh()
+	}
+}
+
+// visibleRows returns the first and last row shown by Scroll, extended by the given margin, or every row if Scroll is not set.
+// The first row is never after the last, which is before the first if there are no rows.
+// The caller must hold the lock.
+func (e *Editor) visibleRows(margin int) (int, int) {
+	first, last := 0, len(e.Lines)-1
+	if e.Scroll != nil {
+		rowHeight := e.charMinSize().Height
+		top := e.Scroll.Offset.Y - theme.Padding()
+		if f := top/rowHeight - margin; f > first {
+			first = f
+		}
+		if l := (top+e.Scroll.Size().Height)/rowHeight + margin; l < last {
+			last = l
+		}
+		// Scrolled past the end, such as when the text has just shrunk, so show the last rows
+		if first > last {
+			first = last - e.Scroll.Size().Height/rowHeight - margin
+			if first < 0 {
+				first = 0
+			}
+		}
+	}
+	return first, last
+}
+
+// wrapLines splits the buffer into rows, only measuring the lines whose text or styles changed since the last refresh, unless the wrap mode, width, or text size or style changed.
+// The caller must hold the lock.
+func (e *Editor) wrapLines(wrap fyne.TextWrap, maxWidth int) {
+	key := wrapKey{
+		wrap:      wrap,
+		maxWidth:  maxWidth,
+		textSize:  e.TextSize,
+		textStyle: e.TextStyle,
+	}
+	if key != e.wrappedKey {
+		e.wrapped = nil
+		e.wrappedKey = key
+	}
+	wrapped := make(map[string]*wrappedLine)
+	e.Lines = nil
+	e.width = 0
+	empty := e.charMinSize().Width
+	for i := 0; i < e.Buffer.Lines(); i++ {
+		start, end := e.Buffer.LineStart(i), e.Buffer.LineEnd(i)
+		runes := e.Buffer.Slice(start, end)
+		text := string(runes)
+		var styles []*Style
+		if e.TextStyles != nil {
+			styles = e.TextStyles(i)
+		}
+		w, ok := wrapped[text]
+		if !ok {
+			w, ok = e.wrapped[text]
+		}
+		if !ok || !sameStyles(w.styles, styles) {
+			w = &wrappedLine{
+				styles: styles,
+			}
+			for _, r := range lineBounds(runes, wrap, maxWidth, func(low, high int) int {
+				return e.measure(start+low, start+high)
+			}) {
+				w.rows = append(w.rows, r)
+				width := empty
+				if r.start < r.end {
+					width = e.measure(start+r.start, start+r.end)
+				}
+				if width > w.width {
+					w.width = width
+				}
+			}
+		}
+		wrapped[text] = w
+		for _, r := range w.rows {
+			e.Lines = append(e.Lines, &Line{start: start + r.start, end: start + r.end})
+		}
+		if w.width > e.width {
+			e.width = w.width
+		}
+	}
+	e.wrapped = wrapped
+}
+
+// sameStyles returns true if both slices hold the same styles, a missing slice is the same as one without any style.
+func sameStyles(a, b []*Style) bool {
+	for i := 0; i < len(a) || i < len(b); i++ {
+		var x, y *Style
+		if i < len(a) {
+			x = a[i]
+		}
+		if i < len(b) {
+			y = b[i]
+		}
+		if x != y {
+			return false
+		}
+	}
+	return true
 }
 
 func (e *Editor) SetText(text string) {
-	e.Buffer = []rune(text)
+	e.Buffer = NewRope(text)
+	e.Refresh()
+}
+
+// SetLexer highlights the buffer with the given lexer, or stops highlighting if it is nil.
+func (e *Editor) SetLexer(lexer Lexer) {
+	e.Lock()
+	e.lexer = lexer
+	if lexer == nil {
+		e.TextStyles = nil
+	} else {
+		highlighter := NewHighlighter(lexer)
+		e.TextStyles = func(line int) []*Style {
+			return highlighter.LineStyles(e.Buffer, line)
+		}
+	}
+	e.Unlock()
 	e.Refresh()
 }
 
+// measure returns the width of the runes of the buffer from start to end, each drawn in its style.
+// Each rune is only measured once, so whole documents can be wrapped quickly.
+// The caller must hold the lock.
+func (e *Editor) measure(start, end int) int {
+	if e.advances == nil || e.advancesSize != e.TextSize {
+		e.advances = make(map[advanceKey]float64)
+		e.advancesSize = e.TextSize
+	}
+	var width float64
+	styles, styled := e.stylesAt(start)
+	for _, r := range styleRuns(nil, styles, styled, start, end) {
+		style := e.textStyle(r.style)
+		for _, c := range e.Buffer.Slice(r.start, r.end) {
+			key := advanceKey{r: c, style: style}
+			advance, ok := e.advances[key]
+			if !ok {
+				advance = float64(fyne.MeasureText(strings.Repeat(string(c), ADVANCE_SAMPLE), e.TextSize, style).Width) / ADVANCE_SAMPLE
+				e.advances[key] = advance
+			}
+			width += advance
+		}
+	}
+	return int(math.Ceil(width))
+}
+
+// stylesAt returns the styles of the runes of the line holding the given offset, and the offset of the first of them, or nil if the buffer is not styled.
+// The caller must hold the lock.
+func (e *Editor) stylesAt(offset int) ([]*Style, int) {
+	if e.TextStyles == nil {
+		return nil, offset
+	}
+	line := e.Buffer.LineOf(offset)
+	return e.TextStyles(line), e.Buffer.LineStart(line)
+}
+
+// textStyle returns the editor's TextStyle with that of the given style added.
+func (e *Editor) textStyle(style *Style) fyne.TextStyle {
+	s := e.TextStyle
+	if style != nil {
+		s.Bold = s.Bold || style.TextStyle.Bold
+		s.Italic = s.Italic || style.TextStyle.Italic
+		s.Monospace = s.Monospace || style.TextStyle.Monospace
+	}
+	return s
+}
+
 // splitLines accepts a slice of runes and returns a slice containing the
 // start and end indicies of each line delimited by the newline character.
 func splitLines(text []rune) []*Line {
@@ -155,9 +472,9 @@ func findSpaceIndex(text []rune, fallback int) int {
 	return curIndex
 }
 
-// lineBounds accepts a slice of runes, a wrapping mode, a maximum line width and a function to measure line width.
+// lineBounds accepts a slice of runes, a wrapping mode, a maximum line width and a function to measure the width of the runes from start to end.
 // lineBounds returns a slice containing the start and end indicies of each line with the given wrapping applied.
-func lineBounds(text []rune, wrap fyne.TextWrap, maxWidth int, measurer func([]rune) int) []*Line {
+func lineBounds(text []rune, wrap fyne.TextWrap, maxWidth int, measurer func(int, int) int) []*Line {
 
 	Lines := splitLines(text)
 	if maxWidth <= 0 || wrap == fyne.TextWrapOff {
@@ -165,7 +482,7 @@ func lineBounds(text []rune, wrap fyne.TextWrap, maxWidth int, measurer func([]r
 	}
 
 	checker := func(low int, high int) bool {
-		return measurer(text[low:high]) <= maxWidth
+		return measurer(low, high) <= maxWidth
 	}
 
 	var bounds []*Line
@@ -182,7 +499,7 @@ func lineBounds(text []rune, wrap fyne.TextWrap, maxWidth int, measurer func([]r
 			bounds = append(bounds, &Line{start: low, end: high})
 		case fyne.TextWrapBreak:
 			for low < high {
-				if measurer(text[low:high]) <= maxWidth {
+				if measurer(low, high) <= maxWidth {
 					bounds = append(bounds, &Line{start: low, end: high})
 					low = high
 					high = l.end
@@ -193,7 +510,7 @@ func lineBounds(text []rune, wrap fyne.TextWrap, maxWidth int, measurer func([]r
 		case fyne.TextWrapWord:
 			for low < high {
 				sub := text[low:high]
-				if measurer(sub) <= maxWidth {
+				if measurer(low, high) <= maxWidth {
 					bounds = append(bounds, &Line{start: low, end: high})
 					low = high
 					high = l.end
@@ -226,67 +543,296 @@ func (e *Editor) Focused() bool {
 
 func (e *Editor) Tapped(event *fyne.PointEvent) {
 	log.Println("Editor.Tapped:", event)
+	if e.selectNumberedLine(event.Position) {
+		return
+	}
 	e.updateCursor(event)
 }
 
 func (e *Editor) TappedSecondary(event *fyne.PointEvent) {
 	log.Println("Editor.TappedSecondary:", event)
-	log.Println("TODO")
-	// TODO show popup menu
-	//  - cut
-	//  - copy
-	//  - paste
-	//  - select all
-	//  - reveal in tree
+	super := e.super()
+	driver := fyne.CurrentApp().Driver()
+	c := driver.CanvasForObject(super)
+	if c == nil {
+		return
+	}
+	var clipboard fyne.Clipboard
+	for _, w := range driver.AllWindows() {
+		if w.Canvas() == c {
+			clipboard = w.Clipboard()
+			break
+		}
+	}
+	position := driver.AbsolutePositionForObject(super).Add(event.Position)
+	widget.ShowPopUpMenuAtPosition(e.ContextMenu(clipboard), c, position)
+}
+
+// ContextMenu returns the menu shown when the editor is tapped with the secondary button.
+// The clipboard items are left out when the clipboard is nil, as when the editor is not in a window.
+func (e *Editor) ContextMenu(clipboard fyne.Clipboard) *fyne.Menu {
+	super := e.super()
+	var items []*fyne.MenuItem
+	if clipboard != nil {
+		if !e.ReadOnly {
+			items = append(items, fyne.NewMenuItem("Cut", func() {
+				super.CutToClipboard(clipboard)
+			}))
+		}
+		items = append(items, fyne.NewMenuItem("Copy", func() {
+			super.CopyToClipboard(clipboard)
+		}))
+		if !e.ReadOnly {
+			items = append(items, fyne.NewMenuItem("Paste", func() {
+				super.PasteFromClipboard(clipboard)
+			}))
+		}
+	}
+	items = append(items, fyne.NewMenuItem("Select All", super.SelectAll))
+	if e.OnReveal != nil {
+		items = append(items, fyne.NewMenuItemSeparator(), fyne.NewMenuItem("Reveal in Tree", e.OnReveal))
+	}
+	return fyne.NewMenu("", items...)
 }
 
 func (e *Editor) DoubleTapped(event *fyne.PointEvent) {
 	log.Println("Editor.DoubleTapped:", event)
-	log.Println("TODO")
-	// TODO select tapped text
+	e.Lock()
+	// Further taps within the interval are delivered as double taps, count them to detect triple and quadruple taps
+	now := time.Now()
+	if now.Sub(e.tapped) <= TAP_INTERVAL {
+		e.taps++
+	} else {
+		e.taps = 2
+	}
+	e.tapped = now
+	cursor := int(e.positionCursor(event.Position))
+	var start, end int
+	switch e.taps {
+	case 2:
+		// Select word
+		start, end = WordBounds(e.Buffer, cursor)
+	case 3:
+		// Select visual line
+		row := 0
+		for i, line := range e.Lines {
+			if line.start <= cursor && line.end >= cursor {
+				row = i
+				break
+			}
+		}
+		if len(e.Lines) > 0 {
+			start, end = e.Lines[row].start, e.Lines[row].end
+		}
+	default:
+		// Select paragraph
+		line := e.Buffer.LineOf(cursor)
+		start, end = e.Buffer.LineStart(line), e.Buffer.LineEnd(line)
+	}
+	e.Selection = uint64(start)
+	e.Cursor = uint64(end)
+	e.IsSelecting = start < end
+	e.Unlock()
+	e.Refresh()
 }
 
 func (e *Editor) MouseDown(event *desktop.MouseEvent) {
 	log.Println("Editor.MouseDown:", event)
+	if event.Modifier&desktop.ShiftModifier == 0 && e.selectNumberedLine(event.Position) {
+		return
+	}
+	e.Lock()
+	if event.Modifier&desktop.ShiftModifier != 0 {
+		// Extend selection from current cursor
+		e.startSelecting()
+	} else {
+		e.IsSelecting = false
+	}
+	e.Unlock()
 	e.updateCursor(&event.PointEvent)
 }
 
 func (e *Editor) MouseUp(event *desktop.MouseEvent) {
 	log.Println("Editor.MouseUp:", event)
-	// TODO
+	e.Lock()
+	e.stopSelectingIfEmpty()
+	e.Unlock()
 }
 
 func (e *Editor) Dragged(event *fyne.DragEvent) {
 	log.Println("Editor.Dragged:", event)
-	log.Println("TODO")
-	// TODO select text
+	e.Lock()
+	e.startSelecting()
+	e.Unlock()
+	e.updateCursor(&event.PointEvent)
 }
 
 func (e *Editor) DragEnd() {
 	log.Println("Editor.DragEnd")
-	log.Println("TODO")
-	// TODO stop Selecting
+	e.Lock()
+	e.stopSelectingIfEmpty()
+	e.Unlock()
+	e.Refresh()
 }
 
 func (e *Editor) KeyDown(event *fyne.KeyEvent) {
 	log.Println("Editor.KeyDown:", event)
-	// TODO
+	switch event.Name {
+	case desktop.KeyShiftLeft, desktop.KeyShiftRight:
+		e.shift = true
+	}
 }
 
 func (e *Editor) KeyUp(event *fyne.KeyEvent) {
 	log.Println("Editor.KeyUp:", event)
-	// TODO
+	switch event.Name {
+	case desktop.KeyShiftLeft, desktop.KeyShiftRight:
+		e.shift = false
+	}
 	e.Refresh()
 }
 
 func (e *Editor) TypedKey(event *fyne.KeyEvent) {
 	log.Println("Editor.TypedKey:", event)
-	// TODO
+	e.Lock()
+	length := uint64(e.Buffer.Len())
+	if e.Cursor > length {
+		e.Cursor = length
+	}
+	switch event.Name {
+	case fyne.KeyBackspace, fyne.KeyDelete, fyne.KeyReturn, fyne.KeyEnter, fyne.KeyTab:
+		if e.ReadOnly {
+			e.Unlock()
+			return
+		}
+	}
+	switch event.Name {
+	case fyne.KeyBackspace:
+		if !e.IsSelecting {
+			if e.Cursor == 0 {
+				e.Unlock()
+				return
+			}
+			// Select previous rune
+			e.Selection = e.Cursor - 1
+			e.IsSelecting = true
+		}
+		e.eraseSelection()
+	case fyne.KeyDelete:
+		if !e.IsSelecting {
+			if e.Cursor == length {
+				e.Unlock()
+				return
+			}
+			// Select next rune
+			e.Selection = e.Cursor + 1
+			e.IsSelecting = true
+		}
+		e.eraseSelection()
+	case fyne.KeyReturn, fyne.KeyEnter:
+		e.eraseSelection()
+		e.insert('\n')
+	case fyne.KeyTab:
+		// The desktop driver uses Tab to move focus, so this is only reached on other drivers
+		e.eraseSelection()
+		e.insert('\t')
+	default:
+		if !e.moveCursor(event.Name) {
+			e.Unlock()
+			return
+		}
+	}
+	e.Unlock()
+	e.Refresh()
+}
+
+// moveCursor moves the cursor according to the given navigation key, extending the selection if shift is held.
+// moveCursor returns false if the key is not a navigation key.
+// The caller must hold the lock.
+func (e *Editor) moveCursor(key fyne.KeyName) bool {
+	length := uint64(e.Buffer.Len())
+	previous := e.Cursor
+	switch key {
+	case fyne.KeyLeft:
+		if e.Cursor > 0 {
+			e.Cursor--
+		}
+	case fyne.KeyRight:
+		if e.Cursor < length {
+			e.Cursor++
+		}
+	case fyne.KeyUp:
+		row, column := e.cursorRowColumn()
+		if row > 0 {
+			e.Cursor = e.rowColumnCursor(row-1, column)
+		} else {
+			e.Cursor = 0
+		}
+	case fyne.KeyDown:
+		row, column := e.cursorRowColumn()
+		if row < len(e.Lines)-1 {
+			e.Cursor = e.rowColumnCursor(row+1, column)
+		} else {
+			e.Cursor = length
+		}
+	case fyne.KeyHome:
+		row, _ := e.cursorRowColumn()
+		e.Cursor = e.rowColumnCursor(row, 0)
+	case fyne.KeyEnd:
+		row, _ := e.cursorRowColumn()
+		e.Cursor = e.rowColumnCursor(row, int(length))
+	case fyne.KeyPageUp:
+		e.Cursor = 0
+	case fyne.KeyPageDown:
+		e.Cursor = length
+	default:
+		return false
+	}
+	e.updateSelection(previous, e.shift)
+	return true
+}
+
+// moveWord moves the cursor to the next or previous word, extending the selection if requested.
+func (e *Editor) moveWord(forward, extend bool) {
+	e.Lock()
+	previous := e.Cursor
+	if length := uint64(e.Buffer.Len()); previous > length {
+		previous = length
+	}
+	if forward {
+		e.Cursor = uint64(NextWordIndex(e.Buffer, int(previous)))
+	} else {
+		e.Cursor = uint64(PreviousWordIndex(e.Buffer, int(previous)))
+	}
+	e.updateSelection(previous, extend)
+	e.Unlock()
+	e.Refresh()
+}
+
+// updateSelection extends the selection from the given previous cursor if requested, otherwise it ends the selection.
+// The caller must hold the lock.
+func (e *Editor) updateSelection(previous uint64, extend bool) {
+	if extend {
+		if !e.IsSelecting {
+			e.Selection = previous
+			e.IsSelecting = true
+		}
+		e.stopSelectingIfEmpty()
+	} else {
+		e.IsSelecting = false
+	}
 }
 
 func (e *Editor) TypedRune(r rune) {
 	log.Println("Editor.TypedRune:", r)
-	// TODO
+	if e.ReadOnly {
+		return
+	}
+	e.Lock()
+	e.eraseSelection()
+	e.insert(r)
+	e.Unlock()
+	e.Refresh()
 }
 
 func (e *Editor) TypedShortcut(shortcut fyne.Shortcut) {
@@ -296,11 +842,11 @@ func (e *Editor) TypedShortcut(shortcut fyne.Shortcut) {
 
 func (e *Editor) CutToClipboard(clipboard fyne.Clipboard) {
 	log.Println("Editor.CutToClipboard:", clipboard)
-	if !e.IsSelecting {
+	if !e.IsSelecting || e.ReadOnly {
 		return
 	}
 	clipboard.SetContent(e.SelectedText())
-	e.EraseSelection()
+	e.super().EraseSelection()
 }
 
 func (e *Editor) CopyToClipboard(clipboard fyne.Clipboard) {
@@ -313,57 +859,311 @@ func (e *Editor) CopyToClipboard(clipboard fyne.Clipboard) {
 
 func (e *Editor) PasteFromClipboard(clipboard fyne.Clipboard) {
 	log.Println("Editor.PasteFromClipboard:", clipboard)
-	// TODO
+	if e.ReadOnly {
+		return
+	}
+	content := clipboard.Content()
+	if content == "" {
+		return
+	}
+	e.Lock()
+	e.eraseSelection()
+	e.insert([]rune(content)...)
+	e.Unlock()
+	e.Refresh()
 }
 
 func (e *Editor) SelectAll() {
 	log.Println("Editor.SelectAll")
-	// TODO
+	e.Lock()
+	e.Selection = 0
+	e.Cursor = uint64(e.Buffer.Len())
+	e.IsSelecting = e.Cursor > 0
+	e.Unlock()
+	e.Refresh()
+}
+
+// Select selects the runes from start up to end, clamped to the buffer, and scrolls to show them.
+func (e *Editor) Select(start, end uint64) {
+	e.Lock()
+	length := uint64(e.Buffer.Len())
+	if end > length {
+		end = length
+	}
+	if start > end {
+		start = end
+	}
+	e.Selection = start
+	e.Cursor = end
+	e.IsSelecting = start < end
+	e.Unlock()
+	e.Refresh()
+	e.ScrollToCursor()
 }
 
 func (e *Editor) EraseSelection() {
 	log.Println("Editor.EraseSelection")
-	// TODO
+	if e.ReadOnly {
+		return
+	}
+	e.Lock()
+	e.eraseSelection()
+	e.Unlock()
+	e.Refresh()
 }
 
 func (e *Editor) SelectedText() string {
+	e.Lock()
+	defer e.Unlock()
+	return e.selectedText()
+}
+
+// selectionBounds returns the start and end of the selection, limited to the length of the buffer.
+// The caller must hold the lock.
+func (e *Editor) selectionBounds() (uint64, uint64) {
+	start, end := e.Cursor, e.Selection
+	if end < start {
+		start, end = end, start
+	}
+	length := uint64(e.Buffer.Len())
+	if start > length {
+		start = length
+	}
+	if end > length {
+		end = length
+	}
+	return start, end
+}
+
+// selectedText returns the selected text, or an empty string if there is no selection.
+// The caller must hold the lock.
+func (e *Editor) selectedText() string {
 	if !e.IsSelecting {
 		return ""
 	}
+	start, end := e.selectionBounds()
+	return string(e.Buffer.Slice(int(start), int(end)))
+}
+
+// eraseSelection removes the selected text from the buffer and moves the cursor to where it was.
+// The caller must hold the lock.
+func (e *Editor) eraseSelection() {
+	if length := uint64(e.Buffer.Len()); e.Cursor > length {
+		e.Cursor = length
+	}
+	if !e.IsSelecting {
+		return
+	}
+	start, end := e.selectionBounds()
+	e.Buffer.Remove(int(start), int(end))
+	e.Cursor = start
+	e.IsSelecting = false
+}
+
+// insert adds the given runes to the buffer at the cursor, and moves the cursor after them.
+// The caller must hold the lock.
+func (e *Editor) insert(runes ...rune) {
+	e.Buffer.Insert(int(e.Cursor), runes)
+	e.Cursor += uint64(len(runes))
+}
+
+// cursorRowColumn returns the index of the line containing the cursor, and the cursor's offset within that line.
+// The caller must hold the lock.
+func (e *Editor) cursorRowColumn() (int, int) {
+	if len(e.Lines) == 0 {
+		return 0, 0
+	}
+	cursor := int(e.Cursor)
+	for i, line := range e.Lines {
+		if line.start <= cursor && line.end >= cursor {
+			return i, cursor - line.start
+		}
+	}
+	last := len(e.Lines) - 1
+	return last, e.Lines[last].end - e.Lines[last].start
+}
+
+// rowColumnCursor returns the cursor for the given line and offset, limited to the length of the line.
+// The caller must hold the lock.
+func (e *Editor) rowColumnCursor(row, column int) uint64 {
+	if len(e.Lines) == 0 {
+		return 0
+	}
+	line := e.Lines[row]
+	if length := line.end - line.start; column > length {
+		column = length
+	}
+	return uint64(line.start + column)
+}
+
+// startSelecting anchors the selection at the cursor, unless a selection is already in progress.
+// The caller must hold the lock.
+func (e *Editor) startSelecting() {
+	if !e.IsSelecting {
+		e.Selection = e.Cursor
+		e.IsSelecting = true
+	}
+}
+
+// stopSelectingIfEmpty ends the selection if the cursor has returned to the anchor.
+// The caller must hold the lock.
+func (e *Editor) stopSelectingIfEmpty() {
+	if e.IsSelecting && e.Selection == e.Cursor {
+		e.IsSelecting = false
+	}
+}
+
+// selectedLines returns the selected part of each line spanned by the selection.
+// The caller must hold the lock.
+func (e *Editor) selectedLines() []*selectedLine {
+	if !e.IsSelecting {
+		return nil
+	}
+	return e.linesBetween(e.Cursor, e.Selection)
+}
+
+// linesBetween returns the columns of each line between the given offsets.
+// The caller must hold the lock.
+func (e *Editor) linesBetween(from, to uint64) (selected []*selectedLine) {
+	low, high := int(from), int(to)
+	if high < low {
+		low, high = high, low
+	}
+	first := sort.Search(len(e.Lines), func(i int) bool {
+		return e.Lines[i].end >= low
+	})
+	for i := first; i < len(e.Lines); i++ {
+		line := e.Lines[i]
+		if line.start >= high {
+			break
+		}
+		start := low - line.start
+		if start < 0 {
+			start = 0
+		}
+		end := high - line.start
+		newline := false
+		if end > line.end-line.start {
+			end = line.end - line.start
+			// Line breaks occupy a rune, wrapping may not
+			newline = i < len(e.Lines)-1 && e.Lines[i+1].start > line.end
+		}
+		if start < end || newline {
+			selected = append(selected, &selectedLine{
+				row:     i,
+				start:   start,
+				end:     end,
+				newline: newline,
+			})
+		}
+	}
+	return
+}
+
+// ScrollToCursor scrolls Scroll, if set, so the row holding the cursor is shown.
+func (e *Editor) ScrollToCursor() {
 	e.Lock()
-	start := e.Cursor
-	end := e.Selection
+	scroll := e.Scroll
+	if scroll == nil {
+		e.Unlock()
+		return
+	}
+	row, _ := e.cursorRowColumn()
+	rowHeight := e.charMinSize().Height
+	top := theme.Padding() + row*rowHeight
+	offset := scroll.Offset.Y
+	if top < offset {
+		offset = top
+	} else if bottom := top + rowHeight - scroll.Size().Height; bottom > offset {
+		offset = bottom
+	}
 	e.Unlock()
-	if end < start {
-		start, end = end, start
+	if offset != scroll.Offset.Y {
+		scroll.Offset.Y = offset
+		scroll.Refresh()
 	}
-	return string(e.Buffer[start:end])
 }
 
 func (e *Editor) updateCursor(event *fyne.PointEvent) {
 	e.Lock()
+	e.Cursor = e.positionCursor(event.Position)
+	e.Unlock()
+	e.Refresh()
+}
+
+// selectNumberedLine selects the whole line, including its line break, if the given position is on its number in the gutter, and returns false if it is not.
+func (e *Editor) selectNumberedLine(position fyne.Position) bool {
+	e.Lock()
+	if e.gutter == 0 || position.X >= e.textLeft() || len(e.Lines) == 0 {
+		e.Unlock()
+		return false
+	}
+	row := int(math.Floor(float64(position.Y-theme.Padding()) / float64(e.charMinSize().Height)))
+	if row < 0 || row >= len(e.Lines) {
+		e.Unlock()
+		return false
+	}
+	// Wrapped rows are numbered by the line they continue
+	line := e.Buffer.LineOf(e.Lines[row].start)
+	start, end := e.Buffer.LineStart(line), e.Buffer.LineEnd(line)
+	if end < e.Buffer.Len() {
+		end++
+	}
+	e.Selection = uint64(start)
+	e.Cursor = uint64(end)
+	e.IsSelecting = start < end
+	e.Unlock()
+	e.Refresh()
+	return true
+}
+
+// textLeft returns the position of the start of each row, after the padding and gutter.
+// The caller must hold the lock.
+func (e *Editor) textLeft() int {
+	return theme.Padding() + e.gutter
+}
+
+// positionCursor returns the cursor closest to the given position.
+// The caller must hold the lock.
+func (e *Editor) positionCursor(position fyne.Position) uint64 {
+	if len(e.Lines) == 0 {
+		return 0
+	}
 	rowHeight := e.charMinSize().Height
-	row := int(math.Floor(float64(event.Position.Y-theme.Padding()) / float64(rowHeight)))
+	row := int(math.Floor(float64(position.Y-theme.Padding()) / float64(rowHeight)))
 	if row < 0 {
 		row = 0
 	} else if row >= len(e.Lines) {
 		row = len(e.Lines) - 1
 	}
 	line := e.Lines[row]
-	e.Cursor = uint64(line.start)
-	text := e.Buffer[line.start:line.end]
-	style := e.TextStyle
-	size := e.TextSize
-	for i := 0; i < len(text); i++ {
-		width := fyne.MeasureText(string(text[0:i]), size, style).Width
-		if width+theme.Padding() > event.Position.X {
+	cursor := uint64(line.start)
+	for i := line.start; i < line.end; i++ {
+		width := e.measure(line.start, i)
+		if width+e.textLeft() > position.X {
 			break
 		} else {
-			e.Cursor++
+			cursor++
 		}
 	}
-	e.Unlock()
-	e.Refresh()
+	return cursor
+}
+
+// positionRune returns the offset of the rune drawn at the given position, or false if there is none.
+// The caller must hold the lock.
+func (e *Editor) positionRune(position fyne.Position) (uint64, bool) {
+	rowHeight := e.charMinSize().Height
+	row := int(math.Floor(float64(position.Y-theme.Padding()) / float64(rowHeight)))
+	if row < 0 || row >= len(e.Lines) || position.X < e.textLeft() {
+		return 0, false
+	}
+	line := e.Lines[row]
+	for i := line.start; i < line.end; i++ {
+		if e.measure(line.start, i+1)+e.textLeft() > position.X {
+			return uint64(i), true
+		}
+	}
+	return 0, false
 }
 
 func (e *Editor) charMinSize() fyne.Size {
@@ -373,101 +1173,495 @@ func (e *Editor) charMinSize() fyne.Size {
 func (e *Editor) CreateRenderer() fyne.WidgetRenderer {
 	cursor := canvas.NewRectangle(theme.FocusColor())
 	cursor.Hide()
+	tooltip := canvas.NewText("", theme.TextColor())
+	tooltip.Hide()
+	tooltipBackground := canvas.NewRectangle(theme.ButtonColor())
+	tooltipBackground.Hide()
+	current := canvas.NewRectangle(theme.HoverColor())
+	current.Hide()
 	return &EditorRenderer{
-		editor:  e,
-		cursor:  cursor,
-		objects: []fyne.CanvasObject{cursor},
+		editor:            e,
+		cursor:            cursor,
+		current:           current,
+		tooltip:           tooltip,
+		tooltipBackground: tooltipBackground,
+		objects:           []fyne.CanvasObject{cursor},
 	}
 }
 
 type EditorRenderer struct {
-	editor    *Editor
-	cursor    *canvas.Rectangle
-	texts     []*canvas.Text
-	selection []fyne.CanvasObject
-	objects   []fyne.CanvasObject
+	// lock guards the fields of the renderer, as the editor is refreshed from the goroutines reading its channel, as well as by the driver
+	lock              sync.Mutex
+	editor            *Editor
+	cursor            *canvas.Rectangle
+	texts             []*canvas.Text
+	runs              []*textRun // Where to draw each text
+	selected          []*selectedLine
+	selection         []fyne.CanvasObject
+	carets            []*Caret
+	caretCursors      []*canvas.Rectangle
+	caretLabels       []*canvas.Text
+	caretSelected     []*selectedLine
+	caretSelection    []fyne.CanvasObject
+	current           *canvas.Rectangle // Highlights the line holding the cursor
+	currentRows       [2]int            // First and last row of the line holding the cursor
+	matchSelected     []*selectedLine
+	matchHighlights   []fyne.CanvasObject
+	numbers           []*canvas.Text
+	numberRows        []int // Row of each line number
+	numberValues      []int // Index of the line of each line number
+	tooltip           *canvas.Text
+	tooltipBackground *canvas.Rectangle
+	objects           []fyne.CanvasObject
+
+	// Measured by Refresh while it holds the lock of the editor, so Layout and MinSize do not read the editor
+	cursorAt      caretPosition
+	caretsAt      []caretPosition
+	selectedAt    []highlightPosition
+	caretSelectAt []highlightPosition
+	matchAt       []highlightPosition
+	rowHeight     int
+	gutter        int
+	textLeft      int
+	tooltipAt     fyne.Position
+	minSize       fyne.Size
+}
+
+// caretPosition holds the position of a cursor, and the height of its line, or false if it is not within a line.
+type caretPosition struct {
+	position fyne.Position
+	height   int
+	ok       bool
+}
+
+// highlightPosition holds the row, and the start and end, in pixels from the left of the text, of the selected part of a line.
+type highlightPosition struct {
+	row, start, end int
+}
+
+// textRun holds the row of a text, and the width of the text before it on its row.
+// A run which is the whole of its line fills the row, so it can be aligned.
+type textRun struct {
+	row   int
+	x     int
+	whole bool
+}
+
+// styleRun holds the start and end of a run of runes drawn in the same color and style.
+type styleRun struct {
+	start, end int
+	color      color.Color
+	style      *Style
+}
+
+// styleRuns splits the runes from start to end into runs of the same color and style, an empty range is a single run.
+// The colors are those of the runes of the buffer, and the styles those of the runes from styled.
+func styleRuns(colors []color.Color, styles []*Style, styled, start, end int) []*styleRun {
+	colorAt := func(i int) color.Color {
+		if i < len(colors) {
+			return colors[i]
+		}
+		return nil
+	}
+	styleAt := func(i int) *Style {
+		if i -= styled; i >= 0 && i < len(styles) {
+			return styles[i]
+		}
+		return nil
+	}
+	run := &styleRun{start: start, end: start, color: colorAt(start), style: styleAt(start)}
+	runs := []*styleRun{run}
+	for i := start; i < end; i++ {
+		if c, s := colorAt(i), styleAt(i); c != run.color || s != run.style {
+			run = &styleRun{start: i, end: i, color: c, style: s}
+			runs = append(runs, run)
+		}
+		run.end = i + 1
+	}
+	return runs
 }
 
 func (r *EditorRenderer) Layout(size fyne.Size) {
 	//log.Println("EditorRenderer.Layout:", size)
-	if r.cursor.Visible() {
-		cursor := r.editor.Cursor
-		for i, line := range r.editor.Lines {
-			if uint64(line.start) <= cursor && uint64(line.end) >= cursor {
-				text := string(r.editor.Buffer[line.start:cursor])
-				size := fyne.MeasureText(text, r.editor.TextSize, r.editor.TextStyle)
-				r.cursor.Resize(fyne.NewSize(2, size.Height))
-				r.cursor.Move(fyne.NewPos(size.Width-1+theme.Padding(), size.Height*i+theme.Padding()))
-				break
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.layout(size)
+}
+
+// layout positions the objects of the editor, as measured by the last refresh.
+// The caller must hold the lock of the renderer.
+func (r *EditorRenderer) layout(size fyne.Size) {
+	if r.cursor.Visible() && r.cursorAt.ok {
+		r.cursor.Resize(fyne.NewSize(2, r.cursorAt.height))
+		r.cursor.Move(r.cursorAt.position)
+	}
+
+	rowHeight := r.rowHeight
+	lineSize := fyne.NewSize(size.Width-theme.Padding()*2-r.gutter, rowHeight)
+	if r.current.Visible() {
+		first, last := r.currentRows[0], r.currentRows[1]
+		r.current.Resize(fyne.NewSize(size.Width, rowHeight*(last-first+1)))
+		r.current.Move(fyne.NewPos(0, theme.Padding()+rowHeight*first))
+	}
+	for i, row := range r.numberRows {
+		n := r.numbers[i]
+		n.Resize(fyne.NewSize(r.gutter-theme.Padding(), rowHeight))
+		n.Move(fyne.NewPos(theme.Padding(), theme.Padding()+rowHeight*row))
+	}
+	r.layoutLines(r.matchAt, r.matchHighlights)
+	r.layoutLines(r.selectedAt, r.selection)
+	r.layoutLines(r.caretSelectAt, r.caretSelection)
+	for i := range r.carets {
+		at := r.caretsAt[i]
+		if !at.ok {
+			r.caretCursors[i].Hide()
+			r.caretLabels[i].Hide()
+			continue
+		}
+		r.caretCursors[i].Resize(fyne.NewSize(2, at.height))
+		r.caretCursors[i].Move(at.position)
+		label := r.caretLabels[i]
+		labelSize := label.MinSize()
+		label.Resize(labelSize)
+		// Above the caret, unless it is on the first line
+		y := at.position.Y - labelSize.Height
+		if y < 0 {
+			y = at.position.Y + at.height
+		}
+		label.Move(fyne.NewPos(at.position.X, y))
+	}
+	for i, t := range r.texts {
+		run := r.runs[i]
+		if run.whole {
+			t.Resize(lineSize)
+		} else {
+			t.Resize(fyne.NewSize(t.MinSize().Width, rowHeight))
+		}
+		t.Move(fyne.NewPos(r.textLeft+run.x, theme.Padding()+rowHeight*run.row))
+	}
+
+	if r.tooltip.Visible() {
+		// Below and right of the mouse, so it is not hidden by the pointer
+		position := r.tooltipAt.Add(fyne.NewPos(theme.Padding()*2, theme.Padding()*2))
+		textSize := r.tooltip.MinSize()
+		r.tooltip.Resize(textSize)
+		r.tooltip.Move(position.Add(fyne.NewPos(theme.Padding(), theme.Padding())))
+		r.tooltipBackground.Resize(textSize.Add(fyne.NewSize(theme.Padding()*2, theme.Padding()*2)))
+		r.tooltipBackground.Move(position)
+	}
+}
+
+// cursorPosition returns the position of the given cursor, and the height of its line.
+// The caller must hold the lock of the editor.
+func (r *EditorRenderer) cursorPosition(cursor uint64) caretPosition {
+	for i, line := range r.editor.Lines {
+		if uint64(line.start) <= cursor && uint64(line.end) >= cursor {
+			height := r.editor.charMinSize().Height
+			return caretPosition{
+				position: fyne.NewPos(r.editor.measure(line.start, int(cursor))-1+r.editor.textLeft(), height*i+theme.Padding()),
+				height:   height,
+				ok:       true,
 			}
 		}
 	}
+	return caretPosition{}
+}
 
-	y := theme.Padding()
-	rowHeight := r.editor.charMinSize().Height
-	lineSize := fyne.NewSize(size.Width-theme.Padding()*2, rowHeight)
-	for _, t := range r.texts {
-		t.Resize(lineSize)
-		t.Move(fyne.NewPos(theme.Padding(), y))
-		y += rowHeight
+// highlightPositions measures the selected part of each of the given lines.
+// The caller must hold the lock of the editor.
+func (r *EditorRenderer) highlightPositions(selected []*selectedLine) []highlightPosition {
+	positions := make([]highlightPosition, len(selected))
+	for i, s := range selected {
+		line := r.editor.Lines[s.row]
+		start := r.editor.measure(line.start, line.start+s.start)
+		end := r.editor.measure(line.start, line.start+s.end)
+		if s.newline {
+			// Show the selected line break as a space
+			end += fyne.MeasureText(" ", r.editor.TextSize, r.editor.TextStyle).Width
+		}
+		positions[i] = highlightPosition{s.row, start, end}
+	}
+	return positions
+}
+
+// layoutLines positions each highlight over the selected part of its line.
+// The caller must hold the lock of the renderer.
+func (r *EditorRenderer) layoutLines(positions []highlightPosition, highlights []fyne.CanvasObject) {
+	for i, p := range positions {
+		highlights[i].Resize(fyne.NewSize(p.end-p.start, r.rowHeight))
+		highlights[i].Move(fyne.NewPos(p.start+r.textLeft, r.rowHeight*p.row+theme.Padding()))
 	}
 }
 
 func (r *EditorRenderer) MinSize() (size fyne.Size) {
-	charMinSize := r.editor.charMinSize()
-	for i := 0; i < fyne.Min(len(r.texts), len(r.editor.Lines)); i++ {
-		min := charMinSize
-		if r.texts[i].Text != "" {
-			min = r.texts[i].MinSize()
-		}
-		size.Height += min.Height
-		size.Width = fyne.Max(size.Width, min.Width)
+	r.lock.Lock()
+	size = r.minSize
+	r.lock.Unlock()
+	if size.Height == 0 {
+		// Not yet refreshed
+		r.editor.Lock()
+		size = r.measureMinSize()
+		r.editor.Unlock()
 	}
-	size.Width += theme.Padding() * 2
-	size.Height += theme.Padding() * 2
 	//log.Println("EditorRenderer.MinSize:", size)
 	return
 }
 
+// measureMinSize returns the size needed to show every row of the editor.
+// The caller must hold the lock of the editor.
+func (r *EditorRenderer) measureMinSize() (size fyne.Size) {
+	size.Width = r.editor.width + theme.Padding()*2 + r.editor.gutter
+	size.Height = r.editor.charMinSize().Height*len(r.editor.Lines) + theme.Padding()*2
+	return
+}
+
 func (r *EditorRenderer) Refresh() {
 	//log.Println("EditorRenderer.Refresh")
+	r.lock.Lock()
+	defer r.lock.Unlock()
 	r.editor.Lock()
+	var colors []color.Color
+	if r.editor.TextColors != nil {
+		colors = r.editor.TextColors()
+	}
+	first, last := r.editor.visibleRows(VIEWPORT_MARGIN)
+	r.editor.drawn = [2]int{first, last}
 	index := 0
-	for ; index < len(r.editor.Lines); index++ {
-		var textCanvas *canvas.Text
-		if index < len(r.texts) {
-			textCanvas = r.texts[index]
-		} else {
-			textCanvas = &canvas.Text{}
-			r.texts = append(r.texts, textCanvas)
-			r.objects = append(r.objects, textCanvas)
+	for row := first; row <= last; row++ {
+		line := r.editor.Lines[row]
+		styles, styled := r.editor.stylesAt(line.start)
+		for _, c := range styleRuns(colors, styles, styled, line.start, line.end) {
+			var textCanvas *canvas.Text
+			if index < len(r.texts) {
+				textCanvas = r.texts[index]
+			} else {
+				textCanvas = &canvas.Text{}
+				r.texts = append(r.texts, textCanvas)
+				r.runs = append(r.runs, &textRun{})
+			}
+			textCanvas.Text = string(r.editor.Buffer.Slice(c.start, c.end))
+			textCanvas.Color = c.color
+			if textCanvas.Color == nil && c.style != nil {
+				textCanvas.Color = c.style.Color
+			}
+			if textCanvas.Color == nil {
+				textCanvas.Color = r.editor.TextColor
+			}
+			textCanvas.TextStyle = r.editor.textStyle(c.style)
+			textCanvas.Show()
+			r.runs[index] = &textRun{
+				row:   row,
+				x:     r.editor.measure(line.start, c.start),
+				whole: c.start == line.start && c.end == line.end,
+			}
+			index++
 		}
-		line := r.editor.Lines[index]
-		textCanvas.Text = string(r.editor.Buffer[line.start:line.end])
-		log.Println("Text:", textCanvas.Text)
-		textCanvas.Show()
 	}
+	// Texts which are no longer drawn are dropped, so scrolling through a large document does not keep them all
+	r.texts = r.texts[:index]
+	r.runs = r.runs[:index]
+	r.numberRows = r.numberRows[:0]
+	r.numberValues = r.numberValues[:0]
+	r.currentRows = [2]int{-1, -1}
+	if r.editor.ShowLineNumbers {
+		current := r.editor.Buffer.LineOf(int(r.editor.Cursor))
+		number := -1
+		for row, line := range r.editor.Lines {
+			// Wrapped rows continue the line before, so are not numbered
+			if row == 0 || r.editor.Buffer.RuneAt(line.start-1) == '\n' {
+				number++
+				if row >= first && row <= last {
+					r.numberRows = append(r.numberRows, row)
+					r.numberValues = append(r.numberValues, number)
+				}
+			}
+			if number == current {
+				if r.currentRows[0] < 0 {
+					r.currentRows[0] = row
+				}
+				r.currentRows[1] = row
+			}
+		}
+	}
+	for len(r.numbers) < len(r.numberRows) {
+		number := canvas.NewText("", theme.TextColor())
+		number.Alignment = fyne.TextAlignTrailing
+		r.numbers = append(r.numbers, number)
+	}
+	r.numbers = r.numbers[:len(r.numberRows)]
+	for i, n := range r.numbers {
+		row := r.numberRows[i]
+		n.Text = strconv.Itoa(r.numberValues[i] + 1)
+		n.Color = theme.DisabledTextColor()
+		if row >= r.currentRows[0] && row <= r.currentRows[1] {
+			n.Color = theme.TextColor()
+		}
+		n.TextSize = r.editor.TextSize
+		n.TextStyle = r.editor.TextStyle
+		n.Show()
+	}
+	r.current.FillColor = theme.HoverColor()
+	r.current.Hidden = r.currentRows[0] < 0
+	r.selected = nil
+	for _, s := range r.editor.selectedLines() {
+		if s.row >= first && s.row <= last {
+			r.selected = append(r.selected, s)
+		}
+	}
+	r.carets = nil
+	if r.editor.Carets != nil {
+		r.carets = r.editor.Carets()
+	}
+	var caretColors []color.Color
+	r.caretSelected = nil
+	for _, c := range r.carets {
+		if c.IsSelecting {
+			// Translucent, so the text can still be read
+			nrgba := color.NRGBAModel.Convert(c.Color).(color.NRGBA)
+			nrgba.A /= 3
+			for _, s := range r.editor.linesBetween(c.Cursor, c.Selection) {
+				if s.row >= first && s.row <= last {
+					r.caretSelected = append(r.caretSelected, s)
+					caretColors = append(caretColors, nrgba)
+				}
+			}
+		}
+	}
+	var matchColors []color.Color
+	r.matchSelected = nil
+	if matches := r.editor.Matches; len(matches) > 0 && len(r.editor.Lines) > 0 {
+		// Only the matches on the rows drawn
+		low, high := r.editor.Lines[first].start, r.editor.Lines[last].end
+		for i := sort.Search(len(matches), func(i int) bool {
+			return matches[i].End >= low
+		}); i < len(matches) && matches[i].Start <= high; i++ {
+			c := MATCH_COLOR
+			if i == r.editor.CurrentMatch {
+				c = CURRENT_MATCH_COLOR
+			}
+			for _, s := range r.editor.linesBetween(uint64(matches[i].Start), uint64(matches[i].End)) {
+				if s.row >= first && s.row <= last {
+					r.matchSelected = append(r.matchSelected, s)
+					matchColors = append(matchColors, c)
+				}
+			}
+		}
+	}
+	r.tooltip.Text = r.editor.Tooltip
+	r.tooltipAt = r.editor.TooltipPosition
+	r.cursorAt = r.cursorPosition(r.editor.Cursor)
+	r.caretsAt = r.caretsAt[:0]
+	for _, c := range r.carets {
+		r.caretsAt = append(r.caretsAt, r.cursorPosition(c.Cursor))
+	}
+	r.selectedAt = r.highlightPositions(r.selected)
+	r.caretSelectAt = r.highlightPositions(r.caretSelected)
+	r.matchAt = r.highlightPositions(r.matchSelected)
+	r.rowHeight = r.editor.charMinSize().Height
+	r.gutter = r.editor.gutter
+	r.textLeft = r.editor.textLeft()
+	r.minSize = r.measureMinSize()
+	focused := r.editor.Focused()
+	textAlign, textSize, hidden := r.editor.TextAlign, r.editor.TextSize, r.editor.Hidden
+	size := r.editor.Size()
 	r.editor.Unlock()
 
-	for ; index < len(r.texts); index++ {
-		r.texts[index].Text = ""
+	for len(r.caretCursors) < len(r.carets) {
+		r.caretCursors = append(r.caretCursors, canvas.NewRectangle(theme.FocusColor()))
+		label := canvas.NewText("", theme.TextColor())
+		label.TextStyle = fyne.TextStyle{Bold: true}
+		r.caretLabels = append(r.caretLabels, label)
+	}
+	for i, cursor := range r.caretCursors {
+		label := r.caretLabels[i]
+		if i < len(r.carets) {
+			c := r.carets[i]
+			cursor.FillColor = c.Color
+			label.Text = c.Label
+			label.Color = c.Color
+			label.TextSize = textSize * 3 / 4
+			cursor.Show()
+			label.Show()
+		} else {
+			cursor.Hide()
+			label.Hide()
+		}
+	}
+	for len(r.caretSelection) < len(r.caretSelected) {
+		r.caretSelection = append(r.caretSelection, canvas.NewRectangle(theme.FocusColor()))
+	}
+	for i, s := range r.caretSelection {
+		if i < len(r.caretSelected) {
+			s.(*canvas.Rectangle).FillColor = caretColors[i]
+			s.Show()
+		} else {
+			s.Hide()
+		}
+	}
+
+	for len(r.matchHighlights) < len(r.matchSelected) {
+		r.matchHighlights = append(r.matchHighlights, canvas.NewRectangle(MATCH_COLOR))
+	}
+	// Dropped once no longer drawn, like texts
+	r.matchHighlights = r.matchHighlights[:len(r.matchSelected)]
+	for i, h := range r.matchHighlights {
+		h.(*canvas.Rectangle).FillColor = matchColors[i]
+		h.Show()
 	}
 
+	for len(r.selection) < len(r.selected) {
+		r.selection = append(r.selection, canvas.NewRectangle(theme.FocusColor()))
+	}
+	for i, s := range r.selection {
+		s.(*canvas.Rectangle).FillColor = theme.FocusColor()
+		if i < len(r.selected) {
+			s.Show()
+		} else {
+			s.Hide()
+		}
+	}
+	r.objects = append([]fyne.CanvasObject{r.current}, r.matchHighlights...)
+	r.objects = append(r.objects, r.selection...)
+	r.objects = append(r.objects, r.caretSelection...)
+	r.objects = append(r.objects, r.cursor)
+	for _, c := range r.caretCursors {
+		r.objects = append(r.objects, c)
+	}
 	for _, t := range r.texts {
-		t.Alignment = r.editor.TextAlign
-		t.Color = r.editor.TextColor
-		t.TextSize = r.editor.TextSize
-		t.TextStyle = r.editor.TextStyle
-		t.Hidden = r.editor.Hidden
+		r.objects = append(r.objects, t)
+	}
+	for _, n := range r.numbers {
+		r.objects = append(r.objects, n)
+	}
+	for _, l := range r.caretLabels {
+		r.objects = append(r.objects, l)
+	}
+	// Drawn last, so it is above the text
+	r.objects = append(r.objects, r.tooltipBackground, r.tooltip)
+	if r.tooltip.Text == "" {
+		r.tooltip.Hide()
+		r.tooltipBackground.Hide()
+	} else {
+		r.tooltip.Color = theme.TextColor()
+		r.tooltip.TextSize = textSize
+		r.tooltipBackground.FillColor = theme.ButtonColor()
+		r.tooltip.Show()
+		r.tooltipBackground.Show()
 	}
 
-	if r.editor.Focused() {
+	for _, t := range r.texts {
+		t.Alignment = textAlign
+		t.TextSize = textSize
+		t.Hidden = hidden
+	}
+
+	if focused {
 		r.cursor.Show()
 	} else {
 		r.cursor.Hide()
 	}
 
-	r.Layout(r.editor.Size())
+	r.layout(size)
 	log.Println("canvas.Refresh")
 	canvas.Refresh(r.editor)
 	for _, t := range r.texts {
@@ -489,3 +1683,9 @@ func (r *EditorRenderer) Destroy() {
 type Line struct {
 	start, end int
 }
+
+// selectedLine holds the selected columns of a line, and whether the selection continues past the end of the line.
+type selectedLine struct {
+	row, start, end int
+	newline         bool
+}
@@ -0,0 +1,276 @@
+/*
+ * Copyright 2020 Aletheia Ware LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package edit_test
+
+import (
+	"fyne.io/fyne"
+	"fyne.io/fyne/canvas"
+	"fyne.io/fyne/test"
+	"fyne.io/fyne/theme"
+	"fyne.io/fyne/widget"
+	"github.com/AletheiaWareLLC/labfynego/ui/edit"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// drawnTexts returns the texts drawn by the editor's renderer.
+func drawnTexts(e *edit.Editor) (texts []string) {
+	for _, o := range test.WidgetRenderer(e).Objects() {
+		if t, ok := o.(*canvas.Text); ok && t.Text != "" && t.Visible() {
+			texts = append(texts, t.Text)
+		}
+	}
+	return
+}
+
+func TestEditor_LineNumbers(t *testing.T) {
+	test.NewApp()
+	e := edit.NewEditor()
+	e.ShowLineNumbers = true
+	e.TextWrap = fyne.TextWrapOff
+	e.Resize(fyne.NewSize(200, 200))
+	e.SetText("First\nSecond\nThird")
+	rowHeight := fyne.MeasureText("M", e.TextSize, e.TextStyle).Height
+	row := func(r int) int {
+		return theme.Padding() + rowHeight*r + rowHeight/2
+	}
+	for name, tt := range map[string]struct {
+		position fyne.Position
+		want     string
+	}{
+		"First":  {fyne.NewPos(1, row(0)), "First\n"},
+		"Second": {fyne.NewPos(1, row(1)), "Second\n"},
+		// The last line has no line break
+		"Third": {fyne.NewPos(1, row(2)), "Third"},
+		// Text beside the gutter is not selected
+		"Text": {fyne.NewPos(150, row(1)), ""},
+	} {
+		t.Run(name, func(t *testing.T) {
+			e.IsSelecting = false
+			e.Tapped(&fyne.PointEvent{Position: tt.position})
+			if got := e.SelectedText(); got != tt.want {
+				t.Errorf("Incorrect selection; expected '%q', got '%q'", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestEditor_Virtualized(t *testing.T) {
+	test.NewApp()
+	lines := make([]string, 5000)
+	for i := range lines {
+		lines[i] = "Line " + strconv.Itoa(i)
+	}
+	e := edit.NewEditor()
+	scroll := widget.NewVScrollContainer(e)
+	e.Scroll = scroll
+	e.SetText(strings.Join(lines, "\n"))
+	scroll.Resize(fyne.NewSize(200, 200))
+	rowHeight := fyne.MeasureText("M", e.TextSize, e.TextStyle).Height
+	if want, got := rowHeight*len(lines)+theme.Padding()*2, e.MinSize().Height; got != want {
+		t.Errorf("Incorrect height; expected '%d', got '%d'", want, got)
+	}
+
+	texts := drawnTexts(e)
+	if max := 200/rowHeight + 2*edit.VIEWPORT_MARGIN; len(texts) > max {
+		t.Errorf("Too many texts drawn; expected at most '%d', got '%d'", max, len(texts))
+	}
+	if texts[0] != "Line 0" {
+		t.Errorf("Incorrect first text; expected 'Line 0', got '%s'", texts[0])
+	}
+
+	// Scrolling draws the rows scrolled into view
+	scroll.Offset.Y = rowHeight * 4000
+	scroll.Refresh()
+	texts = drawnTexts(e)
+	if want, got := "Line "+strconv.Itoa(4000-1-edit.VIEWPORT_MARGIN), texts[0]; got != want {
+		t.Errorf("Incorrect first text; expected '%s', got '%s'", want, got)
+	}
+}
+
+func TestEditor_ShrinkWithMatches(t *testing.T) {
+	test.NewApp()
+	lines := make([]string, 1000)
+	var matches []*edit.Match
+	offset := 0
+	for i := range lines {
+		lines[i] = "Line " + strconv.Itoa(i)
+		matches = append(matches, &edit.Match{Start: offset, End: offset + 4})
+		offset += len(lines[i]) + 1
+	}
+	e := edit.NewEditor()
+	scroll := widget.NewVScrollContainer(e)
+	e.Scroll = scroll
+	e.SetText(strings.Join(lines, "\n"))
+	scroll.Resize(fyne.NewSize(200, 200))
+	e.Matches = matches
+	rowHeight := fyne.MeasureText("M", e.TextSize, e.TextStyle).Height
+	scroll.Offset.Y = rowHeight * 900
+	scroll.Refresh()
+	// Replacing the text, as a Replace All or a remote delete would, leaves the scroll past the end of the rows
+	e.SetText("Line 0\nLine 1")
+	if want, got := []string{"Line 0", "Line 1"}, drawnTexts(e); !reflect.DeepEqual(got, want) {
+		t.Errorf("Incorrect texts; expected '%v', got '%v'", want, got)
+	}
+}
+
+func TestEditor_Wrap(t *testing.T) {
+	test.NewApp()
+	text := "The quick brown fox jumps over the lazy dog\nSecond line\n\nThe quick brown fox jumps over the lazy dog"
+	e := edit.NewEditor()
+	e.Resize(fyne.NewSize(150, 400))
+	e.SetText(text)
+	// Editing one line only wraps it again, so must give the same rows as wrapping everything
+	for _, changed := range []string{
+		strings.Replace(text, "Second", "2nd", 1),
+		strings.Replace(text, "lazy", "sleepy", 1),
+		text + "\nThird line",
+	} {
+		e.SetText(changed)
+		fresh := edit.NewEditor()
+		fresh.Resize(fyne.NewSize(150, 400))
+		fresh.SetText(changed)
+		if want, got := drawnTexts(fresh), drawnTexts(e); !reflect.DeepEqual(got, want) {
+			t.Errorf("Incorrect rows; expected '%q', got '%q'", want, got)
+		}
+	}
+	// Narrowing the editor wraps every line again
+	e.Resize(fyne.NewSize(100, 400))
+	fresh := edit.NewEditor()
+	fresh.Resize(fyne.NewSize(100, 400))
+	fresh.SetText(e.Buffer.String())
+	if want, got := drawnTexts(fresh), drawnTexts(e); !reflect.DeepEqual(got, want) {
+		t.Errorf("Incorrect rows; expected '%q', got '%q'", want, got)
+	}
+	if len(drawnTexts(e)) <= 6 {
+		t.Errorf("Expected lines to be wrapped, got '%q'", drawnTexts(e))
+	}
+}
+
+func TestEditor_DoubleTapped(t *testing.T) {
+	test.NewApp()
+	e := edit.NewEditor()
+	e.Resize(fyne.NewSize(150, 400))
+	e.SetText("The quick brown fox jumps over the lazy dog\nSecond line")
+	rowHeight := fyne.MeasureText("M", e.TextSize, e.TextStyle).Height
+	position := fyne.NewPos(theme.Padding()+fyne.MeasureText("The qu", e.TextSize, e.TextStyle).Width, theme.Padding()+rowHeight/2)
+	// Each further tap within the interval selects a larger unit of text
+	for i, want := range []string{
+		"quick",
+		"The quick brown",
+		"The quick brown fox jumps over the lazy dog",
+		"The quick brown fox jumps over the lazy dog",
+	} {
+		e.DoubleTapped(&fyne.PointEvent{Position: position})
+		if got := e.SelectedText(); got != want {
+			t.Errorf("Incorrect selection after %d taps; expected '%q', got '%q'", i+2, want, got)
+		}
+	}
+	// Taps after the interval start a new sequence
+	time.Sleep(edit.TAP_INTERVAL + 10*time.Millisecond)
+	e.DoubleTapped(&fyne.PointEvent{Position: position})
+	if want, got := "quick", e.SelectedText(); got != want {
+		t.Errorf("Incorrect selection; expected '%q', got '%q'", want, got)
+	}
+}
+
+func TestEditor_ContextMenu(t *testing.T) {
+	test.NewApp()
+	labels := func(menu *fyne.Menu) (labels []string) {
+		for _, i := range menu.Items {
+			if i.IsSeparator {
+				labels = append(labels, "-")
+			} else {
+				labels = append(labels, i.Label)
+			}
+		}
+		return
+	}
+	item := func(menu *fyne.Menu, label string) *fyne.MenuItem {
+		for _, i := range menu.Items {
+			if i.Label == label {
+				return i
+			}
+		}
+		t.Fatalf("Missing menu item '%s' in '%q'", label, labels(menu))
+		return nil
+	}
+	t.Run("Editable", func(t *testing.T) {
+		e := edit.NewEditor()
+		e.SetText("Hello World")
+		clipboard := test.NewClipboard()
+		menu := e.ContextMenu(clipboard)
+		if want, got := []string{"Cut", "Copy", "Paste", "Select All"}, labels(menu); !reflect.DeepEqual(got, want) {
+			t.Errorf("Incorrect items; expected '%q', got '%q'", want, got)
+		}
+		item(menu, "Select All").Action()
+		item(menu, "Cut").Action()
+		if want, got := "Hello World", clipboard.Content(); got != want {
+			t.Errorf("Incorrect clipboard; expected '%s', got '%s'", want, got)
+		}
+		if want, got := "", e.Buffer.String(); got != want {
+			t.Errorf("Incorrect text; expected '%s', got '%s'", want, got)
+		}
+		item(menu, "Paste").Action()
+		if want, got := "Hello World", e.Buffer.String(); got != want {
+			t.Errorf("Incorrect text; expected '%s', got '%s'", want, got)
+		}
+	})
+	t.Run("ReadOnly", func(t *testing.T) {
+		e := edit.NewEditor()
+		e.SetText("Hello World")
+		e.ReadOnly = true
+		clipboard := test.NewClipboard()
+		menu := e.ContextMenu(clipboard)
+		if want, got := []string{"Copy", "Select All"}, labels(menu); !reflect.DeepEqual(got, want) {
+			t.Errorf("Incorrect items; expected '%q', got '%q'", want, got)
+		}
+		item(menu, "Select All").Action()
+		item(menu, "Copy").Action()
+		if want, got := "Hello World", clipboard.Content(); got != want {
+			t.Errorf("Incorrect clipboard; expected '%s', got '%s'", want, got)
+		}
+	})
+	t.Run("Reveal", func(t *testing.T) {
+		e := edit.NewEditor()
+		revealed := 0
+		e.OnReveal = func() {
+			revealed++
+		}
+		// Without a clipboard only the items not needing one are shown
+		menu := e.ContextMenu(nil)
+		if want, got := []string{"Select All", "-", "Reveal in Tree"}, labels(menu); !reflect.DeepEqual(got, want) {
+			t.Errorf("Incorrect items; expected '%q', got '%q'", want, got)
+		}
+		item(menu, "Reveal in Tree").Action()
+		if revealed != 1 {
+			t.Errorf("Incorrect reveals; expected '1', got '%d'", revealed)
+		}
+	})
+	t.Run("TappedSecondary", func(t *testing.T) {
+		e := edit.NewEditor()
+		w := test.NewWindow(e)
+		defer w.Close()
+		e.TappedSecondary(&fyne.PointEvent{Position: fyne.NewPos(1, 1)})
+		if w.Canvas().Overlays().Top() == nil {
+			t.Error("Expected context menu to be shown")
+		}
+	})
+}
@@ -0,0 +1,395 @@
+/*
+ * Copyright 2020 Aletheia Ware LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package edit
+
+import (
+	"fmt"
+	"fyne.io/fyne"
+	"fyne.io/fyne/layout"
+	"fyne.io/fyne/widget"
+	"image/color"
+	"log"
+	"regexp"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	// Replacements separated by at most this many bytes are emitted as one delta, as each delta is a record with its own overhead
+	REPLACE_MERGE_GAP = 64
+)
+
+var (
+	// Color of the matches of a search
+	MATCH_COLOR = color.NRGBA{R: 0xff, G: 0xc1, B: 0x07, A: 0x50}
+	// Color of the current match of a search
+	CURRENT_MATCH_COLOR = color.NRGBA{R: 0xff, G: 0x98, B: 0x00, A: 0xa0}
+)
+
+// Query is what to find in a buffer.
+type Query struct {
+	Text      string
+	MatchCase bool
+	WholeWord bool // Matches must not start or end within a word
+	Regex     bool // Text is a regular expression, and replacements may refer to its groups, such as $1 or ${name}
+}
+
+// Match is an occurrence of a query in a buffer, from Start up to End in runes.
+type Match struct {
+	Start, End int
+	groups     []int // Offsets of the groups of the match in bytes, used to expand replacements
+}
+
+// compile returns the regular expression matching the query.
+func (q *Query) compile() (*regexp.Regexp, error) {
+	expression := q.Text
+	if !q.Regex {
+		expression = regexp.QuoteMeta(expression)
+	}
+	if !q.MatchCase {
+		expression = "(?i)" + expression
+	}
+	return regexp.Compile(expression)
+}
+
+// Find returns the matches of the query in the given buffer, in order, matches of no text are ignored.
+func (q *Query) Find(buffer []rune) ([]*Match, error) {
+	if q.Text == "" {
+		return nil, nil
+	}
+	re, err := q.compile()
+	if err != nil {
+		return nil, err
+	}
+	text := string(buffer)
+	var matches []*Match
+	// Matches are in order, so count the runes before each from the end of the last
+	runes, bytes := 0, 0
+	for _, groups := range re.FindAllStringSubmatchIndex(text, -1) {
+		if groups[0] == groups[1] {
+			continue
+		}
+		runes += utf8.RuneCountInString(text[bytes:groups[0]])
+		start := runes
+		runes += utf8.RuneCountInString(text[groups[0]:groups[1]])
+		bytes = groups[1]
+		if q.WholeWord && (!wordBoundary(buffer, start) || !wordBoundary(buffer, runes)) {
+			continue
+		}
+		matches = append(matches, &Match{
+			Start:  start,
+			End:    runes,
+			groups: groups,
+		})
+	}
+	return matches, nil
+}
+
+// Replace returns the spans, in bytes of the given buffer, replacing the given matches of the query with the replacement.
+// Only text which changes is included, and replacements close enough together are joined into one span, so they are emitted as few small deltas.
+func (q *Query) Replace(buffer []rune, matches []*Match, replacement string) ([]*Span, error) {
+	re, err := q.compile()
+	if err != nil {
+		return nil, err
+	}
+	text := string(buffer)
+	var spans []*Span
+	for _, m := range matches {
+		add := replacement
+		if q.Regex {
+			add = string(re.ExpandString(nil, replacement, text, m.groups))
+		}
+		start, end := m.groups[0], m.groups[1]
+		remove := text[start:end]
+		// Keep the runes the match and replacement start and end with
+		prefix := 0
+		for prefix < len(remove) && prefix < len(add) && remove[prefix] == add[prefix] {
+			prefix++
+		}
+		for prefix > 0 && prefix < len(remove) && !utf8.RuneStart(remove[prefix]) {
+			prefix--
+		}
+		suffix := 0
+		for suffix < len(remove)-prefix && suffix < len(add)-prefix && remove[len(remove)-1-suffix] == add[len(add)-1-suffix] {
+			suffix++
+		}
+		for suffix > 0 && !utf8.RuneStart(remove[len(remove)-suffix]) {
+			suffix--
+		}
+		start += prefix
+		end -= suffix
+		add = add[prefix : len(add)-suffix]
+		if start == end && add == "" {
+			// Already replaced
+			continue
+		}
+		if last := len(spans) - 1; last >= 0 && int(spans[last].Offset+spans[last].Remove)+REPLACE_MERGE_GAP >= start {
+			// Join with the span before, keeping the text between them
+			s := spans[last]
+			s.Add = append(append(s.Add, text[s.Offset+s.Remove:start]...), add...)
+			s.Remove = uint64(end) - s.Offset
+			continue
+		}
+		spans = append(spans, &Span{
+			Offset: uint64(start),
+			Remove: uint64(end - start),
+			Add:    []byte(add),
+		})
+	}
+	return spans, nil
+}
+
+// wordBoundary returns true if the given offset of the buffer is not within a word.
+func wordBoundary(buffer []rune, offset int) bool {
+	return offset <= 0 || offset >= len(buffer) || !isWordRune(buffer[offset-1]) || !isWordRune(buffer[offset])
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_'
+}
+
+// Find returns the matches of the given query in the buffer.
+func (e *DeltaEditor) Find(query *Query) ([]*Match, error) {
+	e.Lock()
+	defer e.Unlock()
+	return query.Find(e.Buffer.Runes())
+}
+
+// Replace replaces the given matches of the query with the replacement, emitting as few deltas as it can, which are undone together, and returns the number of deltas emitted.
+// The matches must have been found in the buffer as it is, so should be found again just before.
+func (e *DeltaEditor) Replace(query *Query, matches []*Match, replacement string) (int, error) {
+	if e.ReadOnly || len(matches) == 0 {
+		return 0, nil
+	}
+	// Emit pending edits first, so the spans are in bytes of the buffer every delta was applied to
+	e.Flush()
+	e.Lock()
+	spans, err := query.Replace(e.Buffer.Runes(), matches, replacement)
+	if err != nil {
+		e.Unlock()
+		return 0, err
+	}
+	deltas, inverse := e.revert(spans)
+	if len(deltas) > 0 {
+		e.undos = append(e.undos, &change{
+			deltas: deltas,
+			spans:  inverse,
+		})
+		// Undone deltas cannot be redone once another edit is made
+		e.redos = nil
+	}
+	e.Unlock()
+	e.emitAll(deltas)
+	return len(deltas), nil
+}
+
+// FindBar searches the buffer of a ChannelEditor as the query is typed, highlighting the matches, and replaces them.
+type FindBar struct {
+	Editor      *ChannelEditor
+	Query       *widget.Entry
+	Replacement *widget.Entry
+	MatchCase   *widget.Check
+	WholeWord   *widget.Check
+	Regex       *widget.Check
+	Status      *widget.Label
+	Matches     []*Match
+	Current     int // Index of the current match, or -1 if there is none
+
+	// OnClose, if set, is called when the close button is tapped
+	OnClose func()
+}
+
+func NewFindBar(editor *ChannelEditor) *FindBar {
+	f := &FindBar{
+		Editor:      editor,
+		Query:       widget.NewEntry(),
+		Replacement: widget.NewEntry(),
+		Status:      widget.NewLabel(""),
+		Current:     -1,
+	}
+	f.Query.SetPlaceHolder("Find")
+	f.Replacement.SetPlaceHolder("Replace")
+	f.Query.OnChanged = func(string) {
+		f.Search()
+	}
+	f.MatchCase = widget.NewCheck("Case", func(bool) {
+		f.Search()
+	})
+	f.WholeWord = widget.NewCheck("Word", func(bool) {
+		f.Search()
+	})
+	f.Regex = widget.NewCheck("Regex", func(bool) {
+		f.Search()
+	})
+	return f
+}
+
+// query returns the query entered.
+func (f *FindBar) query() *Query {
+	return &Query{
+		Text:      f.Query.Text,
+		MatchCase: f.MatchCase.Checked,
+		WholeWord: f.WholeWord.Checked,
+		Regex:     f.Regex.Checked,
+	}
+}
+
+// Update finds the matches of the query again, such as after the buffer changes, making the first match at or after the cursor current.
+func (f *FindBar) Update() {
+	matches, err := f.Editor.Find(f.query())
+	if err != nil {
+		log.Println(err)
+		f.Status.SetText(err.Error())
+	}
+	f.Editor.Lock()
+	cursor := int(f.Editor.Cursor)
+	if f.Editor.IsSelecting && int(f.Editor.Selection) < cursor {
+		cursor = int(f.Editor.Selection)
+	}
+	f.Matches = matches
+	f.Current = -1
+	for i, m := range matches {
+		if m.End > cursor {
+			f.Current = i
+			break
+		}
+	}
+	if f.Current < 0 && len(matches) > 0 {
+		// Wrap around to the start
+		f.Current = 0
+	}
+	f.Editor.Matches = matches
+	f.Editor.CurrentMatch = f.Current
+	f.Editor.Unlock()
+	f.Editor.Refresh()
+	if err == nil {
+		f.updateStatus()
+	}
+}
+
+// Search finds the matches of the query, and selects the first at or after the cursor.
+func (f *FindBar) Search() {
+	f.Update()
+	f.selectMatch(f.Current)
+}
+
+// Next selects the match after the current one, wrapping around to the first.
+func (f *FindBar) Next() {
+	if len(f.Matches) == 0 {
+		return
+	}
+	f.selectMatch((f.Current + 1) % len(f.Matches))
+}
+
+// Previous selects the match before the current one, wrapping around to the last.
+func (f *FindBar) Previous() {
+	if len(f.Matches) == 0 {
+		return
+	}
+	f.selectMatch((f.Current + len(f.Matches) - 1) % len(f.Matches))
+}
+
+// Replace replaces the current match, and selects the next.
+func (f *FindBar) Replace() {
+	// Find again, as the buffer may have changed since
+	f.Update()
+	if f.Current < 0 {
+		return
+	}
+	if _, err := f.Editor.Replace(f.query(), f.Matches[f.Current:f.Current+1], f.Replacement.Text); err != nil {
+		log.Println(err)
+		f.Status.SetText(err.Error())
+		return
+	}
+	f.Search()
+}
+
+// ReplaceAll replaces every match.
+func (f *FindBar) ReplaceAll() {
+	f.Update()
+	count := len(f.Matches)
+	if _, err := f.Editor.Replace(f.query(), f.Matches, f.Replacement.Text); err != nil {
+		log.Println(err)
+		f.Status.SetText(err.Error())
+		return
+	}
+	f.Update()
+	f.Status.SetText(fmt.Sprintf("Replaced %d", count))
+}
+
+// Close stops highlighting the matches, and calls OnClose.
+func (f *FindBar) Close() {
+	f.Matches = nil
+	f.Current = -1
+	f.Editor.Lock()
+	f.Editor.Matches = nil
+	f.Editor.Unlock()
+	f.Editor.Refresh()
+	if f.OnClose != nil {
+		f.OnClose()
+	}
+}
+
+// selectMatch selects the match with the given index, and scrolls the editor to show it.
+func (f *FindBar) selectMatch(index int) {
+	if index < 0 || index >= len(f.Matches) {
+		return
+	}
+	m := f.Matches[index]
+	f.Current = index
+	f.Editor.Lock()
+	f.Editor.Selection = uint64(m.Start)
+	f.Editor.Cursor = uint64(m.End)
+	f.Editor.IsSelecting = true
+	f.Editor.CurrentMatch = index
+	f.Editor.Unlock()
+	f.Editor.Refresh()
+	f.Editor.ScrollToCursor()
+	f.updateStatus()
+}
+
+// updateStatus shows which match is current, and how many there are.
+func (f *FindBar) updateStatus() {
+	switch {
+	case f.Query.Text == "":
+		f.Status.SetText("")
+	case len(f.Matches) == 0:
+		f.Status.SetText("No matches")
+	default:
+		f.Status.SetText(fmt.Sprintf("%d of %d", f.Current+1, len(f.Matches)))
+	}
+}
+
+func (f *FindBar) CanvasObject() fyne.CanvasObject {
+	find := widget.NewHBox(
+		f.Status,
+		widget.NewButton("Previous", f.Previous),
+		widget.NewButton("Next", f.Next),
+		f.MatchCase,
+		f.WholeWord,
+		f.Regex,
+		widget.NewButton("Close", f.Close),
+	)
+	replace := widget.NewHBox(
+		widget.NewButton("Replace", f.Replace),
+		widget.NewButton("Replace All", f.ReplaceAll),
+	)
+	return widget.NewVBox(
+		fyne.NewContainerWithLayout(layout.NewBorderLayout(nil, nil, nil, find), find, f.Query),
+		fyne.NewContainerWithLayout(layout.NewBorderLayout(nil, nil, nil, replace), replace, f.Replacement),
+	)
+}
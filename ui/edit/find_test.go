@@ -0,0 +1,242 @@
+/*
+ * Copyright 2020 Aletheia Ware LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package edit_test
+
+import (
+	"fyne.io/fyne/test"
+	"github.com/AletheiaWareLLC/labfynego/ui/edit"
+	"github.com/AletheiaWareLLC/labgo"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestQuery_Find(t *testing.T) {
+	for name, tt := range map[string]struct {
+		query *edit.Query
+		text  string
+		want  [][2]int
+		err   bool
+	}{
+		"empty": {
+			query: &edit.Query{},
+			text:  "Hello",
+		},
+		"ignore_case": {
+			query: &edit.Query{Text: "hello"},
+			text:  "Hello hello HELLO",
+			want:  [][2]int{{0, 5}, {6, 11}, {12, 17}},
+		},
+		"match_case": {
+			query: &edit.Query{Text: "hello", MatchCase: true},
+			text:  "Hello hello HELLO",
+			want:  [][2]int{{6, 11}},
+		},
+		"whole_word": {
+			query: &edit.Query{Text: "cat", WholeWord: true},
+			text:  "cat concat cats cat_ (cat)",
+			want:  [][2]int{{0, 3}, {22, 25}},
+		},
+		"literal": {
+			query: &edit.Query{Text: "a.b"},
+			text:  "axb a.b",
+			want:  [][2]int{{4, 7}},
+		},
+		"regex": {
+			query: &edit.Query{Text: `\d+`, Regex: true},
+			text:  "a1 b22 c333",
+			want:  [][2]int{{1, 2}, {4, 6}, {8, 11}},
+		},
+		"regex_empty_matches": {
+			query: &edit.Query{Text: `x*`, Regex: true},
+			text:  "axxb",
+			want:  [][2]int{{1, 3}},
+		},
+		"regex_invalid": {
+			query: &edit.Query{Text: `(`, Regex: true},
+			text:  "(",
+			err:   true,
+		},
+		"runes": {
+			query: &edit.Query{Text: "界"},
+			text:  "世界, 世界",
+			want:  [][2]int{{1, 2}, {5, 6}},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			matches, err := tt.query.Find([]rune(tt.text))
+			if tt.err {
+				if err == nil {
+					t.Fatal("Expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got [][2]int
+			for _, m := range matches {
+				got = append(got, [2]int{m.Start, m.End})
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Incorrect matches; expected '%v', got '%v'", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestQuery_Replace(t *testing.T) {
+	for name, tt := range map[string]struct {
+		query       *edit.Query
+		text        string
+		replacement string
+		want        []*edit.Span
+	}{
+		"changed_text_only": {
+			query:       &edit.Query{Text: "colour"},
+			text:        "The colour",
+			replacement: "color",
+			want:        []*edit.Span{&edit.Span{Offset: 8, Remove: 1, Add: []byte{}}},
+		},
+		"unchanged": {
+			query:       &edit.Query{Text: "hello"},
+			text:        "hello Hello",
+			replacement: "hello",
+			want:        []*edit.Span{&edit.Span{Offset: 6, Remove: 1, Add: []byte("h")}},
+		},
+		"joined": {
+			query:       &edit.Query{Text: "a", MatchCase: true},
+			text:        "a, a or a",
+			replacement: "b",
+			want:        []*edit.Span{&edit.Span{Offset: 0, Remove: 9, Add: []byte("b, b or b")}},
+		},
+		"separate": {
+			query:       &edit.Query{Text: "a", MatchCase: true},
+			text:        "a" + strings.Repeat(" ", edit.REPLACE_MERGE_GAP+1) + "a",
+			replacement: "b",
+			want: []*edit.Span{
+				&edit.Span{Offset: 0, Remove: 1, Add: []byte("b")},
+				&edit.Span{Offset: edit.REPLACE_MERGE_GAP + 2, Remove: 1, Add: []byte("b")},
+			},
+		},
+		"runes": {
+			query:       &edit.Query{Text: "世界"},
+			text:        "¡世界!",
+			replacement: "世間",
+			want:        []*edit.Span{&edit.Span{Offset: 5, Remove: 3, Add: []byte("間")}},
+		},
+		"groups": {
+			query:       &edit.Query{Text: `(\w+)@(\w+)`, Regex: true},
+			text:        "alice@example",
+			replacement: "$2@$1",
+			want:        []*edit.Span{&edit.Span{Offset: 0, Remove: 12, Add: []byte("example@alic")}},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			buffer := []rune(tt.text)
+			matches, err := tt.query.Find(buffer)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := tt.query.Replace(buffer, matches, tt.replacement)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Incorrect spans; expected '%v', got '%v'", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestDeltaEditor_Replace(t *testing.T) {
+	test.NewApp()
+	text := "Grüße, Welt! Grüße" + strings.Repeat(".", edit.REPLACE_MERGE_GAP+1) + "grüße"
+	buffer := []byte(text)
+	var deltas []*labgo.Delta
+	e := edit.NewDeltaEditor(func(parent string, delta *labgo.Delta) {
+		deltas = append(deltas, delta)
+		buffer = labgo.DeltaToBuffer(delta, buffer)
+	})
+	e.SetText(text)
+	// Pending edits are emitted first
+	e.Cursor = 12
+	e.TypedRune('!')
+	query := &edit.Query{Text: "grüße"}
+	matches, err := e.Find(query)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := len(matches); got != 3 {
+		t.Fatalf("Incorrect matches; expected '%d', got '%d'", 3, got)
+	}
+	count, err := e.Replace(query, matches, "Hallo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The first two are close enough to share a delta, which follows the pending delta
+	if count != 2 || len(deltas) != 3 {
+		t.Errorf("Incorrect deltas; expected '%d' and '%d', got '%d' and '%d'", 2, 3, count, len(deltas))
+	}
+	want := "Hallo, Welt!! Hallo" + strings.Repeat(".", edit.REPLACE_MERGE_GAP+1) + "Hallo"
+	if got := e.Buffer.String(); got != want {
+		t.Errorf("Incorrect editor buffer; expected '%s', got '%s'", want, got)
+	}
+	if got := string(buffer); got != want {
+		t.Errorf("Incorrect delta buffer; expected '%s', got '%s'", want, got)
+	}
+	// Every replacement is undone at once
+	e.Undo()
+	want = "Grüße, Welt!! Grüße" + strings.Repeat(".", edit.REPLACE_MERGE_GAP+1) + "grüße"
+	if got := e.Buffer.String(); got != want {
+		t.Errorf("Incorrect editor buffer; expected '%s', got '%s'", want, got)
+	}
+	if got := string(buffer); got != want {
+		t.Errorf("Incorrect delta buffer; expected '%s', got '%s'", want, got)
+	}
+}
+
+func TestFindBar(t *testing.T) {
+	test.NewApp()
+	e := edit.NewChannelEditor(makeNode(t, "alice"), nil, nil, nil)
+	e.SetText("one two one two one")
+	f := edit.NewFindBar(e)
+	f.Query.SetText("one")
+	if got := len(f.Matches); got != 3 {
+		t.Fatalf("Incorrect matches; expected '%d', got '%d'", 3, got)
+	}
+	if got := f.Status.Text; got != "1 of 3" {
+		t.Errorf("Incorrect status; expected '%s', got '%s'", "1 of 3", got)
+	}
+	f.Next()
+	f.Next()
+	if got := e.SelectedText(); got != "one" || e.Selection != 16 {
+		t.Errorf("Incorrect selection; expected '%s' at '%d', got '%s' at '%d'", "one", 16, got, e.Selection)
+	}
+	f.Next()
+	if got := f.Status.Text; got != "1 of 3" {
+		t.Errorf("Incorrect status; expected '%s', got '%s'", "1 of 3", got)
+	}
+	f.Previous()
+	if got := f.Status.Text; got != "3 of 3" {
+		t.Errorf("Incorrect status; expected '%s', got '%s'", "3 of 3", got)
+	}
+	f.Close()
+	if e.Matches != nil {
+		t.Errorf("Matches still highlighted")
+	}
+}
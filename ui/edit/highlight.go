@@ -0,0 +1,233 @@
+/*
+ * Copyright 2020 Aletheia Ware LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package edit
+
+import (
+	"fyne.io/fyne"
+	"image/color"
+	"path/filepath"
+	"strings"
+)
+
+// Style is how a run of text is drawn, a nil Color is drawn in the editor's TextColor, and TextStyle is added to the editor's.
+type Style struct {
+	Color     color.Color
+	TextStyle fyne.TextStyle
+}
+
+// TokenKind is what a run of text is, such as a keyword or a comment.
+type TokenKind int
+
+const (
+	TOKEN_TEXT TokenKind = iota
+	TOKEN_KEYWORD
+	TOKEN_TYPE
+	TOKEN_LITERAL
+	TOKEN_STRING
+	TOKEN_NUMBER
+	TOKEN_COMMENT
+	TOKEN_KEY
+	TOKEN_HEADING
+	TOKEN_EMPHASIS
+	TOKEN_STRONG
+	TOKEN_CODE
+	TOKEN_LINK
+)
+
+// TOKEN_STYLES are the styles each kind of token is drawn in, kinds without one are drawn as plain text.
+var TOKEN_STYLES = map[TokenKind]*Style{
+	TOKEN_KEYWORD:  {Color: color.NRGBA{R: 0xaf, G: 0x52, B: 0xde, A: 0xff}, TextStyle: fyne.TextStyle{Bold: true}},
+	TOKEN_TYPE:     {Color: color.NRGBA{R: 0x00, G: 0x97, B: 0xa7, A: 0xff}},
+	TOKEN_LITERAL:  {Color: color.NRGBA{R: 0xe4, G: 0x56, B: 0x49, A: 0xff}},
+	TOKEN_STRING:   {Color: color.NRGBA{R: 0x50, G: 0xa1, B: 0x4f, A: 0xff}},
+	TOKEN_NUMBER:   {Color: color.NRGBA{R: 0xd1, G: 0x7a, B: 0x00, A: 0xff}},
+	TOKEN_COMMENT:  {Color: color.NRGBA{R: 0x80, G: 0x80, B: 0x80, A: 0xff}, TextStyle: fyne.TextStyle{Italic: true}},
+	TOKEN_KEY:      {Color: color.NRGBA{R: 0x40, G: 0x78, B: 0xf2, A: 0xff}},
+	TOKEN_HEADING:  {Color: color.NRGBA{R: 0x40, G: 0x78, B: 0xf2, A: 0xff}, TextStyle: fyne.TextStyle{Bold: true}},
+	TOKEN_EMPHASIS: {TextStyle: fyne.TextStyle{Italic: true}},
+	TOKEN_STRONG:   {TextStyle: fyne.TextStyle{Bold: true}},
+	TOKEN_CODE:     {Color: color.NRGBA{R: 0x50, G: 0xa1, B: 0x4f, A: 0xff}, TextStyle: fyne.TextStyle{Monospace: true}},
+	TOKEN_LINK:     {Color: color.NRGBA{R: 0x40, G: 0x78, B: 0xf2, A: 0xff}},
+}
+
+// Token is a run of a line, from Start up to End in runes, of the given kind.
+type Token struct {
+	Start, End int
+	Kind       TokenKind
+}
+
+// Lexer splits lines of a language into tokens.
+type Lexer interface {
+	// Lex returns the tokens of the given line, which does not include the line break, and the state of the lexer at its end, given the state at its start.
+	// Lines are lexed in order, with the first in state 0, so constructs spanning lines, such as block comments, are carried by the state.
+	Lex(line []rune, state int) ([]*Token, int)
+}
+
+// LEXERS are the lexers used to highlight files, by the extension of their name.
+var LEXERS = map[string]Lexer{
+	".go":       GO_LEXER,
+	".json":     JSON_LEXER,
+	".markdown": MARKDOWN_LEXER,
+	".md":       MARKDOWN_LEXER,
+	".proto":    PROTO_LEXER,
+	".py":       PYTHON_LEXER,
+}
+
+// LexerForPath returns the lexer for the file at the given path, or nil if there is none for its extension.
+func LexerForPath(path ...string) Lexer {
+	if len(path) == 0 {
+		return nil
+	}
+	return LEXERS[strings.ToLower(filepath.Ext(path[len(path)-1]))]
+}
+
+// highlightedLine holds the tokens of a line, the states of the lexer before and after it, and the styles of its runes once asked for.
+type highlightedLine struct {
+	length int
+	state  int
+	tokens []*Token
+	end    int
+	styles []*Style
+}
+
+// Highlighter styles a buffer with a lexer, keeping the tokens of each line so only lines which change are lexed again.
+type Highlighter struct {
+	Lexer  Lexer
+	Styles map[TokenKind]*Style
+	buffer *Rope // Copy of the buffer as last lexed
+	lines  []*highlightedLine
+}
+
+func NewHighlighter(lexer Lexer) *Highlighter {
+	return &Highlighter{
+		Lexer:  lexer,
+		Styles: TOKEN_STYLES,
+	}
+}
+
+// Update lexes the lines of the given buffer from the first which changed since the last update, until the state of the lexer matches that of an unchanged line, and returns the number of lines lexed.
+// The changed runes are found by the nodes the buffer shares with the copy kept by the last update, so an edit is found without reading the whole buffer.
+func (h *Highlighter) Update(buffer *Rope) int {
+	old := h.buffer
+	if old != nil && old.node() == buffer.node() {
+		return 0
+	}
+	h.buffer = buffer.Copy()
+	var first, end, shift int
+	if old == nil {
+		h.lines = nil
+	} else {
+		prefix := buffer.CommonPrefix(old)
+		if prefix == buffer.Len() && prefix == old.Len() {
+			return 0
+		}
+		suffix := buffer.CommonSuffix(old)
+		// The suffix may overlap the prefix, such as when a rune is inserted beside another the same
+		limit := buffer.Len() - prefix
+		if l := old.Len() - prefix; l < limit {
+			limit = l
+		}
+		if suffix > limit {
+			suffix = limit
+		}
+		first = buffer.LineOf(prefix)
+		// Runes from end are unchanged, though moved by shift
+		end = buffer.Len() - suffix
+		shift = old.Len() - buffer.Len()
+	}
+	state := 0
+	if first > 0 {
+		state = h.lines[first-1].end
+	}
+	// Lines from resume on are kept, after the lines lexed
+	var lexed []*highlightedLine
+	resume := len(h.lines)
+	for i := first; i < buffer.Lines(); i++ {
+		start := buffer.LineStart(i)
+		if old != nil && start >= end {
+			// The line is unchanged if it started a line before, and when it starts in the same state it lexes the same, as do the lines after it
+			if moved := start + shift; moved == 0 || old.RuneAt(moved-1) == '\n' {
+				if o := old.LineOf(moved); h.lines[o].state == state {
+					resume = o
+					break
+				}
+			}
+		}
+		runes := buffer.Slice(start, buffer.LineEnd(i))
+		tokens, next := h.Lexer.Lex(runes, state)
+		lexed = append(lexed, &highlightedLine{
+			length: len(runes),
+			state:  state,
+			tokens: tokens,
+			end:    next,
+		})
+		state = next
+	}
+	h.lines = splice(h.lines, first, resume, lexed)
+	return len(lexed)
+}
+
+// splice replaces the lines from start up to end with the given lines, moving those after them in place unless there are more lines than fit.
+func splice(lines []*highlightedLine, start, end int, replacement []*highlightedLine) []*highlightedLine {
+	length := len(lines) - (end - start) + len(replacement)
+	if length > cap(lines) {
+		spliced := make([]*highlightedLine, length, length+length/4)
+		copy(spliced, lines[:start])
+		copy(spliced[start:], replacement)
+		copy(spliced[start+len(replacement):], lines[end:])
+		return spliced
+	}
+	previous := len(lines)
+	lines = lines[:length]
+	copy(lines[start+len(replacement):], lines[end:previous])
+	copy(lines[start:], replacement)
+	// Cleared, so lines removed are not kept
+	for i := length; i < previous; i++ {
+		lines[:previous][i] = nil
+	}
+	return lines
+}
+
+// Tokens returns the tokens of each line of the given buffer.
+func (h *Highlighter) Tokens(buffer *Rope) [][]*Token {
+	h.Update(buffer)
+	tokens := make([][]*Token, len(h.lines))
+	for i, l := range h.lines {
+		tokens[i] = l.tokens
+	}
+	return tokens
+}
+
+// LineStyles returns the style of each rune of the given line of the buffer, runes which are not in a token with a style have none.
+// The styles of a line are made when first asked for, and kept until the line is lexed again, so they are not changed once returned.
+func (h *Highlighter) LineStyles(buffer *Rope, line int) []*Style {
+	h.Update(buffer)
+	if line < 0 || line >= len(h.lines) {
+		return nil
+	}
+	l := h.lines[line]
+	if l.styles == nil && len(l.tokens) > 0 {
+		l.styles = make([]*Style, l.length)
+		for _, t := range l.tokens {
+			style := h.Styles[t.Kind]
+			for i := t.Start; i < t.End; i++ {
+				l.styles[i] = style
+			}
+		}
+	}
+	return l.styles
+}
@@ -0,0 +1,195 @@
+/*
+ * Copyright 2020 Aletheia Ware LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package edit_test
+
+import (
+	"github.com/AletheiaWareLLC/labfynego/ui/edit"
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// tokenText returns the text of each token of each line, prefixed by the initial of its kind.
+func tokenText(buffer string, tokens [][]*edit.Token) []string {
+	kinds := map[edit.TokenKind]string{
+		edit.TOKEN_KEYWORD:  "k",
+		edit.TOKEN_TYPE:     "t",
+		edit.TOKEN_LITERAL:  "l",
+		edit.TOKEN_STRING:   "s",
+		edit.TOKEN_NUMBER:   "n",
+		edit.TOKEN_COMMENT:  "c",
+		edit.TOKEN_KEY:      "y",
+		edit.TOKEN_HEADING:  "h",
+		edit.TOKEN_EMPHASIS: "e",
+		edit.TOKEN_STRONG:   "b",
+		edit.TOKEN_CODE:     "m",
+		edit.TOKEN_LINK:     "a",
+	}
+	var texts []string
+	for i, line := range strings.Split(buffer, "\n") {
+		runes := []rune(line)
+		for _, t := range tokens[i] {
+			texts = append(texts, kinds[t.Kind]+":"+string(runes[t.Start:t.End]))
+		}
+	}
+	return texts
+}
+
+func TestLexerForPath(t *testing.T) {
+	for name, tt := range map[string]struct {
+		path []string
+		want edit.Lexer
+	}{
+		"Go":       {[]string{"src", "main.go"}, edit.GO_LEXER},
+		"Python":   {[]string{"analysis.py"}, edit.PYTHON_LEXER},
+		"Markdown": {[]string{"README.MD"}, edit.MARKDOWN_LEXER},
+		"JSON":     {[]string{"data.json"}, edit.JSON_LEXER},
+		"Proto":    {[]string{"lab.proto"}, edit.PROTO_LEXER},
+		"Unknown":  {[]string{"notes.txt"}, nil},
+		"Empty":    {nil, nil},
+	} {
+		t.Run(name, func(t *testing.T) {
+			if got := edit.LexerForPath(tt.path...); got != tt.want {
+				t.Errorf("Incorrect lexer; expected '%v', got '%v'", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestLexer(t *testing.T) {
+	for name, tt := range map[string]struct {
+		lexer  edit.Lexer
+		buffer string
+		want   []string
+	}{
+		"Go": {
+			edit.GO_LEXER,
+			"func f(s string) error {\n\treturn nil // None\n}",
+			[]string{"k:func", "t:string", "t:error", "k:return", "l:nil", "c:// None"},
+		},
+		"GoBlockComment": {
+			edit.GO_LEXER,
+			"x := 0x1F /* start\nmiddle\nend */ \"a\\\"b\"",
+			[]string{"n:0x1F", "c:/* start", "c:middle", "c:end */", "s:\"a\\\"b\""},
+		},
+		"GoRawString": {
+			edit.GO_LEXER,
+			"s := `if\nfor` + '世'",
+			[]string{"s:`if", "s:for`", "s:'世'"},
+		},
+		"Python": {
+			edit.PYTHON_LEXER,
+			"def f(x):\n    \"\"\"Doc\n    # not a comment\"\"\"\n    return None # Done",
+			[]string{"k:def", "s:\"\"\"Doc", "s:    # not a comment\"\"\"", "k:return", "l:None", "c:# Done"},
+		},
+		"JSON": {
+			edit.JSON_LEXER,
+			"{\"a\": [1.5, true, \"b\"], \"c\" : null}",
+			[]string{"y:\"a\"", "n:1.5", "l:true", "s:\"b\"", "y:\"c\"", "l:null"},
+		},
+		"Proto": {
+			edit.PROTO_LEXER,
+			"message Path {\n    repeated string path = 1;\n}",
+			[]string{"k:message", "k:repeated", "t:string", "n:1"},
+		},
+		"Markdown": {
+			edit.MARKDOWN_LEXER,
+			"# Title\n- *a* **b** `c` snake_case_name\n> Quote\n```\n# Not a title\n```\n[link](http://example.com)",
+			[]string{"h:# Title", "k:- ", "e:*a*", "b:**b**", "m:`c`", "c:> Quote", "m:```", "m:# Not a title", "m:```", "a:[link](http://example.com)"},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			h := edit.NewHighlighter(tt.lexer)
+			got := tokenText(tt.buffer, h.Tokens(edit.NewRope(tt.buffer)))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Incorrect tokens; expected '%q', got '%q'", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestHighlighter_Update(t *testing.T) {
+	lines := make([]string, 100)
+	for i := range lines {
+		lines[i] = "x := 1"
+	}
+	h := edit.NewHighlighter(edit.GO_LEXER)
+	for _, tt := range []struct {
+		name string
+		edit func()
+		want int
+	}{
+		{"Initial", func() {}, 100},
+		{"Unchanged", func() {}, 0},
+		{"EditLine", func() { lines[50] = "y := 2" }, 1},
+		{"InsertLine", func() { lines = append(lines[:10], append([]string{"var z int"}, lines[10:]...)...) }, 1},
+		{"DeleteLine", func() { lines = append(lines[:10], lines[11:]...) }, 0},
+		// Opening a block comment changes the state of every line after it
+		{"OpenComment", func() { lines[90] = "/*" }, 10},
+		{"CloseComment", func() { lines[95] = "*/" }, 5},
+		{"RemoveComment", func() { lines[90] = "x := 1" }, 6},
+	} {
+		tt.edit()
+		if got := h.Update(edit.NewRope(strings.Join(lines, "\n"))); got != tt.want {
+			t.Errorf("%s: Incorrect lines lexed; expected '%d', got '%d'", tt.name, tt.want, got)
+		}
+	}
+	buffer := edit.NewRope(strings.Join(lines, "\n"))
+	last := buffer.Lines() - 1
+	styles := h.LineStyles(buffer, last)
+	if want := buffer.Len() - buffer.LineStart(last); len(styles) != want {
+		t.Fatalf("Incorrect number of styles; expected '%d', got '%d'", want, len(styles))
+	}
+	// The number of the last line
+	if got, want := styles[len(styles)-1], edit.TOKEN_STYLES[edit.TOKEN_NUMBER]; got != want {
+		t.Errorf("Incorrect style; expected '%v', got '%v'", want, got)
+	}
+}
+
+func TestHighlighter_Edit(t *testing.T) {
+	random := rand.New(rand.NewSource(1))
+	alphabet := []rune("ab1 \n\"/*")
+	buffer := edit.NewRope(strings.Repeat("x := \"a\" /* b */ 1\n", 1000))
+	h := edit.NewHighlighter(edit.GO_LEXER)
+	h.Update(buffer)
+	for i := 0; i < 200; i++ {
+		offset := random.Intn(buffer.Len() + 1)
+		if random.Intn(2) > 0 {
+			buffer.Insert(offset, []rune{alphabet[random.Intn(len(alphabet))]})
+		} else {
+			buffer.Remove(offset, offset+random.Intn(20))
+		}
+		// Lexed from the edit, the tokens are those of the whole buffer lexed again
+		want := edit.NewHighlighter(edit.GO_LEXER).Tokens(buffer.Copy())
+		if got := h.Tokens(buffer); !reflect.DeepEqual(got, want) {
+			t.Fatalf("Incorrect tokens after %d edits", i+1)
+		}
+	}
+}
+
+func BenchmarkHighlighter_Update(b *testing.B) {
+	buffer := edit.NewRope(benchmarkText(10000))
+	h := edit.NewHighlighter(edit.GO_LEXER)
+	h.Update(buffer)
+	random := rand.New(rand.NewSource(1))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		buffer.Insert(random.Intn(buffer.Len()+1), []rune{'x'})
+		h.Update(buffer)
+	}
+}
@@ -0,0 +1,186 @@
+/*
+ * Copyright 2020 Aletheia Ware LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package edit
+
+import (
+	"fmt"
+	"fyne.io/fyne"
+	"fyne.io/fyne/widget"
+	"github.com/AletheiaWareLLC/bcgo"
+	"github.com/AletheiaWareLLC/labgo"
+	"log"
+	"strconv"
+	"strings"
+)
+
+const (
+	// Maximum number of runes of added or removed text shown in a preview
+	PREVIEW_LENGTH = 32
+)
+
+// HistoryEntry describes a delta written to a file channel.
+type HistoryEntry struct {
+	Id        string
+	Creator   string
+	Timestamp uint64
+	Delta     *labgo.Delta
+}
+
+// History lists the deltas of a ChannelEditor, and shows the file as it was once the selected delta was applied.
+type History struct {
+	Editor   *ChannelEditor
+	Box      *widget.Box
+	Scroll   *widget.ScrollContainer
+	Buttons  map[string]*widget.Button
+	Preview  *Editor
+	Selected string
+}
+
+func NewHistory(editor *ChannelEditor) *History {
+	h := &History{
+		Editor:  editor,
+		Box:     widget.NewVBox(),
+		Buttons: make(map[string]*widget.Button),
+		Preview: NewEditor(),
+	}
+	h.Preview.ReadOnly = true
+	// Highlighted like the editor
+	editor.Lock()
+	lexer := editor.lexer
+	editor.Unlock()
+	if lexer != nil {
+		h.Preview.SetLexer(lexer)
+	}
+	h.Scroll = widget.NewVScrollContainer(h.Box)
+	h.Update()
+	return h
+}
+
+// Update lists the deltas read by the editor, most recent first.
+func (h *History) Update() {
+	entries := h.Editor.History()
+	var objects []fyne.CanvasObject
+	buttons := make(map[string]*widget.Button)
+	for i := len(entries) - 1; i >= 0; i-- {
+		entry := entries[i]
+		id := entry.Id
+		button := &widget.Button{
+			Text: fmt.Sprintf("%s %s %s", bcgo.TimestampToString(entry.Timestamp), entry.Creator, DeltaPreview(entry.Delta)),
+			OnTapped: func() {
+				h.Select(id)
+			},
+		}
+		if id == h.Selected {
+			button.Style = widget.PrimaryButton
+		}
+		buttons[id] = button
+		objects = append(objects, button)
+	}
+	h.Buttons = buttons
+	h.Box.Children = objects
+	h.Box.Refresh()
+}
+
+// Select shows the file as it was once the delta with the given id was applied.
+func (h *History) Select(id string) {
+	log.Println("History.Select:", id)
+	if previous, ok := h.Buttons[h.Selected]; ok {
+		previous.Style = widget.DefaultButton
+		previous.Refresh()
+	}
+	h.Selected = id
+	if button, ok := h.Buttons[id]; ok {
+		button.Style = widget.PrimaryButton
+		button.Refresh()
+	}
+	h.Preview.Cursor = 0
+	h.Preview.IsSelecting = false
+	h.Preview.SetText(string(h.Editor.BufferAt(id)))
+}
+
+func (h *History) CanvasObject() fyne.CanvasObject {
+	preview := widget.NewVScrollContainer(h.Preview)
+	h.Preview.Lock()
+	h.Preview.Scroll = preview
+	h.Preview.Unlock()
+	split := widget.NewVSplitContainer(h.Scroll, preview)
+	split.Offset = 0.4
+	return split
+}
+
+// History returns the deltas read from the channel, in the order they are applied.
+func (e *ChannelEditor) History() []*HistoryEntry {
+	e.Lock()
+	defer e.Unlock()
+	order, _ := e.history()
+	entries := make([]*HistoryEntry, len(order))
+	for i, id := range order {
+		record := e.Entries[id].Record
+		entries[i] = &HistoryEntry{
+			Id:        id,
+			Creator:   record.Creator,
+			Timestamp: record.Timestamp,
+			Delta:     e.Deltas[id],
+		}
+	}
+	return entries
+}
+
+// BufferAt returns the content of the file once the delta with the given id, and those before it, were applied.
+func (e *ChannelEditor) BufferAt(id string) []byte {
+	e.Lock()
+	defer e.Unlock()
+	order, parents := e.history()
+	for i, o := range order {
+		if o == id {
+			// Every delta is preceded by those it was written after, so the buffer is the same as when the delta was first applied
+			buffer, _ := ReplayDeltas(order[:i+1], parents, e.Deltas)
+			return buffer
+		}
+	}
+	return nil
+}
+
+// history returns the order and parents of every delta read, which are only kept up to date while the channel holds no sequence records.
+// The caller must hold the lock.
+func (e *ChannelEditor) history() ([]string, map[string][]string) {
+	if len(e.Order) == len(e.Entries) {
+		return e.Order, e.Parents
+	}
+	return OrderDeltas(e.Channel.Name, e.Entries), Parents(e.Channel.Name, e.Entries)
+}
+
+// DeltaPreview returns a single line summary of the text removed and added by the given delta.
+func DeltaPreview(delta *labgo.Delta) string {
+	var parts []string
+	if len(delta.Remove) > 0 {
+		parts = append(parts, "-"+previewText(delta.Remove))
+	}
+	if len(delta.Add) > 0 {
+		parts = append(parts, "+"+previewText(delta.Add))
+	}
+	return strings.Join(parts, " ")
+}
+
+// previewText quotes the given text, so line breaks are shown escaped, after shortening it to PREVIEW_LENGTH runes.
+func previewText(text []byte) string {
+	runes := []rune(string(text))
+	if len(runes) > PREVIEW_LENGTH {
+		return strconv.Quote(string(runes[:PREVIEW_LENGTH])) + "…"
+	}
+	return strconv.Quote(string(runes))
+}
@@ -0,0 +1,91 @@
+/*
+ * Copyright 2020 Aletheia Ware LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package edit_test
+
+import (
+	"fyne.io/fyne"
+	"fyne.io/fyne/test"
+	"github.com/AletheiaWareLLC/labfynego/ui/edit"
+	"github.com/AletheiaWareLLC/labgo"
+	"strings"
+	"testing"
+)
+
+func TestDeltaPreview(t *testing.T) {
+	for name, tt := range map[string]struct {
+		delta *labgo.Delta
+		want  string
+	}{
+		"add":     {&labgo.Delta{Add: []byte("Hello")}, `+"Hello"`},
+		"remove":  {&labgo.Delta{Remove: []byte("World")}, `-"World"`},
+		"replace": {&labgo.Delta{Remove: []byte("Grüße"), Add: []byte("世界\n")}, `-"Grüße" +"世界\n"`},
+		"long":    {&labgo.Delta{Add: []byte(strings.Repeat("é", 40))}, `+"` + strings.Repeat("é", edit.PREVIEW_LENGTH) + `"…`},
+	} {
+		t.Run(name, func(t *testing.T) {
+			if got := edit.DeltaPreview(tt.delta); got != tt.want {
+				t.Errorf("Incorrect preview; expected '%s', got '%s'", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestChannelEditor_History(t *testing.T) {
+	test.NewApp()
+	alice := makeNode(t, "alice")
+	channel := labgo.OpenFileChannel("Test")
+	alice.AddChannel(channel)
+	e := edit.NewChannelEditor(alice, nil, channel, nil)
+	e.Timeout = 0
+	for _, r := range "Hi" {
+		e.TypedRune(r)
+	}
+	writeDelta(t, makeNode(t, "bob"), e, &labgo.Delta{Offset: 2, Add: []byte(" Bob")})
+	e.TypedKey(&fyne.KeyEvent{Name: fyne.KeyHome})
+	e.TypedKey(&fyne.KeyEvent{Name: fyne.KeyDelete})
+
+	entries := e.History()
+	for i, want := range []struct {
+		creator string
+		buffer  string
+	}{
+		{"alice", "H"},
+		{"alice", "Hi"},
+		{"bob", "Hi Bob"},
+		{"alice", "i Bob"},
+	} {
+		if i >= len(entries) {
+			t.Fatalf("Missing entry %d", i)
+		}
+		if got := entries[i].Creator; got != want.creator {
+			t.Errorf("Incorrect creator of entry %d; expected '%s', got '%s'", i, want.creator, got)
+		}
+		if got := string(e.BufferAt(entries[i].Id)); got != want.buffer {
+			t.Errorf("Incorrect buffer at entry %d; expected '%s', got '%s'", i, want.buffer, got)
+		}
+	}
+
+	h := edit.NewHistory(e)
+	if got := len(h.Box.Children); got != len(entries) {
+		t.Errorf("Incorrect number of entries listed; expected '%d', got '%d'", len(entries), got)
+	}
+	h.Select(entries[2].Id)
+	h.Preview.TypedRune('!')
+	h.Preview.TypedKey(&fyne.KeyEvent{Name: fyne.KeyBackspace})
+	if got := h.Preview.Buffer.String(); got != "Hi Bob" {
+		t.Errorf("Incorrect preview; expected '%s', got '%s'", "Hi Bob", got)
+	}
+}
@@ -0,0 +1,255 @@
+/*
+ * Copyright 2020 Aletheia Ware LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package edit
+
+import (
+	"fmt"
+	"github.com/AletheiaWareLLC/bcgo"
+	"github.com/AletheiaWareLLC/labgo"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+const (
+	// Name of the files holding the patterns of the files to exclude from an import, beside the files they exclude
+	IGNORE_FILE = ".gitignore"
+	// Patterns excluded from imports unless included again
+	DEFAULT_IGNORE = ".git/"
+)
+
+// ignorePattern is a pattern of paths to exclude, read from a line of an ignore file.
+type ignorePattern struct {
+	base       string // Path of the directory the pattern applies within, relative to the root, or empty for the root
+	negate     bool   // Includes the paths matched again
+	directory  bool   // Only matches directories
+	expression *regexp.Regexp
+}
+
+// Ignore excludes paths matched by patterns in the style of .gitignore, where the last pattern matching a path decides whether it is excluded.
+type Ignore struct {
+	patterns []*ignorePattern
+}
+
+func NewIgnore() *Ignore {
+	return &Ignore{}
+}
+
+// Add adds the pattern on each line of the given text, which applies within the directory at the given slash separated path, relative to the root.
+// Blank lines and lines starting with # are skipped, patterns starting with ! include the paths matched again, and those ending with / only match directories.
+// Patterns holding a / apply to paths relative to the directory, while others apply to names at any depth within it.
+func (i *Ignore) Add(base, text string) error {
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimRight(line, " \t\r")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		p := &ignorePattern{
+			base: base,
+		}
+		if strings.HasPrefix(line, "!") {
+			p.negate = true
+			line = line[1:]
+		} else if strings.HasPrefix(line, `\`) {
+			// Escapes a leading ! or #
+			line = line[1:]
+		}
+		if strings.HasSuffix(line, "/") {
+			p.directory = true
+			line = strings.TrimRight(line, "/")
+		}
+		if line == "" {
+			continue
+		}
+		expression := "^(?:.*/)?"
+		if strings.Contains(line, "/") {
+			expression = "^"
+			line = strings.TrimPrefix(line, "/")
+		}
+		e, err := regexp.Compile(expression + globExpression(line) + "$")
+		if err != nil {
+			return err
+		}
+		p.expression = e
+		i.patterns = append(i.patterns, p)
+	}
+	return nil
+}
+
+// Match returns true if the file, or directory, at the given slash separated path, relative to the root, is excluded.
+func (i *Ignore) Match(path string, directory bool) bool {
+	ignored := false
+	for _, p := range i.patterns {
+		if p.directory && !directory {
+			continue
+		}
+		relative := path
+		if p.base != "" {
+			if !strings.HasPrefix(path, p.base+"/") {
+				continue
+			}
+			relative = path[len(p.base)+1:]
+		}
+		if p.expression.MatchString(relative) {
+			ignored = !p.negate
+		}
+	}
+	return ignored
+}
+
+// globExpression returns a regular expression matching the same paths as the given glob, where * and ? match within a name, ** matches across names, and [] matches a class of runes.
+func globExpression(glob string) string {
+	var b strings.Builder
+	for i := 0; i < len(glob); {
+		switch {
+		case strings.HasPrefix(glob[i:], "**/"):
+			b.WriteString("(?:.*/)?")
+			i += 3
+		case strings.HasPrefix(glob[i:], "**"):
+			b.WriteString(".*")
+			i += 2
+		case glob[i] == '*':
+			b.WriteString("[^/]*")
+			i++
+		case glob[i] == '?':
+			b.WriteString("[^/]")
+			i++
+		case glob[i] == '[' && strings.Contains(glob[i+1:], "]"):
+			end := i + 1 + strings.Index(glob[i+1:], "]")
+			class := glob[i+1 : end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			b.WriteString("[" + class + "]")
+			i = end + 1
+		case glob[i] == '\\' && i+1 < len(glob):
+			b.WriteString(regexp.QuoteMeta(glob[i+1 : i+2]))
+			i += 2
+		default:
+			b.WriteString(regexp.QuoteMeta(glob[i : i+1]))
+			i++
+		}
+	}
+	return b.String()
+}
+
+// ListFiles returns the path of every regular file within the given directory, relative to it, in lexical order.
+// Files matched by the given patterns, or by the patterns in the IGNORE_FILE of a directory holding them, are skipped, as are the contents of directories which are matched.
+func ListFiles(root string, ignore *Ignore) ([][]string, error) {
+	var files [][]string
+	if err := filepath.Walk(root, func(name string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		relative, err := filepath.Rel(root, name)
+		if err != nil {
+			return err
+		}
+		path := filepath.ToSlash(relative)
+		if path == "." {
+			path = ""
+		} else if ignore.Match(path, info.IsDir()) {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if info.IsDir() {
+			// Patterns apply to the files beside and beneath the ignore file
+			data, err := ioutil.ReadFile(filepath.Join(name, IGNORE_FILE))
+			if err != nil {
+				if os.IsNotExist(err) {
+					return nil
+				}
+				return err
+			}
+			return ignore.Add(path, string(data))
+		}
+		if info.Mode().IsRegular() {
+			files = append(files, strings.Split(path, "/"))
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
+// ImportRoot returns the directory at the given path, or file URI, or an error if there is no directory there.
+func ImportRoot(text string) (string, error) {
+	root := strings.TrimSpace(text)
+	if u, err := url.Parse(root); err == nil && u.Scheme == "file" {
+		root = u.Path
+	}
+	if root == "" {
+		return "", fmt.Errorf("Directory is empty")
+	}
+	info, err := os.Stat(root)
+	if err != nil {
+		return "", err
+	}
+	if !info.IsDir() {
+		return "", fmt.Errorf("Not a directory: %s", root)
+	}
+	return filepath.Clean(root), nil
+}
+
+// ImportBlocks returns the number of blocks mined to import the file at the given path, relative to the given directory, one for its path record, and one for each labgo.MAX_DELTA_LENGTH bytes of its content.
+func ImportBlocks(root string, path []string) int {
+	info, err := os.Stat(filepath.Join(append([]string{root}, path...)...))
+	if err != nil {
+		return 1
+	}
+	return 1 + int((uint64(info.Size())+labgo.MAX_DELTA_LENGTH-1)/labgo.MAX_DELTA_LENGTH)
+}
+
+// ImportFiles creates a file on the given path channel for each of the given paths, relative to the given directory, holding the content of the file at the path, and returns the ids of the files created.
+// Paths which a file already has, as changed by the given change channel, are skipped, so importing a directory again only adds the files which are new.
+// The given function, if set, is called with the index of each path before it is imported.
+func ImportFiles(node *bcgo.Node, listener bcgo.MiningListener, channel, changes *bcgo.Channel, root string, paths [][]string, progress func(int)) ([]string, error) {
+	files, _, err := ResolvePaths(channel, changes, node.Cache, node.Network)
+	if err != nil {
+		return nil, err
+	}
+	existing := make(map[string]bool, len(files))
+	for _, path := range files {
+		existing[strings.Join(path, string(os.PathSeparator))] = true
+	}
+	var ids []string
+	for i, path := range paths {
+		if existing[strings.Join(path, string(os.PathSeparator))] {
+			continue
+		}
+		if progress != nil {
+			progress(i)
+		}
+		file, err := os.Open(filepath.Join(append([]string{root}, path...)...))
+		if err != nil {
+			return ids, err
+		}
+		id, _, err := labgo.CreatePathFromReader(node, listener, channel, path, file)
+		file.Close()
+		if err != nil {
+			return ids, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
@@ -0,0 +1,244 @@
+/*
+ * Copyright 2020 Aletheia Ware LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package edit_test
+
+import (
+	"github.com/AletheiaWareLLC/labfynego/ui/edit"
+	"github.com/AletheiaWareLLC/labgo"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestIgnore(t *testing.T) {
+	for name, tt := range map[string]struct {
+		patterns  string
+		path      string
+		directory bool
+		want      bool
+	}{
+		"none":                {"", "a.go", false, false},
+		"comment":             {"# a.go", "a.go", false, false},
+		"name":                {"a.go", "a.go", false, true},
+		"name_nested":         {"a.go", "src/a.go", false, true},
+		"star":                {"*.log", "build/out.log", false, true},
+		"star_within_name":    {"src/*.go", "src/pkg/a.go", false, false},
+		"question":            {"a?.go", "ab.go", false, true},
+		"class":               {"[ab].go", "b.go", false, true},
+		"class_negated":       {"[!ab].go", "b.go", false, false},
+		"anchored":            {"/a.go", "src/a.go", false, false},
+		"anchored_root":       {"/a.go", "a.go", false, true},
+		"slash_anchors":       {"src/a.go", "lib/src/a.go", false, false},
+		"directory":           {"build/", "build", true, true},
+		"directory_not_file":  {"build/", "build", false, false},
+		"double_star_prefix":  {"**/test", "a/b/test", true, true},
+		"double_star_suffix":  {"src/**", "src/a/b.go", false, true},
+		"double_star_infix":   {"a/**/b.go", "a/x/y/b.go", false, true},
+		"double_star_no_dirs": {"a/**/b.go", "a/b.go", false, true},
+		"negate":              {"*.log\n!keep.log", "keep.log", false, false},
+		"negate_last_wins":    {"!keep.log\n*.log", "keep.log", false, true},
+		"escape":              {`\#a`, "#a", false, true},
+		"trailing_space":      {"a.go  ", "a.go", false, true},
+	} {
+		t.Run(name, func(t *testing.T) {
+			ignore := edit.NewIgnore()
+			if err := ignore.Add("", tt.patterns); err != nil {
+				t.Fatal(err)
+			}
+			if got := ignore.Match(tt.path, tt.directory); got != tt.want {
+				t.Errorf("Incorrect match; expected '%t', got '%t'", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestIgnore_Base(t *testing.T) {
+	ignore := edit.NewIgnore()
+	if err := ignore.Add("src", "/a.go\n*.tmp"); err != nil {
+		t.Fatal(err)
+	}
+	for path, want := range map[string]bool{
+		"a.go":          false,
+		"src/a.go":      true,
+		"src/pkg/a.go":  false,
+		"x.tmp":         false,
+		"src/pkg/x.tmp": true,
+	} {
+		if got := ignore.Match(path, false); got != want {
+			t.Errorf("Incorrect match of '%s'; expected '%t', got '%t'", path, want, got)
+		}
+	}
+}
+
+// writeFiles writes each of the given files, by slash separated path, within the given directory.
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for path, content := range files {
+		name := filepath.Join(dir, filepath.FromSlash(path))
+		if err := os.MkdirAll(filepath.Dir(name), os.ModePerm); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(name, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestListFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "import")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	writeFiles(t, dir, map[string]string{
+		".git/HEAD":           "ref: refs/heads/master",
+		".gitignore":          "*.log\nbuild/\n",
+		"README.md":           "# Test",
+		"build/out":           "binary",
+		"debug.log":           "log",
+		"src/main.go":         "package main",
+		"src/pkg/.gitignore":  "*.tmp\n!keep.log\n",
+		"src/pkg/foo.go":      "package pkg",
+		"src/pkg/foo.tmp":     "temporary",
+		"src/pkg/keep.log":    "kept",
+		"src/other/other.tmp": "not ignored here",
+	})
+	ignore := edit.NewIgnore()
+	if err := ignore.Add("", edit.DEFAULT_IGNORE); err != nil {
+		t.Fatal(err)
+	}
+	files, err := edit.ListFiles(dir, ignore)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, f := range files {
+		got = append(got, strings.Join(f, "/"))
+	}
+	want := []string{
+		".gitignore",
+		"README.md",
+		"src/main.go",
+		"src/other/other.tmp",
+		"src/pkg/.gitignore",
+		"src/pkg/foo.go",
+		"src/pkg/keep.log",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Incorrect files; expected '%v', got '%v'", want, got)
+	}
+}
+
+func TestImportFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "import")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	writeFiles(t, dir, map[string]string{
+		"README.md":   "# Test",
+		"src/main.go": "package main",
+	})
+	alice := makeNode(t, "alice")
+	paths := labgo.OpenPathChannel("Test")
+	alice.AddChannel(paths)
+	var progress []int
+	ids, err := edit.ImportFiles(alice, nil, paths, nil, dir, [][]string{{"README.md"}, {"src", "main.go"}}, func(i int) {
+		progress = append(progress, i)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(progress, []int{0, 1}) {
+		t.Errorf("Incorrect progress; expected '%v', got '%v'", []int{0, 1}, progress)
+	}
+	files, _, err := edit.ResolvePaths(paths, nil, alice.Cache, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, want := range []struct {
+		path []string
+		text string
+	}{
+		{[]string{"README.md"}, "# Test"},
+		{[]string{"src", "main.go"}, "package main"},
+	} {
+		if got := files[ids[i]]; !reflect.DeepEqual(got, want.path) {
+			t.Errorf("Incorrect path; expected '%v', got '%v'", want.path, got)
+		}
+		// One channel per file
+		channel, err := alice.GetChannel(labgo.LAB_PREFIX_FILE + ids[i])
+		if err != nil {
+			t.Fatal(err)
+		}
+		text, err := edit.ReadText(alice, channel)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := string(text); got != want.text {
+			t.Errorf("Incorrect text; expected '%s', got '%s'", want.text, got)
+		}
+	}
+
+	// Importing again only adds the new files
+	writeFiles(t, dir, map[string]string{
+		"src/util.go": "package main",
+	})
+	progress = nil
+	again, err := edit.ImportFiles(alice, nil, paths, nil, dir, [][]string{{"README.md"}, {"src", "main.go"}, {"src", "util.go"}}, func(i int) {
+		progress = append(progress, i)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(progress, []int{2}) {
+		t.Errorf("Incorrect progress; expected '%v', got '%v'", []int{2}, progress)
+	}
+	files, _, err = edit.ResolvePaths(paths, nil, alice.Cache, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(again) != 1 || len(files) != 3 {
+		t.Errorf("Incorrect files; expected 1 of 3 imported, got %d of %d", len(again), len(files))
+	}
+}
+
+func TestImportRoot(t *testing.T) {
+	dir, err := ioutil.TempDir("", "import")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	writeFiles(t, dir, map[string]string{
+		"README.md": "# Test",
+	})
+	for _, text := range []string{dir, " " + dir + " ", "file://" + dir} {
+		if got, err := edit.ImportRoot(text); err != nil {
+			t.Errorf("Unexpected error for '%s': %v", text, err)
+		} else if got != dir {
+			t.Errorf("Incorrect root; expected '%s', got '%s'", dir, got)
+		}
+	}
+	for _, text := range []string{"", filepath.Join(dir, "README.md"), filepath.Join(dir, "missing")} {
+		if _, err := edit.ImportRoot(text); err == nil {
+			t.Errorf("Expected error for '%s'", text)
+		}
+	}
+}
@@ -0,0 +1,309 @@
+/*
+ * Copyright 2020 Aletheia Ware LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package edit
+
+import (
+	"strings"
+	"unicode"
+)
+
+var (
+	GO_LEXER = &CodeLexer{
+		Keywords:        words("break case chan const continue default defer else fallthrough for func go goto if import interface map package range return select struct switch type var"),
+		Types:           words("bool byte complex64 complex128 error float32 float64 int int8 int16 int32 int64 rune string uint uint8 uint16 uint32 uint64 uintptr"),
+		Literals:        words("true false nil iota"),
+		LineComment:     "//",
+		BlockComment:    [2]string{"/*", "*/"},
+		Quotes:          `"'`,
+		MultiLineQuotes: []string{"`"},
+	}
+	JSON_LEXER = &CodeLexer{
+		Literals: words("true false null"),
+		Quotes:   `"`,
+		Keys:     true,
+	}
+	PROTO_LEXER = &CodeLexer{
+		Keywords:     words("syntax package import option message enum service rpc returns stream repeated optional required oneof map reserved extend extensions to max public weak"),
+		Types:        words("double float int32 int64 uint32 uint64 sint32 sint64 fixed32 fixed64 sfixed32 sfixed64 bool string bytes"),
+		Literals:     words("true false"),
+		LineComment:  "//",
+		BlockComment: [2]string{"/*", "*/"},
+		Quotes:       `"'`,
+	}
+	PYTHON_LEXER = &CodeLexer{
+		Keywords:        words("and as assert async await break class continue def del elif else except finally for from global if import in is lambda nonlocal not or pass raise return try while with yield"),
+		Types:           words("bool bytes dict float int list object set str tuple"),
+		Literals:        words("True False None"),
+		LineComment:     "#",
+		Quotes:          `"'`,
+		MultiLineQuotes: []string{`"""`, `'''`},
+	}
+	MARKDOWN_LEXER = &MarkdownLexer{}
+)
+
+// words returns a set of the words in the given space separated list.
+func words(list string) map[string]bool {
+	set := make(map[string]bool)
+	for _, w := range strings.Fields(list) {
+		set[w] = true
+	}
+	return set
+}
+
+// CodeLexer lexes languages made of words, numbers, strings, and comments, such as Go, Python, JSON, and protobuf.
+// The state is 0 between tokens, 1 within a block comment, and 2 plus the index of the quote within a multi-line string.
+type CodeLexer struct {
+	Keywords        map[string]bool
+	Types           map[string]bool
+	Literals        map[string]bool
+	LineComment     string    // Starts a comment to the end of the line
+	BlockComment    [2]string // Starts and ends a comment which may span lines
+	Quotes          string    // Each starts and ends a string on one line, within which a backslash escapes the next rune
+	MultiLineQuotes []string  // Each starts and ends a string which may span lines, and is checked before Quotes
+	Keys            bool      // Strings followed by a colon are keys
+}
+
+func (l *CodeLexer) Lex(line []rune, state int) ([]*Token, int) {
+	var tokens []*Token
+	i := 0
+	// Continue the comment or string the previous line ended in
+	switch {
+	case state == 1:
+		end, ok := find(line, 0, l.BlockComment[1])
+		tokens = append(tokens, &Token{Start: 0, End: end, Kind: TOKEN_COMMENT})
+		if !ok {
+			return tokens, state
+		}
+		i = end
+	case state >= 2 && state-2 < len(l.MultiLineQuotes):
+		end, ok := find(line, 0, l.MultiLineQuotes[state-2])
+		tokens = append(tokens, &Token{Start: 0, End: end, Kind: TOKEN_STRING})
+		if !ok {
+			return tokens, state
+		}
+		i = end
+	}
+	for i < len(line) {
+		r := line[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case l.LineComment != "" && hasPrefix(line, i, l.LineComment):
+			return append(tokens, &Token{Start: i, End: len(line), Kind: TOKEN_COMMENT}), 0
+		case l.BlockComment[0] != "" && hasPrefix(line, i, l.BlockComment[0]):
+			end, ok := find(line, i+len([]rune(l.BlockComment[0])), l.BlockComment[1])
+			tokens = append(tokens, &Token{Start: i, End: end, Kind: TOKEN_COMMENT})
+			if !ok {
+				return tokens, 1
+			}
+			i = end
+		case l.multiLineQuote(line, i) >= 0:
+			q := l.multiLineQuote(line, i)
+			end, ok := find(line, i+len([]rune(l.MultiLineQuotes[q])), l.MultiLineQuotes[q])
+			tokens = append(tokens, &Token{Start: i, End: end, Kind: TOKEN_STRING})
+			if !ok {
+				return tokens, 2 + q
+			}
+			i = end
+		case strings.ContainsRune(l.Quotes, r):
+			end := i + 1
+			for end < len(line) && line[end] != r {
+				if line[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end < len(line) {
+				end++
+			} else {
+				end = len(line)
+			}
+			kind := TOKEN_STRING
+			if l.Keys {
+				next := end
+				for next < len(line) && unicode.IsSpace(line[next]) {
+					next++
+				}
+				if next < len(line) && line[next] == ':' {
+					kind = TOKEN_KEY
+				}
+			}
+			tokens = append(tokens, &Token{Start: i, End: end, Kind: kind})
+			i = end
+		case unicode.IsDigit(r) || (r == '.' && i+1 < len(line) && unicode.IsDigit(line[i+1])):
+			end := i + 1
+			for end < len(line) && (unicode.IsLetter(line[end]) || unicode.IsDigit(line[end]) || line[end] == '.' || line[end] == '_') {
+				end++
+			}
+			tokens = append(tokens, &Token{Start: i, End: end, Kind: TOKEN_NUMBER})
+			i = end
+		case unicode.IsLetter(r) || r == '_':
+			end := i + 1
+			for end < len(line) && (unicode.IsLetter(line[end]) || unicode.IsDigit(line[end]) || line[end] == '_') {
+				end++
+			}
+			word := string(line[i:end])
+			switch {
+			case l.Keywords[word]:
+				tokens = append(tokens, &Token{Start: i, End: end, Kind: TOKEN_KEYWORD})
+			case l.Types[word]:
+				tokens = append(tokens, &Token{Start: i, End: end, Kind: TOKEN_TYPE})
+			case l.Literals[word]:
+				tokens = append(tokens, &Token{Start: i, End: end, Kind: TOKEN_LITERAL})
+			}
+			i = end
+		default:
+			i++
+		}
+	}
+	return tokens, 0
+}
+
+// multiLineQuote returns the index of the multi-line quote starting at the given offset of the line, or -1 if there is none.
+func (l *CodeLexer) multiLineQuote(line []rune, offset int) int {
+	for i, q := range l.MultiLineQuotes {
+		if hasPrefix(line, offset, q) {
+			return i
+		}
+	}
+	return -1
+}
+
+// MarkdownLexer lexes Markdown headings, lists, quotes, code, emphasis, and links.
+// The state is 0 in text, and 1 within a fenced code block.
+type MarkdownLexer struct{}
+
+func (l *MarkdownLexer) Lex(line []rune, state int) ([]*Token, int) {
+	trimmed := strings.TrimLeftFunc(string(line), unicode.IsSpace)
+	indent := len(line) - len([]rune(trimmed))
+	if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+		// Fences start and end code blocks
+		return []*Token{{Start: 0, End: len(line), Kind: TOKEN_CODE}}, 1 - state
+	}
+	if state == 1 {
+		return []*Token{{Start: 0, End: len(line), Kind: TOKEN_CODE}}, state
+	}
+	if indent < 4 {
+		switch {
+		case isHeading(trimmed):
+			return []*Token{{Start: 0, End: len(line), Kind: TOKEN_HEADING}}, 0
+		case strings.HasPrefix(trimmed, ">"):
+			return []*Token{{Start: 0, End: len(line), Kind: TOKEN_COMMENT}}, 0
+		}
+	}
+	var tokens []*Token
+	i := indent
+	if marker := listMarker(trimmed); marker > 0 {
+		tokens = append(tokens, &Token{Start: i, End: i + marker, Kind: TOKEN_KEYWORD})
+		i += marker
+	}
+	for i < len(line) {
+		switch r := line[i]; {
+		case r == '\\':
+			i += 2
+		case r == '`':
+			end, _ := find(line, i+1, "`")
+			tokens = append(tokens, &Token{Start: i, End: end, Kind: TOKEN_CODE})
+			i = end
+		case r == '_' && i > 0 && (unicode.IsLetter(line[i-1]) || unicode.IsDigit(line[i-1])):
+			// Within a word, such as snake_case
+			i++
+		case (r == '*' || r == '_') && hasPrefix(line, i, string([]rune{r, r})):
+			if end, ok := find(line, i+2, string([]rune{r, r})); ok && end > i+4 {
+				tokens = append(tokens, &Token{Start: i, End: end, Kind: TOKEN_STRONG})
+				i = end
+			} else {
+				i += 2
+			}
+		case r == '*' || r == '_':
+			if end, ok := find(line, i+1, string(r)); ok && end > i+2 {
+				tokens = append(tokens, &Token{Start: i, End: end, Kind: TOKEN_EMPHASIS})
+				i = end
+			} else {
+				i++
+			}
+		case r == '[':
+			if end, ok := link(line, i); ok {
+				tokens = append(tokens, &Token{Start: i, End: end, Kind: TOKEN_LINK})
+				i = end
+			} else {
+				i++
+			}
+		default:
+			i++
+		}
+	}
+	return tokens, 0
+}
+
+// isHeading returns true if the given line, without indentation, is an ATX heading.
+func isHeading(line string) bool {
+	level := 0
+	for level < len(line) && line[level] == '#' {
+		level++
+	}
+	return level > 0 && level <= 6 && (level == len(line) || line[level] == ' ' || line[level] == '\t')
+}
+
+// listMarker returns the length of the bullet or number, and the space after it, starting the given line, or 0 if it is not a list item.
+func listMarker(line string) int {
+	if len(line) >= 2 && strings.ContainsRune("-*+", rune(line[0])) && line[1] == ' ' {
+		return 2
+	}
+	digits := 0
+	for digits < len(line) && line[digits] >= '0' && line[digits] <= '9' {
+		digits++
+	}
+	if digits > 0 && digits+1 < len(line) && (line[digits] == '.' || line[digits] == ')') && line[digits+1] == ' ' {
+		return digits + 2
+	}
+	return 0
+}
+
+// link returns the end of the link "[text](target)" starting at the given offset, or false if there is none.
+func link(line []rune, offset int) (int, bool) {
+	text, ok := find(line, offset+1, "]")
+	if !ok || text >= len(line) || line[text] != '(' {
+		return 0, false
+	}
+	return find(line, text+1, ")")
+}
+
+// hasPrefix returns true if the line holds the given prefix at the given offset.
+func hasPrefix(line []rune, offset int, prefix string) bool {
+	p := []rune(prefix)
+	if offset+len(p) > len(line) {
+		return false
+	}
+	for i, r := range p {
+		if line[offset+i] != r {
+			return false
+		}
+	}
+	return true
+}
+
+// find returns the offset after the first occurrence of the given delimiter at or after the given offset, or the end of the line and false if it does not occur.
+func find(line []rune, offset int, delimiter string) (int, bool) {
+	d := len([]rune(delimiter))
+	for i := offset; i+d <= len(line); i++ {
+		if hasPrefix(line, i, delimiter) {
+			return i + d, true
+		}
+	}
+	return len(line), false
+}
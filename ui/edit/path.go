@@ -0,0 +1,188 @@
+/*
+ * Copyright 2020 Aletheia Ware LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package edit
+
+import (
+	"encoding/base64"
+	"github.com/AletheiaWareLLC/bcgo"
+	"github.com/AletheiaWareLLC/labgo"
+	"github.com/golang/protobuf/proto"
+)
+
+// Files are created by labgo.Path records on the path channel of an experiment, and renamed or deleted, as is the format of the files set, by records on its change channel.
+// The change channel is kept apart, as clients which only read the path channel take every record on it for a new file.
+// Change records hold at most one meta data entry, as maps are not marshalled in a consistent order, so the block holding a record with several would not hash the same when validated.
+const (
+	// Prefix of the name of the change channel of an experiment, followed by its id
+	CHANGE_PREFIX = "Lab-Change-"
+
+	// Key of the record meta data holding the id of the file which a labgo.Path moves to the path it holds
+	META_RENAME = "rename"
+	// Key of the record meta data holding the id of the file which a record deletes, such records hold an empty labgo.Path
+	META_DELETE = "delete"
+	// Key of the record meta data holding the format of the files of the experiment, such records hold an empty labgo.Path
+	META_FORMAT = "format"
+)
+
+// OpenChangeChannel returns the channel holding the records which rename and delete the files of the experiment with the given id, and set their format.
+func OpenChangeChannel(experimentId string) *bcgo.Channel {
+	return bcgo.OpenPoWChannel(CHANGE_PREFIX+experimentId, labgo.CHANNEL_THRESHOLD)
+}
+
+// ResolvePaths reads the given path and change channels, and returns the current path of each file which has not been deleted, and the id of the latest change record for each file changed, by file id.
+// The change channel may be nil, in which case files are only created.
+func ResolvePaths(paths, changes *bcgo.Channel, cache bcgo.Cache, network bcgo.Network) (map[string][]string, map[string]string, error) {
+	entries, err := ReadPathEntries(paths, cache, network)
+	if err != nil {
+		return nil, nil, err
+	}
+	changeEntries := make(map[string]*bcgo.BlockEntry)
+	changeName := ""
+	if changes != nil {
+		changeEntries, err = ReadPathEntries(changes, cache, network)
+		if err != nil {
+			return nil, nil, err
+		}
+		changeName = changes.Name
+	}
+	return ResolvePathEntries(paths.Name, entries, changeName, changeEntries)
+}
+
+// ReadPathEntries reads the given path or change channel, and returns its entries by id.
+func ReadPathEntries(channel *bcgo.Channel, cache bcgo.Cache, network bcgo.Network) (map[string]*bcgo.BlockEntry, error) {
+	entries := make(map[string]*bcgo.BlockEntry)
+	if err := bcgo.Read(channel.Name, channel.Head, nil, cache, network, "", nil, nil, func(entry *bcgo.BlockEntry, key, data []byte) error {
+		entries[base64.RawURLEncoding.EncodeToString(entry.RecordHash)] = entry
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// ResolvePathEntries applies the given entries of a path channel, then those of a change channel, each in order, and returns the current path of each file which has not been deleted, and the id of the latest change record for each file changed, by file id.
+// Records of the path channel create a file, identified by the record id, while those of the change channel with META_RENAME or META_DELETE supersede the earlier records for the file they name.
+// Records with META_RENAME, META_DELETE, or META_FORMAT on the path channel, as written by earlier versions, are applied as changes, but are not returned as latest, as later changes are written to the change channel.
+// Once deleted, a file stays deleted, even if it was renamed concurrently.
+// Several files may have the same path, such as when a file is created by collaborators at once, so each is kept, and shown apart by the tree.
+func ResolvePathEntries(paths string, entries map[string]*bcgo.BlockEntry, changes string, changeEntries map[string]*bcgo.BlockEntry) (map[string][]string, map[string]string, error) {
+	files := make(map[string][]string)
+	latest := make(map[string]string)
+	deleted := make(map[string]bool)
+	apply := func(id string, record *bcgo.Record, change bool) error {
+		if _, ok := record.Meta[META_FORMAT]; ok {
+			return nil
+		}
+		// Unmarshal as Path
+		p := &labgo.Path{}
+		if err := proto.Unmarshal(record.Payload, p); err != nil {
+			return err
+		}
+		if file, ok := record.Meta[META_RENAME]; ok {
+			if _, ok := files[file]; !ok {
+				// Deleted, or not yet created
+				return nil
+			}
+			files[file] = p.Path
+			if change {
+				latest[file] = id
+			}
+		} else if file, ok := record.Meta[META_DELETE]; ok {
+			delete(files, file)
+			deleted[file] = true
+			if change {
+				latest[file] = id
+			}
+		} else if !change && !deleted[id] {
+			files[id] = p.Path
+		}
+		return nil
+	}
+	for _, id := range OrderDeltas(paths, entries) {
+		if err := apply(id, entries[id].Record, false); err != nil {
+			return nil, nil, err
+		}
+	}
+	for _, id := range OrderDeltas(changes, changeEntries) {
+		if err := apply(id, changeEntries[id].Record, true); err != nil {
+			return nil, nil, err
+		}
+	}
+	return files, latest, nil
+}
+
+// ResolveFormat returns the format set by the last of the given entries of a path channel, then a change channel, to set one, and the id of the change record which set it, or FORMAT_DELTA if none has.
+// Every replica orders the entries the same, so agrees on the format.
+func ResolveFormat(paths string, entries map[string]*bcgo.BlockEntry, changes string, changeEntries map[string]*bcgo.BlockEntry) (string, string) {
+	format, latest := FORMAT_DELTA, ""
+	for _, id := range OrderDeltas(paths, entries) {
+		if f, ok := entries[id].Record.Meta[META_FORMAT]; ok {
+			format = f
+		}
+	}
+	for _, id := range OrderDeltas(changes, changeEntries) {
+		if f, ok := changeEntries[id].Record.Meta[META_FORMAT]; ok {
+			format, latest = f, id
+		}
+	}
+	return format, latest
+}
+
+// SetFormat mines a record onto the given change channel which sets the format of the files of the experiment.
+// The record is written after the given latest record to set a format, so it supersedes it.
+func SetFormat(node *bcgo.Node, listener bcgo.MiningListener, changes *bcgo.Channel, format, latest string) error {
+	return writeChange(node, listener, changes, META_FORMAT, format, latest, nil)
+}
+
+// RenamePath mines a record onto the given change channel which moves the file with the given id to the given path.
+// The record is written after the given latest change record for the file, if any, so it supersedes it.
+func RenamePath(node *bcgo.Node, listener bcgo.MiningListener, changes *bcgo.Channel, id, latest string, path []string) error {
+	return writeChange(node, listener, changes, META_RENAME, id, latest, path)
+}
+
+// DeletePath mines a record onto the given change channel which deletes the file with the given id.
+// The record is written after the given latest change record for the file, if any, so it supersedes it.
+func DeletePath(node *bcgo.Node, listener bcgo.MiningListener, changes *bcgo.Channel, id, latest string) error {
+	return writeChange(node, listener, changes, META_DELETE, id, latest, nil)
+}
+
+// writeChange mines a labgo.Path with the given meta data key, holding the given value, such as the id of the file it changes, onto the given change channel, referencing the given latest record it supersedes.
+func writeChange(node *bcgo.Node, listener bcgo.MiningListener, channel *bcgo.Channel, meta, value, latest string, path []string) error {
+	var references []*bcgo.Reference
+	if latest != "" {
+		hash, err := base64.RawURLEncoding.DecodeString(latest)
+		if err != nil {
+			return err
+		}
+		references = append(references, &bcgo.Reference{
+			ChannelName: channel.Name,
+			RecordHash:  hash,
+		})
+	}
+	hash, record, err := ProtoToRecord(node.Alias, node.Key, bcgo.Timestamp(), references, map[string]string{
+		meta: value,
+	}, &labgo.Path{
+		Path: path,
+	})
+	if err != nil {
+		return err
+	}
+	return Mine(node, listener, channel, &bcgo.BlockEntry{
+		RecordHash: hash,
+		Record:     record,
+	})
+}
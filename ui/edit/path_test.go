@@ -0,0 +1,254 @@
+/*
+ * Copyright 2020 Aletheia Ware LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package edit_test
+
+import (
+	"fyne.io/fyne/test"
+	"github.com/AletheiaWareLLC/bcgo"
+	"github.com/AletheiaWareLLC/labfynego/ui/edit"
+	"github.com/AletheiaWareLLC/labgo"
+	"github.com/golang/protobuf/proto"
+	"reflect"
+	"testing"
+)
+
+// makePath adds an entry holding the given path, with the given meta data key holding the id of the file with the given hash, or creating a file if the key is empty.
+func makePath(t *testing.T, entries map[string]*bcgo.BlockEntry, hash string, timestamp uint64, meta, file string, path []string, parents ...string) {
+	t.Helper()
+	makeEntry(t, entries, hash, "alice", timestamp, parents...)
+	payload, err := proto.Marshal(&labgo.Path{Path: path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	record := entries[id(hash)].Record
+	record.Payload = payload
+	if meta != "" {
+		record.Meta = map[string]string{
+			meta: id(file),
+		}
+	}
+}
+
+func TestResolvePathEntries(t *testing.T) {
+	for name, tt := range map[string]struct {
+		entries    func(t *testing.T, paths, changes map[string]*bcgo.BlockEntry)
+		want       map[string][]string
+		wantLatest map[string]string
+	}{
+		"create": {
+			entries: func(t *testing.T, paths, changes map[string]*bcgo.BlockEntry) {
+				makePath(t, paths, "a", 1, "", "", []string{"a.go"})
+				makePath(t, paths, "b", 2, "", "", []string{"src", "b.go"})
+			},
+			want:       map[string][]string{id("a"): {"a.go"}, id("b"): {"src", "b.go"}},
+			wantLatest: map[string]string{},
+		},
+		"rename": {
+			entries: func(t *testing.T, paths, changes map[string]*bcgo.BlockEntry) {
+				makePath(t, paths, "a", 1, "", "", []string{"a.go"})
+				makePath(t, changes, "r1", 2, edit.META_RENAME, "a", []string{"src", "a.go"})
+				makePath(t, changes, "r2", 3, edit.META_RENAME, "a", []string{"src", "c.go"}, "r1")
+			},
+			want:       map[string][]string{id("a"): {"src", "c.go"}},
+			wantLatest: map[string]string{id("a"): id("r2")},
+		},
+		"rename_follows_superseded": {
+			// Written after r2, despite the earlier timestamp
+			entries: func(t *testing.T, paths, changes map[string]*bcgo.BlockEntry) {
+				makePath(t, paths, "a", 1, "", "", []string{"a.go"})
+				makePath(t, changes, "r1", 5, edit.META_RENAME, "a", []string{"b.go"})
+				makePath(t, changes, "r2", 2, edit.META_RENAME, "a", []string{"c.go"}, "r1")
+			},
+			want:       map[string][]string{id("a"): {"c.go"}},
+			wantLatest: map[string]string{id("a"): id("r2")},
+		},
+		"delete": {
+			entries: func(t *testing.T, paths, changes map[string]*bcgo.BlockEntry) {
+				makePath(t, paths, "a", 1, "", "", []string{"a.go"})
+				makePath(t, paths, "b", 2, "", "", []string{"b.go"})
+				makePath(t, changes, "d", 3, edit.META_DELETE, "a", nil)
+			},
+			want:       map[string][]string{id("b"): {"b.go"}},
+			wantLatest: map[string]string{id("a"): id("d")},
+		},
+		"delete_concurrent_rename": {
+			// Deleted files stay deleted, whichever comes first
+			entries: func(t *testing.T, paths, changes map[string]*bcgo.BlockEntry) {
+				makePath(t, paths, "a", 1, "", "", []string{"a.go"})
+				makePath(t, changes, "d", 2, edit.META_DELETE, "a", nil)
+				makePath(t, changes, "r", 3, edit.META_RENAME, "a", []string{"b.go"})
+			},
+			want:       map[string][]string{},
+			wantLatest: map[string]string{id("a"): id("d")},
+		},
+		"duplicate": {
+			// Both files are kept, neither is lost
+			entries: func(t *testing.T, paths, changes map[string]*bcgo.BlockEntry) {
+				makePath(t, paths, "a", 1, "", "", []string{"a.go"})
+				makePath(t, paths, "b", 2, "", "", []string{"a.go"})
+			},
+			want:       map[string][]string{id("a"): {"a.go"}, id("b"): {"a.go"}},
+			wantLatest: map[string]string{},
+		},
+		"rename_over": {
+			// Moving a file to the path of another keeps both
+			entries: func(t *testing.T, paths, changes map[string]*bcgo.BlockEntry) {
+				makePath(t, paths, "a", 1, "", "", []string{"a.go"})
+				makePath(t, paths, "b", 2, "", "", []string{"b.go"})
+				makePath(t, changes, "r", 3, edit.META_RENAME, "a", []string{"b.go"})
+			},
+			want:       map[string][]string{id("a"): {"b.go"}, id("b"): {"b.go"}},
+			wantLatest: map[string]string{id("a"): id("r")},
+		},
+		"path_channel_changes": {
+			// Written to the path channel by earlier versions, changes apply before those of the change channel
+			entries: func(t *testing.T, paths, changes map[string]*bcgo.BlockEntry) {
+				makePath(t, paths, "a", 1, "", "", []string{"a.go"})
+				makePath(t, paths, "b", 2, "", "", []string{"b.go"})
+				makePath(t, paths, "r", 3, edit.META_RENAME, "a", []string{"c.go"}, "a")
+				makePath(t, paths, "d", 4, edit.META_DELETE, "b", nil, "b")
+				makePath(t, changes, "r2", 5, edit.META_RENAME, "a", []string{"d.go"})
+			},
+			want:       map[string][]string{id("a"): {"d.go"}},
+			wantLatest: map[string]string{id("a"): id("r2")},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			paths := make(map[string]*bcgo.BlockEntry)
+			changes := make(map[string]*bcgo.BlockEntry)
+			tt.entries(t, paths, changes)
+			got, latest, err := edit.ResolvePathEntries(testChannel, paths, testChannel, changes)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Incorrect paths; expected '%v', got '%v'", tt.want, got)
+			}
+			if !reflect.DeepEqual(latest, tt.wantLatest) {
+				t.Errorf("Incorrect latest; expected '%v', got '%v'", tt.wantLatest, latest)
+			}
+		})
+	}
+}
+
+func TestTree_RenameDelete(t *testing.T) {
+	test.NewApp()
+	alice := makeNode(t, "alice")
+	paths := labgo.OpenPathChannel("Test")
+	changes := edit.OpenChangeChannel("Test")
+	alice.AddChannel(paths)
+	alice.AddChannel(changes)
+	tree := edit.NewTree(paths, changes, alice.Cache, nil, func(string, ...string) {})
+	a, _, err := labgo.CreatePath(alice, nil, paths, []string{"src", "a.go"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _, err := labgo.CreatePath(alice, nil, paths, []string{"src", "b.go"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range [][]string{{"pkg", "a.go"}, {"pkg", "c.go"}} {
+		_, latest := tree.Files()
+		if err := edit.RenamePath(alice, nil, changes, a, latest[a], path); err != nil {
+			t.Fatal(err)
+		}
+	}
+	_, latest := tree.Files()
+	if err := edit.DeletePath(alice, nil, changes, b, latest[b]); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string][]string{a: {"pkg", "c.go"}}
+	if got, _ := tree.Files(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Incorrect paths; expected '%v', got '%v'", want, got)
+	}
+	if got := treeNames(tree.Root, 0); !reflect.DeepEqual(got, []string{"pkg/", " c.go"}) {
+		t.Errorf("Incorrect tree; expected '%v', got '%v'", []string{"pkg/", " c.go"}, got)
+	}
+	// Clients which only read the path channel see the files created, and nothing else
+	entries, err := edit.ReadPathEntries(paths, alice.Cache, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		t.Errorf("Incorrect number of path records; expected '%d', got '%d'", 2, len(entries))
+	}
+}
+
+func TestTree_Format(t *testing.T) {
+	test.NewApp()
+	alice := makeNode(t, "alice")
+	paths := labgo.OpenPathChannel("Test")
+	changes := edit.OpenChangeChannel("Test")
+	alice.AddChannel(paths)
+	alice.AddChannel(changes)
+	tree := edit.NewTree(paths, changes, alice.Cache, nil, func(string, ...string) {})
+	if format, latest := tree.Format(); format != edit.FORMAT_DELTA || latest != "" {
+		t.Errorf("Incorrect format; expected '%s', got '%s' from '%s'", edit.FORMAT_DELTA, format, latest)
+	}
+	a, _, err := labgo.CreatePath(alice, nil, paths, []string{"a.go"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, format := range []string{edit.FORMAT_SEQUENCE, edit.FORMAT_DELTA, edit.FORMAT_SEQUENCE} {
+		_, latest := tree.Format()
+		if err := edit.SetFormat(alice, nil, changes, format, latest); err != nil {
+			t.Fatal(err)
+		}
+		if got, id := tree.Format(); got != format || id == latest {
+			t.Errorf("Incorrect format; expected '%s', got '%s' from '%s'", format, got, id)
+		}
+	}
+	// Format records are not files
+	want := map[string][]string{a: {"a.go"}}
+	if !reflect.DeepEqual(tree.Paths, want) {
+		t.Errorf("Incorrect paths; expected '%v', got '%v'", want, tree.Paths)
+	}
+}
+
+func TestTree_Menu(t *testing.T) {
+	test.NewApp()
+	tree := edit.NewTree(nil, nil, nil, nil, func(string, ...string) {})
+	tree.SetPaths(map[string][]string{
+		"1": {"src", "a.go"},
+		"2": {"src", "b.go"},
+	})
+	directory := tree.Root.Children[0]
+	var renamed, deleted []*edit.TreeNode
+	if menu := tree.Menu(directory); menu != nil {
+		t.Errorf("Incorrect menu; expected none, got '%v'", menu.Items)
+	}
+	tree.OnRename = func(node *edit.TreeNode) {
+		renamed = node.Files()
+	}
+	tree.OnDelete = func(node *edit.TreeNode) {
+		deleted = node.Files()
+	}
+	var labels []string
+	for _, item := range tree.Menu(directory).Items {
+		labels = append(labels, item.Label)
+		item.Action()
+	}
+	if want := []string{"Rename or Move", "Delete"}; !reflect.DeepEqual(labels, want) {
+		t.Errorf("Incorrect menu; expected '%v', got '%v'", want, labels)
+	}
+	if len(renamed) != 2 || len(deleted) != 2 {
+		t.Errorf("Incorrect files; expected '%d' and '%d', got '%d' and '%d'", 2, 2, len(renamed), len(deleted))
+	}
+	if got := len(tree.Menu(directory.Children[0]).Items); got != 3 {
+		t.Errorf("Incorrect file menu; expected '%d' items, got '%d'", 3, got)
+	}
+}
@@ -0,0 +1,488 @@
+/*
+ * Copyright 2020 Aletheia Ware LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package edit
+
+import (
+	"encoding/base64"
+	"github.com/AletheiaWareLLC/bcgo"
+	"github.com/golang/protobuf/proto"
+	"image/color"
+	"log"
+	"reflect"
+	"sort"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	// Prefix of the channel carrying the cursors of those editing a file, followed by the file id
+	PRESENCE_PREFIX = "Lab-Presence-"
+	// Presence records are written as the cursor moves, so are not worth proving work for
+	PRESENCE_THRESHOLD = bcgo.THRESHOLD_Z
+
+	// Duration the cursor must stop moving for before it is written to the presence channel
+	PRESENCE_INTERVAL = time.Second
+	// Minimum duration between presence blocks written by this node, as peers keep them
+	PRESENCE_LIMIT = 15 * time.Second
+	// Duration without activity after which the caret of a collaborator starts to fade
+	PRESENCE_IDLE = 10 * time.Second
+	// Duration without activity after which the caret of a collaborator is no longer shown
+	PRESENCE_TIMEOUT = time.Minute
+	// Duration between refreshes while carets fade
+	PRESENCE_FADE_INTERVAL = time.Second
+)
+
+// Collaborator is where another alias was last active, in bytes of the buffer made by the deltas applied, or in files holding sequence records, after the runes given.
+type Collaborator struct {
+	Alias          string
+	Cursor         uint64
+	Selection      uint64
+	CursorAfter    *ElementId
+	SelectionAfter *ElementId
+	IsSelecting    bool
+	Time           time.Time
+}
+
+func OpenPresenceChannel(fileId string) *bcgo.Channel {
+	return bcgo.OpenPoWChannel(PRESENCE_PREFIX+fileId, PRESENCE_THRESHOLD)
+}
+
+// PresenceAlpha returns the opacity of a caret after the given duration without activity, or false once it is no longer shown.
+func PresenceAlpha(idle time.Duration) (uint8, bool) {
+	switch {
+	case idle >= PRESENCE_TIMEOUT:
+		return 0, false
+	case idle <= PRESENCE_IDLE:
+		return 0xff, true
+	default:
+		return uint8(0xff * (PRESENCE_TIMEOUT - idle) / (PRESENCE_TIMEOUT - PRESENCE_IDLE)), true
+	}
+}
+
+// SetPresenceChannel sets the channel used to send the cursor to, and receive the cursors of, collaborators, and the cache holding its blocks.
+// Presence is not worth keeping, so each block written starts a new chain, and the block it replaces is removed from the cache.
+func (e *ChannelEditor) SetPresenceChannel(channel *bcgo.Channel, cache *bcgo.MemoryCache) {
+	e.Lock()
+	e.PresenceChannel = channel
+	e.PresenceCache = cache
+	e.Unlock()
+	channel.AddTrigger(e.ReadPresence)
+	e.ReadPresence()
+}
+
+// ReadPresence reads the presence records written to PresenceChannel since the last read.
+func (e *ChannelEditor) ReadPresence() {
+	e.reading.Lock()
+	defer e.reading.Unlock()
+	if e.isClosed() {
+		return
+	}
+
+	var blocks []*bcgo.Block
+	if err := bcgo.Iterate(e.PresenceChannel.Name, e.PresenceChannel.Head, nil, e.PresenceCache, e.Node.Network, func(hash []byte, block *bcgo.Block) error {
+		h := base64.RawURLEncoding.EncodeToString(hash)
+		if e.presenceBlocks[h] {
+			return bcgo.StopIterationError{}
+		}
+		e.presenceBlocks[h] = true
+		blocks = append(blocks, block)
+		return nil
+	}); err != nil {
+		if _, ok := err.(bcgo.StopIterationError); !ok {
+			log.Println(err)
+			return
+		}
+	}
+
+	e.Lock()
+	for i := len(blocks) - 1; i >= 0; i-- {
+		for _, entry := range blocks[i].Entry {
+			if entry.Record.Creator == e.Node.Alias {
+				continue
+			}
+			// Unmarshal as Presence
+			presence := &Presence{}
+			if err := proto.Unmarshal(entry.Record.Payload, presence); err != nil {
+				log.Println(err)
+				continue
+			}
+			if e.readPresence(entry.Record, presence) {
+				e.presenceEntries[entry.Record.Creator] = entry
+			}
+		}
+	}
+	e.Unlock()
+	e.Refresh()
+	e.fade()
+}
+
+// readPresence moves the given collaborator to where they were when the given record was written, unless they have since been active, and returns true if they were moved.
+// The caller must hold the lock.
+func (e *ChannelEditor) readPresence(record *bcgo.Record, presence *Presence) bool {
+	t := time.Unix(0, int64(record.Timestamp))
+	if c, ok := e.Collaborators[record.Creator]; ok && c.Time.After(t) {
+		return false
+	}
+	c := &Collaborator{
+		Alias:       record.Creator,
+		IsSelecting: presence.IsSelecting,
+		Time:        t,
+	}
+	if e.Sequence.Len() > 0 {
+		// Runes keep their ids as the text changes, so the presence need not be moved
+		if !e.Sequence.HasElement(presence.CursorAfter) || !e.Sequence.HasElement(presence.SelectionAfter) {
+			return false
+		}
+		c.CursorAfter = presence.CursorAfter
+		c.SelectionAfter = presence.SelectionAfter
+	} else {
+		heads := ParentIds(e.Channel.Name, record)
+		cursor, ok := e.transformPresence(heads, presence.Cursor)
+		if !ok {
+			return false
+		}
+		selection, ok := e.transformPresence(heads, presence.Selection)
+		if !ok {
+			return false
+		}
+		c.Cursor = cursor
+		c.Selection = selection
+	}
+	e.Collaborators[record.Creator] = c
+	return true
+}
+
+// transformPresence moves the given offset, in the buffer made by the deltas up to the given heads, over the deltas applied which were not, and returns false if they are not known.
+// The caller must hold the lock.
+func (e *ChannelEditor) transformPresence(heads []string, offset uint64) (uint64, bool) {
+	if e.replay == nil {
+		return 0, false
+	}
+	seen := make(map[string]bool)
+	for _, h := range heads {
+		if _, ok := e.Entries[h]; !ok {
+			return 0, false
+		}
+		seen[h] = true
+		for a := range Ancestors(e.Parents, h) {
+			seen[a] = true
+		}
+	}
+	for _, id := range e.Order {
+		if !seen[id] {
+			spans, ok := e.replay.Executed[id]
+			if !ok {
+				return 0, false
+			}
+			offset = TransformOffset(offset, spans)
+		}
+	}
+	return offset, true
+}
+
+// moveCollaborators moves collaborators with the given spans of the given delta, and its author, unless this node, to its end.
+// The caller must hold the lock.
+func (e *ChannelEditor) moveCollaborators(id string, spans []*Span) {
+	for _, c := range e.Collaborators {
+		c.Cursor = TransformOffset(c.Cursor, spans)
+		c.Selection = TransformOffset(c.Selection, spans)
+	}
+	record := e.Entries[id].Record
+	if record.Creator == e.Node.Alias || len(spans) == 0 {
+		return
+	}
+	t := time.Unix(0, int64(record.Timestamp))
+	if c, ok := e.Collaborators[record.Creator]; ok && c.Time.After(t) {
+		return
+	}
+	var cursor uint64
+	for _, s := range spans {
+		cursor = TransformOffset(s.Offset+s.Remove, spans)
+	}
+	e.Collaborators[record.Creator] = &Collaborator{
+		Alias:     record.Creator,
+		Cursor:    cursor,
+		Selection: cursor,
+		Time:      t,
+	}
+}
+
+// moveAuthor moves the author of the given sequence record, unless this node, to the end of the text it inserted.
+// The caller must hold the lock.
+func (e *ChannelEditor) moveAuthor(id string, edit *SequenceEdit) {
+	entry, ok := e.EditEntry(id)
+	if !ok || entry.Record.Creator == e.Node.Alias {
+		return
+	}
+	var count int
+	for _, insert := range edit.Insert {
+		count += utf8.RuneCountInString(insert.Text)
+	}
+	if count == 0 {
+		return
+	}
+	t := time.Unix(0, int64(entry.Record.Timestamp))
+	if c, ok := e.Collaborators[entry.Record.Creator]; ok && c.Time.After(t) {
+		return
+	}
+	hash, err := base64.RawURLEncoding.DecodeString(id)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+	after := &ElementId{
+		Record: hash,
+		Index:  uint32(count - 1),
+	}
+	e.Collaborators[entry.Record.Creator] = &Collaborator{
+		Alias:          entry.Record.Creator,
+		CursorAfter:    after,
+		SelectionAfter: after,
+		Time:           t,
+	}
+}
+
+// carets returns the carets of the collaborators active within PRESENCE_TIMEOUT, in the color of their alias, fading once idle.
+// The caller must hold the lock.
+func (e *ChannelEditor) carets() []*Caret {
+	if e.replay == nil && e.Sequence.Len() == 0 {
+		return nil
+	}
+	var aliases []string
+	for a := range e.Collaborators {
+		aliases = append(aliases, a)
+	}
+	sort.Strings(aliases)
+	var carets []*Caret
+	for _, a := range aliases {
+		c := e.Collaborators[a]
+		alpha, ok := PresenceAlpha(time.Since(c.Time))
+		if !ok {
+			continue
+		}
+		var cursor, selection uint64
+		if e.Sequence.Len() > 0 {
+			cursor = e.shownOffset(e.Sequence.elementOffset(c.CursorAfter))
+			selection = e.shownOffset(e.Sequence.elementOffset(c.SelectionAfter))
+		} else {
+			cursor = e.displayOffset(c.Cursor)
+			selection = e.displayOffset(c.Selection)
+		}
+		nrgba := color.NRGBAModel.Convert(AuthorColor(a)).(color.NRGBA)
+		nrgba.A = alpha
+		carets = append(carets, &Caret{
+			Label:       a,
+			Cursor:      cursor,
+			Selection:   selection,
+			IsSelecting: c.IsSelecting,
+			Color:       nrgba,
+		})
+	}
+	return carets
+}
+
+// displayOffset converts the given offset in bytes of the buffer made by the deltas applied, into runes of the buffer shown, which includes the pending delta.
+// The caller must hold the lock.
+func (e *ChannelEditor) displayOffset(offset uint64) uint64 {
+	return e.shownOffset(uint64(e.replay.Buffer.ByteToRune(int(offset))))
+}
+
+// shownOffset converts the given offset in runes of the text applied, into runes of the buffer shown, which includes the pending delta.
+// The caller must hold the lock.
+func (e *ChannelEditor) shownOffset(offset uint64) uint64 {
+	if e.pending != nil && offset > e.pending.Offset {
+		removed := uint64(utf8.RuneCount(e.pending.Remove))
+		added := uint64(utf8.RuneCount(e.pending.Add))
+		if offset < e.pending.Offset+removed {
+			offset = e.pending.Offset + added
+		} else {
+			offset = offset - removed + added
+		}
+	}
+	return offset
+}
+
+// replayOffset converts the given offset in runes of the buffer shown, into bytes of the buffer made by the deltas applied, which excludes the pending delta.
+// The caller must hold the lock.
+func (e *ChannelEditor) replayOffset(offset uint64) uint64 {
+	return uint64(e.replay.Buffer.RuneToByte(int(e.appliedOffset(offset))))
+}
+
+// appliedOffset converts the given offset in runes of the buffer shown, into runes of the text applied, which excludes the pending delta.
+// The caller must hold the lock.
+func (e *ChannelEditor) appliedOffset(offset uint64) uint64 {
+	if e.pending != nil && offset > e.pending.Offset {
+		removed := uint64(utf8.RuneCount(e.pending.Remove))
+		added := uint64(utf8.RuneCount(e.pending.Add))
+		if offset < e.pending.Offset+added {
+			offset = e.pending.Offset
+		} else {
+			offset = offset - added + removed
+		}
+	}
+	return offset
+}
+
+// cursorChanged writes the cursor to PresenceChannel once it has stopped moving for PRESENCE_INTERVAL.
+func (e *ChannelEditor) cursorChanged() {
+	e.Lock()
+	defer e.Unlock()
+	if e.PresenceChannel == nil {
+		return
+	}
+	e.schedulePresence(PRESENCE_INTERVAL)
+}
+
+// schedulePresence writes the cursor to PresenceChannel after the given duration, replacing any write already scheduled.
+// The caller must hold the lock.
+func (e *ChannelEditor) schedulePresence(d time.Duration) {
+	if e.presenceTimer == nil {
+		e.presenceTimer = time.AfterFunc(d, e.WritePresence)
+	} else {
+		e.presenceTimer.Reset(d)
+	}
+}
+
+// WritePresence writes the cursor and selection to PresenceChannel, unless they have not changed since last written.
+// Presence is written at most once every PresenceLimit, a write made sooner is put off until then.
+// The block written carries the latest presence of the collaborators still active, so it replaces those before it.
+func (e *ChannelEditor) WritePresence() {
+	e.Lock()
+	if e.closed || e.PresenceChannel == nil || (e.replay == nil && e.Sequence.Len() == 0) {
+		e.Unlock()
+		return
+	}
+	presence := &Presence{
+		IsSelecting: e.IsSelecting,
+	}
+	var heads []string
+	if e.Sequence.Len() > 0 {
+		presence.CursorAfter = e.Sequence.elementAnchor(e.appliedOffset(e.Cursor))
+		presence.SelectionAfter = e.Sequence.elementAnchor(e.appliedOffset(e.Selection))
+	} else {
+		presence.Cursor = e.replayOffset(e.Cursor)
+		presence.Selection = e.replayOffset(e.Selection)
+		heads = append(heads, e.Heads...)
+	}
+	if e.presenceWritten != nil && proto.Equal(presence, e.presenceWritten) && reflect.DeepEqual(heads, e.presenceHeads) {
+		e.Unlock()
+		return
+	}
+	if wait := e.PresenceLimit - time.Since(e.presenceTime); wait > 0 {
+		e.schedulePresence(wait)
+		e.Unlock()
+		return
+	}
+	e.presenceWritten = presence
+	e.presenceHeads = heads
+	e.presenceTime = time.Now()
+	references := e.references(heads)
+	var entries []*bcgo.BlockEntry
+	for alias, entry := range e.presenceEntries {
+		if c, ok := e.Collaborators[alias]; ok && time.Since(c.Time) < PRESENCE_TIMEOUT {
+			entries = append(entries, entry)
+		}
+	}
+	channel := e.PresenceChannel
+	cache := e.PresenceCache
+	e.Unlock()
+
+	hash, record, err := ProtoToRecord(e.Node.Alias, e.Node.Key, bcgo.Timestamp(), references, nil, presence)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+	entries = append(entries, &bcgo.BlockEntry{
+		RecordHash: hash,
+		Record:     record,
+	})
+	previous := channel.Head
+	block := &bcgo.Block{
+		Timestamp:   bcgo.Timestamp(),
+		ChannelName: channel.Name,
+		Length:      1,
+		Miner:       e.Node.Alias,
+		Entry:       entries,
+	}
+	if previous != nil {
+		// Longer than the chain it replaces, though not linked to it
+		if b, err := cache.GetBlock(previous); err == nil {
+			block.Length = b.Length + 1
+		}
+	}
+	node := &bcgo.Node{
+		Alias:   e.Node.Alias,
+		Key:     e.Node.Key,
+		Cache:   cache,
+		Network: e.Node.Network,
+	}
+	if _, _, err := node.MineBlock(channel, PRESENCE_THRESHOLD, e.Listener, block); err != nil {
+		log.Println(err)
+		return
+	}
+	if previous != nil {
+		e.reading.Lock()
+		prunePresence(cache, previous)
+		e.reading.Unlock()
+	}
+	if e.Node.Network != nil {
+		if err := channel.Push(cache, e.Node.Network); err != nil {
+			log.Println(err)
+		}
+	}
+}
+
+// prunePresence removes the presence block with the given hash, and the blocks it was mined after, from the given cache.
+func prunePresence(cache *bcgo.MemoryCache, hash []byte) {
+	for len(hash) > 0 {
+		key := base64.RawURLEncoding.EncodeToString(hash)
+		block, ok := cache.Block[key]
+		if !ok {
+			return
+		}
+		delete(cache.Block, key)
+		for _, entry := range block.Entry {
+			// Records carried into later blocks stay mapped to them
+			k := base64.RawURLEncoding.EncodeToString(entry.RecordHash)
+			if cache.Mapping[k] == block {
+				delete(cache.Mapping, k)
+			}
+		}
+		hash = block.Previous
+	}
+}
+
+// fade refreshes the editor every PRESENCE_FADE_INTERVAL while the carets of collaborators are shown.
+func (e *ChannelEditor) fade() {
+	e.Lock()
+	defer e.Unlock()
+	if e.closed || e.fader != nil || len(e.carets()) == 0 {
+		return
+	}
+	var step func()
+	step = func() {
+		e.Refresh()
+		e.Lock()
+		defer e.Unlock()
+		if e.closed || len(e.carets()) == 0 {
+			e.fader = nil
+			return
+		}
+		e.fader = time.AfterFunc(PRESENCE_FADE_INTERVAL, step)
+	}
+	e.fader = time.AfterFunc(PRESENCE_FADE_INTERVAL, step)
+}
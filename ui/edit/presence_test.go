@@ -0,0 +1,242 @@
+/*
+ * Copyright 2020 Aletheia Ware LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package edit_test
+
+import (
+	"bytes"
+	"fyne.io/fyne/test"
+	"github.com/AletheiaWareLLC/bcgo"
+	"github.com/AletheiaWareLLC/labfynego/ui/edit"
+	"github.com/AletheiaWareLLC/labgo"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestPresenceAlpha(t *testing.T) {
+	for name, tt := range map[string]struct {
+		idle  time.Duration
+		alpha uint8
+		shown bool
+	}{
+		"Active":  {time.Second, 0xff, true},
+		"Idle":    {edit.PRESENCE_IDLE, 0xff, true},
+		"Fading":  {(edit.PRESENCE_IDLE + edit.PRESENCE_TIMEOUT) / 2, 0x7f, true},
+		"Timeout": {edit.PRESENCE_TIMEOUT, 0, false},
+	} {
+		t.Run(name, func(t *testing.T) {
+			alpha, shown := edit.PresenceAlpha(tt.idle)
+			if alpha != tt.alpha || shown != tt.shown {
+				t.Errorf("Incorrect alpha; expected '%d %t', got '%d %t'", tt.alpha, tt.shown, alpha, shown)
+			}
+		})
+	}
+}
+
+// writePresence writes the given presence to the editor's presence channel as another node, after the given deltas, which need not be known.
+func writePresence(t *testing.T, node *bcgo.Node, e *edit.ChannelEditor, heads []string, presence *edit.Presence) {
+	t.Helper()
+	var references []*bcgo.Reference
+	for _, h := range heads {
+		reference := &bcgo.Reference{
+			ChannelName: e.Channel.Name,
+			RecordHash:  []byte(h),
+		}
+		if entry, ok := e.Entries[h]; ok {
+			reference.Timestamp = entry.Record.Timestamp
+			reference.RecordHash = entry.RecordHash
+		}
+		references = append(references, reference)
+	}
+	hash, record, err := edit.ProtoToRecord(node.Alias, node.Key, bcgo.Timestamp(), references, nil, presence)
+	if err != nil {
+		t.Fatal(err)
+	}
+	miner := &bcgo.Node{
+		Alias: node.Alias,
+		Key:   node.Key,
+		Cache: e.PresenceCache,
+	}
+	if _, _, err := miner.MineEntries(e.PresenceChannel, edit.PRESENCE_THRESHOLD, nil, []*bcgo.BlockEntry{
+		{
+			RecordHash: hash,
+			Record:     record,
+		},
+	}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func assertCarets(t *testing.T, e *edit.ChannelEditor, want map[string]uint64) {
+	t.Helper()
+	e.Lock()
+	carets := e.Carets()
+	e.Unlock()
+	got := make(map[string]uint64)
+	for _, c := range carets {
+		got[c.Label] = c.Cursor
+	}
+	if len(got) != len(want) {
+		t.Fatalf("Incorrect carets; expected '%v', got '%v'", want, got)
+	}
+	for alias, cursor := range want {
+		if got[alias] != cursor {
+			t.Errorf("Incorrect caret of %s; expected '%d', got '%d'", alias, cursor, got[alias])
+		}
+	}
+}
+
+func TestChannelEditor_Collaborators(t *testing.T) {
+	test.NewApp()
+	alice := makeNode(t, "alice")
+	bob := makeNode(t, "bob")
+	channel := labgo.OpenFileChannel("Test")
+	alice.AddChannel(channel)
+	e := edit.NewChannelEditor(alice, nil, channel, nil)
+	e.Timeout = 0
+	presence := edit.OpenPresenceChannel("Test")
+	alice.AddChannel(presence)
+	e.SetPresenceChannel(presence, bcgo.NewMemoryCache(10))
+	for _, r := range "Hello" {
+		e.TypedRune(r)
+	}
+	assertCarets(t, e, map[string]uint64{})
+
+	// Bob's caret is placed at the end of his edit
+	writeDelta(t, bob, e, &labgo.Delta{Offset: 5, Add: []byte(", 世界")})
+	assertCarets(t, e, map[string]uint64{"bob": 9})
+	heads := e.Heads
+
+	// And moves with the edits of others
+	e.Cursor = 0
+	e.TypedRune('¡')
+	if want, got := "¡Hello, 世界", e.Buffer.String(); got != want {
+		t.Fatalf("Incorrect buffer; expected '%s', got '%s'", want, got)
+	}
+	assertCarets(t, e, map[string]uint64{"bob": 10})
+
+	// Presence written before alice's edit is moved over it, the offset is in bytes so 'H' is at 0 and 'l' is at 3
+	writePresence(t, bob, e, heads, &edit.Presence{Cursor: 3, Selection: 1, IsSelecting: true})
+	assertCarets(t, e, map[string]uint64{"bob": 4})
+
+	// Presence after unknown deltas is ignored
+	writePresence(t, bob, e, []string{"unknown"}, &edit.Presence{Cursor: 0})
+	assertCarets(t, e, map[string]uint64{"bob": 4})
+
+	// Carets are hidden once inactive
+	e.Lock()
+	e.Collaborators["bob"].Time = time.Now().Add(-edit.PRESENCE_TIMEOUT)
+	e.Unlock()
+	assertCarets(t, e, map[string]uint64{})
+}
+
+func TestChannelEditor_CollaboratorsSequence(t *testing.T) {
+	test.NewApp()
+	alice := makeNode(t, "alice")
+	bob := makeNode(t, "bob")
+	channel := labgo.OpenFileChannel("Test")
+	alice.AddChannel(channel)
+	e := edit.NewChannelEditor(alice, nil, channel, nil)
+	e.Format = edit.FORMAT_SEQUENCE
+	e.Timeout = 0
+	presence := edit.OpenPresenceChannel("Test")
+	alice.AddChannel(presence)
+	e.SetPresenceChannel(presence, bcgo.NewMemoryCache(10))
+	for _, r := range "Hello" {
+		e.TypedRune(r)
+	}
+	assertCarets(t, e, map[string]uint64{})
+
+	// Bob's caret is placed at the end of his edit
+	writeEdit(t, bob, e, 5, 0, ", 世界")
+	assertCarets(t, e, map[string]uint64{"bob": 9})
+
+	// And moves with the edits of others
+	e.Cursor = 0
+	e.TypedRune('¡')
+	if want, got := "¡Hello, 世界", e.Buffer.String(); got != want {
+		t.Fatalf("Incorrect buffer; expected '%s', got '%s'", want, got)
+	}
+	assertCarets(t, e, map[string]uint64{"bob": 10})
+
+	// Presence is held as the rune the cursor follows, here the first 'l'
+	e.Lock()
+	records := e.Sequence.Records()
+	e.Unlock()
+	writePresence(t, bob, e, nil, &edit.Presence{CursorAfter: &edit.ElementId{Record: records[3]}})
+	assertCarets(t, e, map[string]uint64{"bob": 4})
+
+	// Presence after unknown runes is ignored
+	writePresence(t, bob, e, nil, &edit.Presence{CursorAfter: &edit.ElementId{Record: []byte("unknown")}})
+	assertCarets(t, e, map[string]uint64{"bob": 4})
+}
+
+func TestChannelEditor_WritePresence(t *testing.T) {
+	test.NewApp()
+	alice := makeNode(t, "alice")
+	bob := makeNode(t, "bob")
+	channel := labgo.OpenFileChannel("Test")
+	alice.AddChannel(channel)
+	e := edit.NewChannelEditor(alice, nil, channel, nil)
+	e.Timeout = 0
+	presence := edit.OpenPresenceChannel("Test")
+	alice.AddChannel(presence)
+	cache := bcgo.NewMemoryCache(10)
+	e.SetPresenceChannel(presence, cache)
+	for _, r := range "Hello" {
+		e.TypedRune(r)
+	}
+	writePresence(t, bob, e, e.Heads, &edit.Presence{Cursor: 1})
+	previous := presence.Head
+
+	e.PresenceLimit = 0
+	e.Cursor = 2
+	e.WritePresence()
+	head := presence.Head
+	block, err := cache.GetBlock(head)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The block replaces those before it, carrying the presence of active collaborators
+	if block.Previous != nil {
+		t.Errorf("Incorrect previous; expected 'nil', got '%v'", block.Previous)
+	}
+	var creators []string
+	for _, entry := range block.Entry {
+		creators = append(creators, entry.Record.Creator)
+	}
+	if want := []string{"bob", "alice"}; !reflect.DeepEqual(creators, want) {
+		t.Errorf("Incorrect creators; expected '%v', got '%v'", want, creators)
+	}
+	if _, err := cache.GetBlock(previous); err == nil {
+		t.Error("Expected the replaced block to be pruned")
+	}
+
+	// Presence is not written again until it changes
+	e.WritePresence()
+	if !bytes.Equal(presence.Head, head) {
+		t.Error("Expected unchanged presence not to be written")
+	}
+
+	// Nor more often than the limit
+	e.PresenceLimit = time.Hour
+	e.Cursor = 3
+	e.WritePresence()
+	if !bytes.Equal(presence.Head, head) {
+		t.Error("Expected presence not to be written within the limit")
+	}
+}
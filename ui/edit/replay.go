@@ -0,0 +1,430 @@
+/*
+ * Copyright 2020 Aletheia Ware LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package edit
+
+import (
+	"github.com/AletheiaWareLLC/labgo"
+	"github.com/golang/protobuf/proto"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+const (
+	// Number of deltas applied between snapshots
+	SNAPSHOT_INTERVAL = 100
+)
+
+// SnapshotStore keeps the latest snapshot of each channel, so opening a file resumes from it instead of applying every delta.
+type SnapshotStore interface {
+	GetSnapshot(channel string) (*Snapshot, error)
+	PutSnapshot(channel string, snapshot *Snapshot) error
+}
+
+// FileSnapshotStore keeps snapshots in files named after their channel.
+type FileSnapshotStore struct {
+	Directory string
+}
+
+func NewFileSnapshotStore(directory string) (*FileSnapshotStore, error) {
+	if err := os.MkdirAll(directory, os.ModePerm); err != nil {
+		return nil, err
+	}
+	return &FileSnapshotStore{
+		Directory: directory,
+	}, nil
+}
+
+func (f *FileSnapshotStore) GetSnapshot(channel string) (*Snapshot, error) {
+	data, err := ioutil.ReadFile(filepath.Join(f.Directory, channel))
+	if err != nil {
+		return nil, err
+	}
+	snapshot := &Snapshot{}
+	if err := proto.Unmarshal(data, snapshot); err != nil {
+		return nil, err
+	}
+	return snapshot, nil
+}
+
+func (f *FileSnapshotStore) PutSnapshot(channel string, snapshot *Snapshot) error {
+	data, err := proto.Marshal(snapshot)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filepath.Join(f.Directory, channel), data, 0600)
+}
+
+// Replay applies deltas in order, keeping a snapshot every SNAPSHOT_INTERVAL deltas, so when the order changes only the deltas after the latest snapshot before the change are applied again.
+// The buffer is a rope, so each delta changes only the text around it, and snapshots share the text they hold.
+type Replay struct {
+	Order    []string
+	Buffer   *Rope
+	Executed map[string][]*Span
+
+	heads     map[string]bool
+	removed   map[string][][]byte // Text removed by each span executed for each delta
+	bounds    map[string]int      // Index of the first delta in Order not seen by each concurrent delta applied since the replay started or resumed
+	snapshots []*replaySnapshot
+	text      *tombstones
+}
+
+type replaySnapshot struct {
+	length int
+	buffer *Rope
+	heads  map[string]bool
+}
+
+func NewReplay() *Replay {
+	r := &Replay{}
+	r.reset()
+	return r
+}
+
+// NewReplayFromSnapshot returns a Replay which resumes from the given snapshot.
+// If a later delta was written without seeing every delta in the snapshot, the deltas in the snapshot are applied again to place it in the text its author saw.
+func NewReplayFromSnapshot(snapshot *Snapshot, parents map[string][]string) *Replay {
+	r := NewReplay()
+	r.Order = append([]string{}, snapshot.Order...)
+	r.Buffer = NewRope(string(snapshot.Buffer))
+	for _, id := range Heads(r.Order, parents) {
+		r.heads[id] = true
+	}
+	r.snapshot()
+	return r
+}
+
+// Snapshot returns the current state of the replay.
+func (r *Replay) Snapshot() *Snapshot {
+	return &Snapshot{
+		Order:  append([]string{}, r.Order...),
+		Buffer: []byte(r.Buffer.String()),
+	}
+}
+
+// Apply applies the deltas in the given order which follow the longest prefix it shares with the order already applied, and returns the index of the first delta applied.
+func (r *Replay) Apply(order []string, parents map[string][]string, deltas map[string]*labgo.Delta) int {
+	common := 0
+	for common < len(order) && common < len(r.Order) && order[common] == r.Order[common] {
+		common++
+	}
+	if common < len(r.Order) {
+		// Rewind to the latest snapshot before the change, a copy of a rope is unchanged by edits to the original
+		i := len(r.snapshots) - 1
+		for r.snapshots[i].length > common {
+			i--
+		}
+		s := r.snapshots[i]
+		r.snapshots = r.snapshots[:i+1]
+		r.Order = r.Order[:s.length]
+		r.Buffer = s.buffer.Copy()
+		r.heads = copySet(s.heads)
+		r.text = nil
+	}
+	start := len(r.Order)
+	for _, id := range order[start:] {
+		r.apply(id, parents, deltas)
+	}
+	return start
+}
+
+// apply applies the given delta, placing it in the text its author saw if it was not written after every delta before it.
+func (r *Replay) apply(id string, parents map[string][]string, deltas map[string]*labgo.Delta) {
+	delta := deltas[id]
+	var spans []*Span
+	if isConcurrent(parents[id], r.heads) {
+		ancestors := Ancestors(parents, id)
+		bound := 0
+		for bound < len(r.Order) && ancestors[r.Order[bound]] {
+			bound++
+		}
+		spans = r.tombstones(bound, parents, deltas).integrate(id, parents[id], delta, ancestors)
+		r.bounds[id] = bound
+	} else {
+		delete(r.bounds, id)
+		spans = []*Span{DeltaToSpan(delta)}
+	}
+	removed := make([][]byte, len(spans))
+	for i, s := range spans {
+		removed[i] = []byte(string(r.Buffer.Slice(r.Buffer.ByteToRune(int(s.Offset)), r.Buffer.ByteToRune(int(s.Offset+s.Remove)))))
+	}
+	ApplySpansToRope(spans, r.Buffer)
+	r.Executed[id] = spans
+	r.removed[id] = removed
+	r.Order = append(r.Order, id)
+	for _, p := range parents[id] {
+		delete(r.heads, p)
+	}
+	r.heads[id] = true
+	if len(r.Order)%SNAPSHOT_INTERVAL == 0 {
+		r.snapshot()
+	}
+}
+
+// Revert returns the spans which revert the given deltas in the buffer, or false if any has not been applied since the replay started or resumed.
+// The spans are moved over the deltas applied after those they revert, so deltas ordered before them, even if concurrent, are left as they are.
+func (r *Replay) Revert(ids []string) ([]*Span, bool) {
+	set := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		if _, ok := r.Executed[id]; !ok {
+			return nil, false
+		}
+		set[id] = true
+	}
+	start := len(r.Order)
+	for i := len(r.Order) - 1; i >= 0; i-- {
+		if set[r.Order[i]] {
+			start = i
+		}
+	}
+	var spans []*Span
+	for _, id := range r.Order[start:] {
+		spans = TransformSpans(spans, r.Executed[id])
+		if set[id] {
+			spans = append(spans, r.invert(id)...)
+		}
+	}
+	return spans, true
+}
+
+// invert returns the spans which revert the spans executed for the given delta, in offsets of the buffer once they were applied.
+func (r *Replay) invert(id string) []*Span {
+	spans, removed := r.Executed[id], r.removed[id]
+	indices := make([]int, len(spans))
+	for i := range indices {
+		indices[i] = i
+	}
+	sort.SliceStable(indices, func(i, j int) bool {
+		return spans[indices[i]].Offset < spans[indices[j]].Offset
+	})
+	var inverse []*Span
+	var shift int64
+	for _, i := range indices {
+		s := spans[i]
+		inverse = append(inverse, &Span{
+			Offset: uint64(int64(s.Offset) + shift),
+			Remove: uint64(len(s.Add)),
+			Add:    removed[i],
+		})
+		shift += int64(len(s.Add)) - int64(s.Remove)
+	}
+	return inverse
+}
+
+// tombstones returns the text of the replay, holding every delta in Order, for a delta which has seen only the first bound deltas in Order.
+// The text starts from a snapshot whose deltas have been seen by every concurrent delta after it, and is kept to place later deltas, unless a later snapshot can be used instead.
+func (r *Replay) tombstones(bound int, parents map[string][]string, deltas map[string]*labgo.Delta) *tombstones {
+	if r.text == nil || r.text.base > bound || r.text.length < r.snapshots[len(r.snapshots)-1].length {
+		// The first snapshot is empty, so is always seen
+		i := len(r.snapshots) - 1
+		for limit := bound; ; {
+			for r.snapshots[i].length > limit {
+				i--
+			}
+			for _, id := range r.Order[r.snapshots[i].length:] {
+				if b, ok := r.bounds[id]; ok && b < limit {
+					limit = b
+				}
+			}
+			if limit >= r.snapshots[i].length {
+				break
+			}
+		}
+		s := r.snapshots[i]
+		if r.text == nil || r.text.base > bound || r.text.length < s.length {
+			r.text = newTombstones(s)
+		}
+	}
+	// Catch up with the deltas applied since the text was last used
+	for _, id := range r.Order[r.text.length:] {
+		var ancestors map[string]bool
+		if isConcurrent(parents[id], r.text.heads) {
+			ancestors = Ancestors(parents, id)
+		}
+		r.text.integrate(id, parents[id], deltas[id], ancestors)
+	}
+	return r.text
+}
+
+func (r *Replay) reset() {
+	r.Order = nil
+	r.Buffer = &Rope{}
+	r.Executed = make(map[string][]*Span)
+	r.removed = make(map[string][][]byte)
+	r.heads = make(map[string]bool)
+	r.bounds = make(map[string]int)
+	r.snapshots = nil
+	r.text = nil
+	r.snapshot()
+}
+
+func (r *Replay) snapshot() {
+	r.snapshots = append(r.snapshots, &replaySnapshot{
+		length: len(r.Order),
+		buffer: r.Buffer.Copy(),
+		heads:  copySet(r.heads),
+	})
+}
+
+// isConcurrent reports whether a delta written after the given parents has not seen every delta with the given heads.
+// A delta written after every head has seen all before it, so applies as it is.
+func isConcurrent(parents []string, heads map[string]bool) bool {
+	if len(parents) != len(heads) {
+		return true
+	}
+	for _, p := range parents {
+		if !heads[p] {
+			return true
+		}
+	}
+	return false
+}
+
+func copySet(set map[string]bool) map[string]bool {
+	c := make(map[string]bool, len(set))
+	for k, v := range set {
+		c[k] = v
+	}
+	return c
+}
+
+// tombstones holds the text added by every delta since a snapshot, including text since removed, so a delta can be placed in the text its author saw.
+type tombstones struct {
+	base   int // Number of deltas in the snapshot
+	length int // Number of deltas integrated, including those in the snapshot
+	heads  map[string]bool
+	pieces []*piece
+}
+
+// piece is a run of text added by one delta, or by the snapshot if the id is empty, along with the deltas which removed it.
+type piece struct {
+	id       string
+	length   uint64
+	removers []string
+}
+
+func newTombstones(snapshot *replaySnapshot) *tombstones {
+	t := &tombstones{
+		base:   snapshot.length,
+		length: snapshot.length,
+		heads:  copySet(snapshot.heads),
+	}
+	if length := snapshot.buffer.ByteLen(); length > 0 {
+		t.pieces = append(t.pieces, &piece{
+			length: uint64(length),
+		})
+	}
+	return t
+}
+
+// visible reports whether the piece was in the text seen by the author of a delta written after the given ancestors, or in the current text if ancestors is nil.
+func (p *piece) visible(ancestors map[string]bool) bool {
+	if ancestors != nil && p.id != "" && !ancestors[p.id] {
+		return false
+	}
+	for _, r := range p.removers {
+		if ancestors == nil || ancestors[r] {
+			return false
+		}
+	}
+	return true
+}
+
+// integrate adds the given delta, written after the given parents and ancestors, or after every delta integrated if ancestors is nil, and returns the spans it applies to the current text.
+// Text added at the same place as text the author did not see is placed after it, so deltas applied later land after those applied earlier.
+func (t *tombstones) integrate(id string, parents []string, delta *labgo.Delta, ancestors map[string]bool) []*Span {
+	start := delta.Offset
+	end := start + uint64(len(delta.Remove))
+	inserted := len(delta.Add) == 0
+	var spans []*Span
+	var pieces []*piece
+	var offset, current uint64 // Offsets in the text seen by the author, and in the current text
+	add := func(span *Span) {
+		if l := len(spans) - 1; l >= 0 && len(spans[l].Add) == 0 && spans[l].Offset+spans[l].Remove == span.Offset {
+			spans[l].Remove += span.Remove
+			spans[l].Add = span.Add
+		} else {
+			spans = append(spans, span)
+		}
+	}
+	insert := func() {
+		pieces = append(pieces, &piece{
+			id:     id,
+			length: uint64(len(delta.Add)),
+		})
+		add(&Span{
+			Offset: current,
+			Add:    delta.Add,
+		})
+		inserted = true
+	}
+	for _, p := range t.pieces {
+		shown := len(p.removers) == 0
+		if !p.visible(ancestors) {
+			pieces = append(pieces, p)
+			if shown {
+				current += p.length
+			}
+			continue
+		}
+		// Split the piece where the removal starts and ends
+		from := offset
+		to := offset + p.length
+		for from < to {
+			cut := to
+			if from < start && start < cut {
+				cut = start
+			} else if from < end && end < cut {
+				cut = end
+			}
+			if !inserted && from >= end {
+				insert()
+			}
+			part := &piece{
+				id:       p.id,
+				length:   cut - from,
+				removers: p.removers,
+			}
+			if from >= start && cut <= end {
+				part.removers = append(append([]string{}, p.removers...), id)
+				if shown {
+					add(&Span{
+						Offset: current,
+						Remove: part.length,
+					})
+				}
+			}
+			if shown {
+				current += part.length
+			}
+			pieces = append(pieces, part)
+			from = cut
+		}
+		offset = to
+	}
+	if !inserted {
+		insert()
+	}
+	t.pieces = pieces
+	t.length++
+	for _, p := range parents {
+		delete(t.heads, p)
+	}
+	t.heads[id] = true
+	return spans
+}
@@ -0,0 +1,130 @@
+/*
+ * Copyright 2020 Aletheia Ware LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package edit_test
+
+import (
+	"fmt"
+	"github.com/AletheiaWareLLC/bcgo"
+	"github.com/AletheiaWareLLC/labfynego/ui/edit"
+	"github.com/AletheiaWareLLC/labgo"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+)
+
+// makeChain adds deltas by alice each appending a character after the one before.
+func makeChain(t *testing.T, entries map[string]*bcgo.BlockEntry, deltas map[string]*labgo.Delta, from, to int) {
+	t.Helper()
+	for i := from; i < to; i++ {
+		var parents []string
+		if i > 0 {
+			parents = append(parents, fmt.Sprintf("a%03d", i-1))
+		}
+		makeDelta(t, entries, deltas, fmt.Sprintf("a%03d", i), "alice", uint64(i), &labgo.Delta{
+			Offset: uint64(i),
+			Add:    []byte{'a' + byte(i%26)},
+		}, parents...)
+	}
+}
+
+func assertReplay(t *testing.T, r *edit.Replay, entries map[string]*bcgo.BlockEntry, deltas map[string]*labgo.Delta, wantStart int) {
+	t.Helper()
+	order := edit.OrderDeltas(testChannel, entries)
+	parents := edit.Parents(testChannel, entries)
+	if got := r.Apply(order, parents, deltas); got != wantStart {
+		t.Errorf("Incorrect start; expected '%d', got '%d'", wantStart, got)
+	}
+	want, _ := edit.ReplayDeltas(order, parents, deltas)
+	if got := r.Buffer.String(); got != string(want) {
+		t.Errorf("Incorrect buffer; expected '%s', got '%s'", want, got)
+	}
+}
+
+func TestReplay_Extend(t *testing.T) {
+	entries := make(map[string]*bcgo.BlockEntry)
+	deltas := make(map[string]*labgo.Delta)
+	r := edit.NewReplay()
+	makeChain(t, entries, deltas, 0, 150)
+	assertReplay(t, r, entries, deltas, 0)
+	makeChain(t, entries, deltas, 150, 250)
+	assertReplay(t, r, entries, deltas, 150)
+}
+
+func TestReplay_OutOfOrder(t *testing.T) {
+	entries := make(map[string]*bcgo.BlockEntry)
+	deltas := make(map[string]*labgo.Delta)
+	r := edit.NewReplay()
+	makeChain(t, entries, deltas, 0, 250)
+	assertReplay(t, r, entries, deltas, 0)
+	// Ordered before alice's delta written after the same parent, so the replay rewinds to the snapshot before it
+	makeDelta(t, entries, deltas, "b", "aaron", 121, &labgo.Delta{Offset: 0, Add: []byte("B")}, "a120")
+	assertReplay(t, r, entries, deltas, edit.SNAPSHOT_INTERVAL)
+}
+
+func TestReplay_Snapshot(t *testing.T) {
+	dir, err := ioutil.TempDir("", "snapshot")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	store, err := edit.NewFileSnapshotStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entries := make(map[string]*bcgo.BlockEntry)
+	deltas := make(map[string]*labgo.Delta)
+	makeChain(t, entries, deltas, 0, 150)
+	r := edit.NewReplay()
+	assertReplay(t, r, entries, deltas, 0)
+	if err := store.PutSnapshot(testChannel, r.Snapshot()); err != nil {
+		t.Fatal(err)
+	}
+
+	snapshot, err := store.GetSnapshot(testChannel)
+	if err != nil {
+		t.Fatal(err)
+	}
+	makeChain(t, entries, deltas, 150, 160)
+	r = edit.NewReplayFromSnapshot(snapshot, edit.Parents(testChannel, entries))
+	assertReplay(t, r, entries, deltas, 150)
+
+	// Written without seeing most of the deltas in the snapshot, which are applied again to place it, but only it is added to the buffer
+	makeDelta(t, entries, deltas, "b", "bob", 11, &labgo.Delta{Offset: 0, Add: []byte("B")}, "a010")
+	assertReplay(t, r, entries, deltas, 160)
+}
+
+// BenchmarkReplay_Apply measures applying a delta from a collaborator to a large file.
+func BenchmarkReplay_Apply(b *testing.B) {
+	text := strings.Repeat("Grüße, 世界!\n", 10000)
+	order := []string{"base"}
+	parents := make(map[string][]string)
+	deltas := map[string]*labgo.Delta{
+		"base": {Add: []byte(text)},
+	}
+	r := edit.NewReplay()
+	r.Apply(order, parents, deltas)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		id := fmt.Sprintf("d%d", i)
+		parents[id] = []string{order[len(order)-1]}
+		deltas[id] = &labgo.Delta{Offset: uint64(len(text) / 2), Add: []byte("x")}
+		order = append(order, id)
+		r.Apply(order, parents, deltas)
+	}
+}
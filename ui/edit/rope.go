@@ -0,0 +1,530 @@
+/*
+ * Copyright 2020 Aletheia Ware LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package edit
+
+import (
+	"unicode/utf8"
+)
+
+const (
+	// Maximum number of runes held by a leaf of a rope
+	ROPE_LEAF_LENGTH = 512
+)
+
+// Rope is a text buffer held as a tree of runes, so runes can be inserted and removed, and offsets converted between runes, bytes, and lines, without copying the whole text.
+// Nodes are never modified once made, so a copy of a rope is cheap, and is unchanged by edits to the original.
+// The zero value is an empty rope.
+type Rope struct {
+	root *ropeNode
+}
+
+// ropeNode is either a leaf holding runes, or a branch joining two nodes, and counts the runes, bytes, and line breaks below it.
+type ropeNode struct {
+	left, right *ropeNode
+	runes       []rune
+	length      int
+	bytes       int
+	breaks      int
+	depth       int
+}
+
+func NewRope(text string) *Rope {
+	return NewRopeFromRunes([]rune(text))
+}
+
+func NewRopeFromRunes(runes []rune) *Rope {
+	return &Rope{
+		root: buildRope(runes),
+	}
+}
+
+// Copy returns a rope holding the same text, which is unchanged by edits to this one.
+func (r *Rope) Copy() *Rope {
+	return &Rope{
+		root: r.node(),
+	}
+}
+
+// Len returns the number of runes in the rope.
+func (r *Rope) Len() int {
+	return r.node().len()
+}
+
+// ByteLen returns the number of bytes used to encode the runes in the rope.
+func (r *Rope) ByteLen() int {
+	if n := r.node(); n != nil {
+		return n.bytes
+	}
+	return 0
+}
+
+// Lines returns the number of lines in the rope, which is one more than the number of line breaks.
+func (r *Rope) Lines() int {
+	if n := r.node(); n != nil {
+		return n.breaks + 1
+	}
+	return 1
+}
+
+// String returns the text of the rope.
+func (r *Rope) String() string {
+	return string(r.Runes())
+}
+
+// Runes returns the runes of the rope.
+func (r *Rope) Runes() []rune {
+	return r.Slice(0, r.Len())
+}
+
+// Slice returns the runes from start up to end.
+func (r *Rope) Slice(start, end int) []rune {
+	start, end = r.clamp(start, end)
+	runes := make([]rune, 0, end-start)
+	r.node().each(start, end, func(leaf []rune) {
+		runes = append(runes, leaf...)
+	})
+	return runes
+}
+
+// RuneAt returns the rune at the given offset, which must be less than Len.
+func (r *Rope) RuneAt(offset int) rune {
+	n := r.node()
+	for n.runes == nil {
+		if offset < n.left.len() {
+			n = n.left
+		} else {
+			offset -= n.left.len()
+			n = n.right
+		}
+	}
+	return n.runes[offset]
+}
+
+// Insert inserts the given runes at the given offset.
+func (r *Rope) Insert(offset int, runes []rune) {
+	if len(runes) == 0 {
+		return
+	}
+	offset, _ = r.clamp(offset, offset)
+	left, right := splitRope(r.node(), offset)
+	r.root = concatRope(concatRope(left, buildRope(runes)), right)
+}
+
+// Remove removes the runes from start up to end.
+func (r *Rope) Remove(start, end int) {
+	start, end = r.clamp(start, end)
+	if start == end {
+		return
+	}
+	left, rest := splitRope(r.node(), start)
+	_, right := splitRope(rest, end-start)
+	r.root = concatRope(left, right)
+}
+
+// RuneToByte returns the number of bytes used to encode the given number of runes from the start of the rope.
+func (r *Rope) RuneToByte(offset int) int {
+	offset, _ = r.clamp(offset, offset)
+	count := 0
+	n := r.node()
+	for n != nil && n.runes == nil {
+		if offset < n.left.len() {
+			n = n.left
+		} else {
+			offset -= n.left.len()
+			count += n.left.bytes
+			n = n.right
+		}
+	}
+	if n != nil {
+		for _, c := range n.runes[:offset] {
+			count += utf8.RuneLen(c)
+		}
+	}
+	return count
+}
+
+// ByteToRune returns the number of runes encoded in the given number of bytes from the start of the rope, counting a rune if any of its bytes are.
+func (r *Rope) ByteToRune(offset int) int {
+	count := 0
+	n := r.node()
+	for n != nil && n.runes == nil {
+		if offset < n.left.bytes {
+			n = n.left
+		} else {
+			offset -= n.left.bytes
+			count += n.left.length
+			n = n.right
+		}
+	}
+	if n != nil {
+		for _, c := range n.runes {
+			if offset <= 0 {
+				break
+			}
+			offset -= utf8.RuneLen(c)
+			count++
+		}
+	}
+	return count
+}
+
+// LineStart returns the offset of the first rune of the given line, or Len if there are not that many lines.
+func (r *Rope) LineStart(line int) int {
+	if line <= 0 {
+		return 0
+	}
+	n := r.node()
+	if n == nil || line > n.breaks {
+		return r.Len()
+	}
+	// Find the line break ending the previous line
+	count := 0
+	for n.runes == nil {
+		if line <= n.left.breaks {
+			n = n.left
+		} else {
+			line -= n.left.breaks
+			count += n.left.length
+			n = n.right
+		}
+	}
+	for i, c := range n.runes {
+		if c == '\n' {
+			line--
+			if line == 0 {
+				return count + i + 1
+			}
+		}
+	}
+	return r.Len()
+}
+
+// LineEnd returns the offset of the line break ending the given line, or Len if it is the last line.
+func (r *Rope) LineEnd(line int) int {
+	if line+1 < r.Lines() {
+		return r.LineStart(line+1) - 1
+	}
+	return r.Len()
+}
+
+// LineOf returns the index of the line holding the given offset, which is the number of line breaks before it.
+func (r *Rope) LineOf(offset int) int {
+	offset, _ = r.clamp(offset, offset)
+	count := 0
+	n := r.node()
+	for n != nil && n.runes == nil {
+		if offset < n.left.len() {
+			n = n.left
+		} else {
+			offset -= n.left.len()
+			count += n.left.breaks
+			n = n.right
+		}
+	}
+	if n != nil {
+		for _, c := range n.runes[:offset] {
+			if c == '\n' {
+				count++
+			}
+		}
+	}
+	return count
+}
+
+// CommonPrefix returns the number of runes at the start of this rope which are the same as those at the start of the given rope.
+// Nodes shared by both, as after an edit to a copy, are skipped without reading their runes.
+func (r *Rope) CommonPrefix(other *Rope) int {
+	return commonRunes(r.node(), other.node(), false)
+}
+
+// CommonSuffix returns the number of runes at the end of this rope which are the same as those at the end of the given rope.
+// Nodes shared by both, as after an edit to a copy, are skipped without reading their runes.
+func (r *Rope) CommonSuffix(other *Rope) int {
+	return commonRunes(r.node(), other.node(), true)
+}
+
+// node returns the root of the rope, which is nil if the rope is empty.
+func (r *Rope) node() *ropeNode {
+	if r == nil {
+		return nil
+	}
+	return r.root
+}
+
+// clamp limits the given range to the runes of the rope.
+func (r *Rope) clamp(start, end int) (int, int) {
+	length := r.Len()
+	if start < 0 {
+		start = 0
+	}
+	if start > length {
+		start = length
+	}
+	if end < start {
+		end = start
+	}
+	if end > length {
+		end = length
+	}
+	return start, end
+}
+
+func (n *ropeNode) len() int {
+	if n == nil {
+		return 0
+	}
+	return n.length
+}
+
+// each calls the given function with the runes of each leaf from start up to end.
+func (n *ropeNode) each(start, end int, f func([]rune)) {
+	if n == nil || start >= end {
+		return
+	}
+	if n.runes != nil {
+		f(n.runes[start:end])
+		return
+	}
+	left := n.left.len()
+	if start < left {
+		e := end
+		if e > left {
+			e = left
+		}
+		n.left.each(start, e, f)
+	}
+	if end > left {
+		s := start - left
+		if s < 0 {
+			s = 0
+		}
+		n.right.each(s, end-left, f)
+	}
+}
+
+func newRopeLeaf(runes []rune) *ropeNode {
+	n := &ropeNode{
+		// Capped, so appending to a slice of the runes copies them
+		runes:  runes[:len(runes):len(runes)],
+		length: len(runes),
+	}
+	for _, c := range runes {
+		n.bytes += utf8.RuneLen(c)
+		if c == '\n' {
+			n.breaks++
+		}
+	}
+	return n
+}
+
+func newRopeBranch(left, right *ropeNode) *ropeNode {
+	depth := left.depth
+	if right.depth > depth {
+		depth = right.depth
+	}
+	return &ropeNode{
+		left:   left,
+		right:  right,
+		length: left.length + right.length,
+		bytes:  left.bytes + right.bytes,
+		breaks: left.breaks + right.breaks,
+		depth:  depth + 1,
+	}
+}
+
+// buildRope returns a balanced tree of leaves holding the given runes.
+func buildRope(runes []rune) *ropeNode {
+	var leaves []*ropeNode
+	for len(runes) > 0 {
+		length := len(runes)
+		if length > ROPE_LEAF_LENGTH {
+			length = ROPE_LEAF_LENGTH
+		}
+		leaves = append(leaves, newRopeLeaf(append([]rune{}, runes[:length]...)))
+		runes = runes[length:]
+	}
+	return joinRope(leaves)
+}
+
+// joinRope returns a balanced tree of the given leaves.
+func joinRope(leaves []*ropeNode) *ropeNode {
+	switch len(leaves) {
+	case 0:
+		return nil
+	case 1:
+		return leaves[0]
+	}
+	middle := len(leaves) / 2
+	return newRopeBranch(joinRope(leaves[:middle]), joinRope(leaves[middle:]))
+}
+
+// splitRope returns the nodes holding the runes before, and from, the given offset.
+func splitRope(n *ropeNode, offset int) (*ropeNode, *ropeNode) {
+	switch {
+	case n == nil:
+		return nil, nil
+	case offset <= 0:
+		return nil, n
+	case offset >= n.length:
+		return n, nil
+	case n.runes != nil:
+		return newRopeLeaf(n.runes[:offset]), newRopeLeaf(n.runes[offset:])
+	case offset < n.left.length:
+		left, right := splitRope(n.left, offset)
+		return left, concatRope(right, n.right)
+	default:
+		left, right := splitRope(n.right, offset-n.left.length)
+		return concatRope(n.left, left), right
+	}
+}
+
+// concatRope returns a node holding the runes of the given nodes in order, merging small leaves, and rotating branches so the depths of the two sides of each differ by at most one.
+func concatRope(left, right *ropeNode) *ropeNode {
+	switch {
+	case left == nil:
+		return right
+	case right == nil:
+		return left
+	case left.runes != nil && right.runes != nil && left.length+right.length <= ROPE_LEAF_LENGTH:
+		return newRopeLeaf(append(append(make([]rune, 0, left.length+right.length), left.runes...), right.runes...))
+	case left.runes == nil && left.right.runes != nil && right.runes != nil && left.right.length+right.length <= ROPE_LEAF_LENGTH:
+		return concatRope(left.left, concatRope(left.right, right))
+	case right.runes == nil && right.left.runes != nil && left.runes != nil && left.length+right.left.length <= ROPE_LEAF_LENGTH:
+		return concatRope(concatRope(left, right.left), right.right)
+	case left.depth > right.depth+1:
+		// Join into the right side of the deeper left
+		child := concatRope(left.right, right)
+		if child.depth > left.left.depth+1 {
+			return rotateLeft(newRopeBranch(left.left, child))
+		}
+		return newRopeBranch(left.left, child)
+	case right.depth > left.depth+1:
+		// Join into the left side of the deeper right
+		child := concatRope(left, right.left)
+		if child.depth > right.right.depth+1 {
+			return rotateRight(newRopeBranch(child, right.right))
+		}
+		return newRopeBranch(child, right.right)
+	}
+	return newRopeBranch(left, right)
+}
+
+// rotateLeft returns the given branch with its right child raised above it, first raising the left grandchild if it is the deeper.
+func rotateLeft(n *ropeNode) *ropeNode {
+	right := n.right
+	if right.left.depth > right.right.depth {
+		right = rotateRight(right)
+	}
+	return newRopeBranch(newRopeBranch(n.left, right.left), right.right)
+}
+
+// rotateRight returns the given branch with its left child raised above it, first raising the right grandchild if it is the deeper.
+func rotateRight(n *ropeNode) *ropeNode {
+	left := n.left
+	if left.right.depth > left.left.depth {
+		left = rotateLeft(left)
+	}
+	return newRopeBranch(left.left, newRopeBranch(left.right, n.right))
+}
+
+// ropeCursor reads the leaves of a rope in order, or in reverse order.
+type ropeCursor struct {
+	stack   []*ropeNode // Nodes not yet read, with the next on top
+	read    int         // Runes of the node on top which have been read, which is a leaf if any have
+	reverse bool
+}
+
+func (c *ropeCursor) push(n *ropeNode) {
+	if n.len() > 0 {
+		c.stack = append(c.stack, n)
+	}
+}
+
+// top returns the next node to read, or nil if every node has been read.
+func (c *ropeCursor) top() *ropeNode {
+	if len(c.stack) == 0 {
+		return nil
+	}
+	return c.stack[len(c.stack)-1]
+}
+
+func (c *ropeCursor) pop() {
+	c.stack = c.stack[:len(c.stack)-1]
+	c.read = 0
+}
+
+// split replaces the branch on top with its children.
+func (c *ropeCursor) split() {
+	n := c.top()
+	c.pop()
+	if c.reverse {
+		c.push(n.left)
+		c.push(n.right)
+	} else {
+		c.push(n.right)
+		c.push(n.left)
+	}
+}
+
+// next returns the next rune of the leaf on top.
+func (c *ropeCursor) next() rune {
+	n := c.top()
+	if c.reverse {
+		return n.runes[len(n.runes)-1-c.read]
+	}
+	return n.runes[c.read]
+}
+
+// commonRunes returns the number of runes at the start, or the end if reverse, of both nodes which are the same.
+func commonRunes(a, b *ropeNode, reverse bool) int {
+	x := &ropeCursor{reverse: reverse}
+	y := &ropeCursor{reverse: reverse}
+	x.push(a)
+	y.push(b)
+	count := 0
+	for {
+		n, m := x.top(), y.top()
+		switch {
+		case n == nil || m == nil:
+			return count
+		case n == m && x.read == y.read:
+			count += n.length - x.read
+			x.pop()
+			y.pop()
+		case n.runes == nil && (m.runes != nil || n.length >= m.length):
+			// Split the larger node first, so a node shared by both is reached whole
+			x.split()
+		case m.runes == nil:
+			y.split()
+		default:
+			for x.read < len(n.runes) && y.read < len(m.runes) {
+				if x.next() != y.next() {
+					return count
+				}
+				x.read++
+				y.read++
+				count++
+			}
+			if x.read == len(n.runes) {
+				x.pop()
+			}
+			if y.read == len(m.runes) {
+				y.pop()
+			}
+		}
+	}
+}
@@ -0,0 +1,328 @@
+/*
+ * Copyright 2020 Aletheia Ware LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package edit_test
+
+import (
+	"github.com/AletheiaWareLLC/labfynego/ui/edit"
+	"math/rand"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestRope(t *testing.T) {
+	for name, tt := range map[string]struct {
+		text string
+		edit func(*edit.Rope)
+		want string
+	}{
+		"empty": {
+			edit: func(r *edit.Rope) {},
+		},
+		"insert_empty": {
+			edit: func(r *edit.Rope) {
+				r.Insert(0, []rune("Hello"))
+			},
+			want: "Hello",
+		},
+		"insert_start": {
+			text: "World",
+			edit: func(r *edit.Rope) {
+				r.Insert(0, []rune("Hello "))
+			},
+			want: "Hello World",
+		},
+		"insert_middle": {
+			text: "Hllo",
+			edit: func(r *edit.Rope) {
+				r.Insert(1, []rune("e"))
+			},
+			want: "Hello",
+		},
+		"insert_end": {
+			text: "Hello",
+			edit: func(r *edit.Rope) {
+				r.Insert(5, []rune(", 世界"))
+			},
+			want: "Hello, 世界",
+		},
+		"insert_beyond_end": {
+			text: "Hello",
+			edit: func(r *edit.Rope) {
+				r.Insert(10, []rune("!"))
+			},
+			want: "Hello!",
+		},
+		"remove": {
+			text: "Hello, 世界",
+			edit: func(r *edit.Rope) {
+				r.Remove(5, 7)
+			},
+			want: "Hello世界",
+		},
+		"remove_all": {
+			text: "Hello",
+			edit: func(r *edit.Rope) {
+				r.Remove(0, 5)
+			},
+		},
+		"remove_beyond_end": {
+			text: "Hello",
+			edit: func(r *edit.Rope) {
+				r.Remove(3, 10)
+			},
+			want: "Hel",
+		},
+		"remove_across_leaves": {
+			text: strings.Repeat("a", edit.ROPE_LEAF_LENGTH) + strings.Repeat("b", edit.ROPE_LEAF_LENGTH),
+			edit: func(r *edit.Rope) {
+				r.Remove(1, 2*edit.ROPE_LEAF_LENGTH-1)
+			},
+			want: "ab",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			r := edit.NewRope(tt.text)
+			tt.edit(r)
+			if got := r.String(); got != tt.want {
+				t.Errorf("Incorrect text; expected '%s', got '%s'", tt.want, got)
+			}
+			if got, want := r.Len(), utf8.RuneCountInString(tt.want); got != want {
+				t.Errorf("Incorrect length; expected '%d', got '%d'", want, got)
+			}
+			if got, want := r.ByteLen(), len(tt.want); got != want {
+				t.Errorf("Incorrect byte length; expected '%d', got '%d'", want, got)
+			}
+		})
+	}
+}
+
+func TestRope_Copy(t *testing.T) {
+	r := edit.NewRope("Hello")
+	c := r.Copy()
+	r.Insert(5, []rune(" World"))
+	c.Remove(0, 1)
+	if got, want := r.String(), "Hello World"; got != want {
+		t.Errorf("Incorrect text; expected '%s', got '%s'", want, got)
+	}
+	if got, want := c.String(), "ello"; got != want {
+		t.Errorf("Incorrect copy; expected '%s', got '%s'", want, got)
+	}
+}
+
+func TestRope_Lines(t *testing.T) {
+	for name, tt := range map[string]struct {
+		text string
+		want []string
+	}{
+		"empty": {
+			want: []string{""},
+		},
+		"single": {
+			text: "Hello",
+			want: []string{"Hello"},
+		},
+		"multiple": {
+			text: "Hello\n世界\n!",
+			want: []string{"Hello", "世界", "!"},
+		},
+		"trailing": {
+			text: "Hello\n",
+			want: []string{"Hello", ""},
+		},
+		"blank": {
+			text: "\n\n",
+			want: []string{"", "", ""},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			r := edit.NewRope(tt.text)
+			if got := r.Lines(); got != len(tt.want) {
+				t.Fatalf("Incorrect lines; expected '%d', got '%d'", len(tt.want), got)
+			}
+			for i, want := range tt.want {
+				start, end := r.LineStart(i), r.LineEnd(i)
+				if got := string(r.Slice(start, end)); got != want {
+					t.Errorf("Incorrect line %d; expected '%s', got '%s'", i, want, got)
+				}
+				if got := r.LineOf(start); got != i {
+					t.Errorf("Incorrect line of start %d; expected '%d', got '%d'", start, i, got)
+				}
+				if got := r.LineOf(end); got != i {
+					t.Errorf("Incorrect line of end %d; expected '%d', got '%d'", end, i, got)
+				}
+			}
+		})
+	}
+}
+
+// TestRope_Random compares a rope against a slice of runes given the same random edits.
+func TestRope_Random(t *testing.T) {
+	random := rand.New(rand.NewSource(1))
+	alphabet := []rune("abc \n¡世")
+	r := &edit.Rope{}
+	var want []rune
+	for i := 0; i < 2000; i++ {
+		previous, before := r.Copy(), append([]rune{}, want...)
+		offset := random.Intn(len(want) + 1)
+		if random.Intn(3) > 0 {
+			runes := make([]rune, random.Intn(2*edit.ROPE_LEAF_LENGTH))
+			for j := range runes {
+				runes[j] = alphabet[random.Intn(len(alphabet))]
+			}
+			r.Insert(offset, runes)
+			want = append(want[:offset], append(runes, want[offset:]...)...)
+		} else {
+			end := offset + random.Intn(len(want)-offset+1)
+			r.Remove(offset, end)
+			want = append(want[:offset], want[end:]...)
+		}
+		if got := r.String(); got != string(want) {
+			t.Fatalf("Incorrect text after %d edits", i+1)
+		}
+		if got, want := r.Lines(), strings.Count(string(want), "\n")+1; got != want {
+			t.Fatalf("Incorrect lines after %d edits; expected '%d', got '%d'", i+1, want, got)
+		}
+		if got, want := r.CommonPrefix(previous), commonRunes(want, before, false); got != want {
+			t.Fatalf("Incorrect common prefix after %d edits; expected '%d', got '%d'", i+1, want, got)
+		}
+		if got, want := r.CommonSuffix(previous), commonRunes(want, before, true); got != want {
+			t.Fatalf("Incorrect common suffix after %d edits; expected '%d', got '%d'", i+1, want, got)
+		}
+		for j := 0; j < 10 && len(want) > 0; j++ {
+			offset := random.Intn(len(want) + 1)
+			bytes := len(string(want[:offset]))
+			if got := r.RuneToByte(offset); got != bytes {
+				t.Fatalf("Incorrect byte offset of %d; expected '%d', got '%d'", offset, bytes, got)
+			}
+			if got := r.ByteToRune(bytes); got != offset {
+				t.Fatalf("Incorrect rune offset of %d; expected '%d', got '%d'", bytes, offset, got)
+			}
+			if got, want := r.LineOf(offset), strings.Count(string(want[:offset]), "\n"); got != want {
+				t.Fatalf("Incorrect line of %d; expected '%d', got '%d'", offset, want, got)
+			}
+			if offset < len(want) && r.RuneAt(offset) != want[offset] {
+				t.Fatalf("Incorrect rune at %d; expected '%c', got '%c'", offset, want[offset], r.RuneAt(offset))
+			}
+		}
+	}
+}
+
+// commonRunes returns the number of runes at the start, or the end if reverse, of both slices which are the same.
+func commonRunes(a, b []rune, reverse bool) int {
+	count := 0
+	for count < len(a) && count < len(b) {
+		x, y := a[count], b[count]
+		if reverse {
+			x, y = a[len(a)-1-count], b[len(b)-1-count]
+		}
+		if x != y {
+			break
+		}
+		count++
+	}
+	return count
+}
+
+// benchmarkText returns a document of the given number of lines.
+func benchmarkText(lines int) string {
+	return strings.Repeat("The quick brown fox jumps over the lazy dog, 敏捷的棕色狐狸跳过了懒狗\n", lines)
+}
+
+func BenchmarkRope_Insert(b *testing.B) {
+	r := edit.NewRope(benchmarkText(10000))
+	random := rand.New(rand.NewSource(1))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		r.Insert(random.Intn(r.Len()+1), []rune{'x'})
+	}
+}
+
+// BenchmarkRunes_Insert is the baseline for BenchmarkRope_Insert, inserting into a slice of runes as Editor did.
+func BenchmarkRunes_Insert(b *testing.B) {
+	runes := []rune(benchmarkText(10000))
+	random := rand.New(rand.NewSource(1))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		offset := random.Intn(len(runes) + 1)
+		buffer := make([]rune, 0, len(runes)+1)
+		buffer = append(buffer, runes[:offset]...)
+		buffer = append(buffer, 'x')
+		runes = append(buffer, runes[offset:]...)
+	}
+}
+
+func BenchmarkRope_Remove(b *testing.B) {
+	r := edit.NewRope(benchmarkText(10000))
+	random := rand.New(rand.NewSource(1))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if r.Len() == 0 {
+			b.StopTimer()
+			r = edit.NewRope(benchmarkText(10000))
+			b.StartTimer()
+		}
+		offset := random.Intn(r.Len())
+		r.Remove(offset, offset+1)
+	}
+}
+
+func BenchmarkRope_RuneToByte(b *testing.B) {
+	r := edit.NewRope(benchmarkText(10000))
+	random := rand.New(rand.NewSource(1))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		r.RuneToByte(random.Intn(r.Len() + 1))
+	}
+}
+
+// BenchmarkRunes_RuneToByte is the baseline for BenchmarkRope_RuneToByte, counting the bytes of a slice of runes as Editor did.
+func BenchmarkRunes_RuneToByte(b *testing.B) {
+	runes := []rune(benchmarkText(10000))
+	random := rand.New(rand.NewSource(1))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		edit.RuneToByteOffset(runes, uint64(random.Intn(len(runes)+1)))
+	}
+}
+
+func BenchmarkRope_ByteToRune(b *testing.B) {
+	r := edit.NewRope(benchmarkText(10000))
+	random := rand.New(rand.NewSource(1))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		r.ByteToRune(random.Intn(r.ByteLen() + 1))
+	}
+}
+
+func BenchmarkRope_LineStart(b *testing.B) {
+	r := edit.NewRope(benchmarkText(10000))
+	random := rand.New(rand.NewSource(1))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		r.LineStart(random.Intn(r.Lines()))
+	}
+}
+
+func BenchmarkRope_LineOf(b *testing.B) {
+	r := edit.NewRope(benchmarkText(10000))
+	random := rand.New(rand.NewSource(1))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		r.LineOf(random.Intn(r.Len() + 1))
+	}
+}
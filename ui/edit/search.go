@@ -0,0 +1,331 @@
+/*
+ * Copyright 2020 Aletheia Ware LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package edit
+
+import (
+	"fmt"
+	"fyne.io/fyne"
+	"fyne.io/fyne/layout"
+	"fyne.io/fyne/widget"
+	"github.com/AletheiaWareLLC/bcgo"
+	"github.com/AletheiaWareLLC/labgo"
+	"github.com/golang/protobuf/proto"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const (
+	// Number of runes either side of a match kept in the snippet of its result
+	SNIPPET_CONTEXT = 40
+)
+
+// SearchResult is a match of a query in a file of an experiment.
+type SearchResult struct {
+	Id         string   // Id of the file
+	Path       []string // Path of the file
+	Line       int      // Line holding the start of the match, counting from one
+	Start, End int      // Offsets of the match in runes
+	Snippet    string   // Text of the line around the match
+}
+
+// Label returns the path, line, and snippet of the result.
+func (r *SearchResult) Label() string {
+	name := r.Id
+	if len(r.Path) > 0 {
+		name = strings.Join(r.Path, string(os.PathSeparator))
+	}
+	return fmt.Sprintf("%s:%d: %s", name, r.Line, r.Snippet)
+}
+
+// ReadText returns the text of the file on the given channel, integrating its records of type TYPE_SEQUENCE if it holds any, along with any deltas written by editors which had not seen them, otherwise replaying its deltas.
+func ReadText(node *bcgo.Node, channel *bcgo.Channel) ([]rune, error) {
+	var blocks []*bcgo.Block
+	if err := bcgo.Iterate(channel.Name, channel.Head, nil, node.Cache, node.Network, func(hash []byte, block *bcgo.Block) error {
+		blocks = append(blocks, block)
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	entries := make(map[string]*bcgo.BlockEntry)
+	deltas := make(map[string]*labgo.Delta)
+	sequence := NewSequence()
+	edits := false
+	readFileBlocks(blocks, nil, func(id string, entry *bcgo.BlockEntry, message proto.Message) {
+		switch m := message.(type) {
+		case *SequenceEdit:
+			// Edits converge whatever order they are integrated in
+			sequence.Integrate(entry.RecordHash, m)
+			edits = true
+		case *labgo.Delta:
+			deltas[id] = m
+			entries[id] = entry
+		}
+	})
+	order := OrderDeltas(channel.Name, entries)
+	parents := Parents(channel.Name, entries)
+	if edits {
+		sequence.IntegrateDeltas(order, parents, deltas)
+		return sequence.Runes(), nil
+	}
+	buffer, _ := ReplayDeltas(order, parents, deltas)
+	return []rune(string(buffer)), nil
+}
+
+// SearchText returns a result for each match of the given query in the given text of a file.
+func SearchText(id string, path []string, text []rune, query *Query) ([]*SearchResult, error) {
+	matches, err := query.Find(text)
+	if err != nil {
+		return nil, err
+	}
+	var results []*SearchResult
+	line, start := 1, 0 // Line and offset of the start of the line, before the current match
+	for _, m := range matches {
+		for i := start; i < m.Start; i++ {
+			if text[i] == '\n' {
+				line++
+				start = i + 1
+			}
+		}
+		end := start
+		for end < len(text) && text[end] != '\n' {
+			end++
+		}
+		from, to := start, end
+		if m.Start-from > SNIPPET_CONTEXT {
+			from = m.Start - SNIPPET_CONTEXT
+		}
+		if to-m.End > SNIPPET_CONTEXT {
+			to = m.End + SNIPPET_CONTEXT
+		}
+		snippet := strings.TrimSpace(string(text[from:to]))
+		if from > start {
+			snippet = "…" + snippet
+		}
+		if to < end {
+			snippet += "…"
+		}
+		results = append(results, &SearchResult{
+			Id:      id,
+			Path:    path,
+			Line:    line,
+			Start:   m.Start,
+			End:     m.End,
+			Snippet: snippet,
+		})
+	}
+	return results, nil
+}
+
+// SearchPaths searches every file on the given path channel which has not been deleted by the given change channel, in order of path, passing each result to the given callback as it is found.
+// The text of each file is returned by the given function, and the search stops early once done is closed.
+func SearchPaths(paths, changes *bcgo.Channel, cache bcgo.Cache, network bcgo.Network, query *Query, text func(id string) ([]rune, error), done <-chan struct{}, callback func(*SearchResult)) error {
+	// Fail before reading any files if the query is not valid
+	if _, err := query.compile(); err != nil {
+		return err
+	}
+	files, _, err := ResolvePaths(paths, changes, cache, network)
+	if err != nil {
+		return err
+	}
+	var ids []string
+	for id := range files {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		return pathKey(files[ids[i]]) < pathKey(files[ids[j]])
+	})
+	for _, id := range ids {
+		select {
+		case <-done:
+			return nil
+		default:
+		}
+		t, err := text(id)
+		if err != nil {
+			// Keep searching the other files
+			log.Println(err)
+			continue
+		}
+		results, err := SearchText(id, files[id], t, query)
+		if err != nil {
+			return err
+		}
+		for _, r := range results {
+			callback(r)
+		}
+	}
+	return nil
+}
+
+// SearchPanel searches every file of an experiment in the background as the query is typed, listing the results as they are found.
+type SearchPanel struct {
+	Query     *widget.Entry
+	MatchCase *widget.Check
+	WholeWord *widget.Check
+	Regex     *widget.Check
+	Status    *widget.Label
+	Box       *widget.Box
+	Scroll    *widget.ScrollContainer
+	Results   []*SearchResult
+
+	// Find passes each result of the given query to the given callback, until done is closed
+	Find func(query *Query, done <-chan struct{}, callback func(*SearchResult)) error
+
+	// OnSelect, if set, is called when a result is tapped
+	OnSelect func(*SearchResult)
+
+	// OnClose, if set, is called when the close button is tapped
+	OnClose func()
+
+	lock    sync.Mutex
+	done    chan struct{} // Closed to stop the running search
+	running sync.WaitGroup
+}
+
+func NewSearchPanel(find func(query *Query, done <-chan struct{}, callback func(*SearchResult)) error) *SearchPanel {
+	p := &SearchPanel{
+		Query:  widget.NewEntry(),
+		Status: widget.NewLabel(""),
+		Box:    widget.NewVBox(),
+		Find:   find,
+	}
+	p.Scroll = widget.NewVScrollContainer(p.Box)
+	p.Query.SetPlaceHolder("Find in Experiment")
+	p.Query.OnChanged = func(string) {
+		p.Search()
+	}
+	p.MatchCase = widget.NewCheck("Case", func(bool) {
+		p.Search()
+	})
+	p.WholeWord = widget.NewCheck("Word", func(bool) {
+		p.Search()
+	})
+	p.Regex = widget.NewCheck("Regex", func(bool) {
+		p.Search()
+	})
+	return p
+}
+
+// query returns the query entered.
+func (p *SearchPanel) query() *Query {
+	return &Query{
+		Text:      p.Query.Text,
+		MatchCase: p.MatchCase.Checked,
+		WholeWord: p.WholeWord.Checked,
+		Regex:     p.Regex.Checked,
+	}
+}
+
+// Search stops the running search, if any, and starts searching for the query in the background, listing each result as it is found.
+func (p *SearchPanel) Search() {
+	p.lock.Lock()
+	p.stop()
+	p.Results = nil
+	p.Box.Children = nil
+	query := p.query()
+	if query.Text == "" {
+		p.lock.Unlock()
+		p.Status.SetText("")
+		p.Box.Refresh()
+		return
+	}
+	done := make(chan struct{})
+	p.done = done
+	p.running.Add(1)
+	p.lock.Unlock()
+	p.Status.SetText("Searching…")
+	p.Box.Refresh()
+	go func() {
+		defer p.running.Done()
+		err := p.Find(query, done, func(result *SearchResult) {
+			p.lock.Lock()
+			if p.done != done {
+				// Results of a search which has since stopped
+				p.lock.Unlock()
+				return
+			}
+			p.Results = append(p.Results, result)
+			p.Box.Children = append(p.Box.Children, &widget.Button{
+				Text: result.Label(),
+				OnTapped: func() {
+					if p.OnSelect != nil {
+						p.OnSelect(result)
+					}
+				},
+			})
+			p.lock.Unlock()
+			p.Box.Refresh()
+		})
+		p.lock.Lock()
+		if p.done != done {
+			p.lock.Unlock()
+			return
+		}
+		count := len(p.Results)
+		p.lock.Unlock()
+		switch {
+		case err != nil:
+			log.Println(err)
+			p.Status.SetText(err.Error())
+		case count == 1:
+			p.Status.SetText("1 result")
+		default:
+			p.Status.SetText(fmt.Sprintf("%d results", count))
+		}
+	}()
+}
+
+// Wait blocks until every search which has been started has finished.
+func (p *SearchPanel) Wait() {
+	p.running.Wait()
+}
+
+// Close stops the running search, if any, and calls OnClose.
+func (p *SearchPanel) Close() {
+	p.lock.Lock()
+	p.stop()
+	p.lock.Unlock()
+	if p.OnClose != nil {
+		p.OnClose()
+	}
+}
+
+// stop closes the done channel of the running search, if any.
+// The caller must hold the lock.
+func (p *SearchPanel) stop() {
+	if p.done != nil {
+		close(p.done)
+		p.done = nil
+	}
+}
+
+func (p *SearchPanel) CanvasObject() fyne.CanvasObject {
+	options := widget.NewHBox(
+		p.MatchCase,
+		p.WholeWord,
+		p.Regex,
+		widget.NewButton("Close", p.Close),
+	)
+	top := widget.NewVBox(
+		p.Query,
+		fyne.NewContainerWithLayout(layout.NewBorderLayout(nil, nil, nil, options), options, p.Status),
+	)
+	return fyne.NewContainerWithLayout(layout.NewBorderLayout(top, nil, nil, nil), top, p.Scroll)
+}
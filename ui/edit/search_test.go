@@ -0,0 +1,156 @@
+/*
+ * Copyright 2020 Aletheia Ware LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package edit_test
+
+import (
+	"fyne.io/fyne/test"
+	"fyne.io/fyne/widget"
+	"github.com/AletheiaWareLLC/labfynego/ui/edit"
+	"github.com/AletheiaWareLLC/labgo"
+	"io/ioutil"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestSearchText(t *testing.T) {
+	for name, tt := range map[string]struct {
+		query *edit.Query
+		text  string
+		want  []string
+	}{
+		"none": {
+			query: &edit.Query{Text: "bar"},
+			text:  "foo",
+		},
+		"lines": {
+			query: &edit.Query{Text: "foo"},
+			text:  "foo\nbar\n\tfoo bar foo\n",
+			want:  []string{"a:1: foo", "a:3: foo bar foo", "a:3: foo bar foo"},
+		},
+		"long": {
+			query: &edit.Query{Text: "foo"},
+			text:  strings.Repeat("x", edit.SNIPPET_CONTEXT+1) + "foo" + strings.Repeat("y", edit.SNIPPET_CONTEXT+1),
+			want:  []string{"a:1: …" + strings.Repeat("x", edit.SNIPPET_CONTEXT) + "foo" + strings.Repeat("y", edit.SNIPPET_CONTEXT) + "…"},
+		},
+		"runes": {
+			query: &edit.Query{Text: "界"},
+			text:  "Grüße\n世界",
+			want:  []string{"a:2: 世界"},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			results, err := edit.SearchText("id", []string{"a"}, []rune(tt.text), tt.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, r := range results {
+				got = append(got, r.Label())
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Incorrect results; expected '%v', got '%v'", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestSearchPaths(t *testing.T) {
+	test.NewApp()
+	alice := makeNode(t, "alice")
+	paths := labgo.OpenPathChannel("Test")
+	alice.AddChannel(paths)
+	files := map[string]string{
+		"a.txt": "Hello World",
+		"b.txt": "Goodbye\nWorld",
+		"c.txt": "Nothing",
+	}
+	for name, text := range files {
+		if _, _, err := labgo.CreatePathFromReader(alice, nil, paths, []string{"dir", name}, ioutil.NopCloser(strings.NewReader(text))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	text := func(id string) ([]rune, error) {
+		channel, err := alice.GetChannel(labgo.LAB_PREFIX_FILE + id)
+		if err != nil {
+			return nil, err
+		}
+		return edit.ReadText(alice, channel)
+	}
+	var got []string
+	if err := edit.SearchPaths(paths, nil, alice.Cache, nil, &edit.Query{Text: "world"}, text, nil, func(r *edit.SearchResult) {
+		got = append(got, strings.Join(r.Path, "/")+":"+r.Snippet)
+	}); err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(got)
+	want := []string{"dir/a.txt:Hello World", "dir/b.txt:World"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Incorrect results; expected '%v', got '%v'", want, got)
+	}
+
+	// Stops once done is closed
+	done := make(chan struct{})
+	close(done)
+	got = nil
+	if err := edit.SearchPaths(paths, nil, alice.Cache, nil, &edit.Query{Text: "world"}, text, done, func(r *edit.SearchResult) {
+		got = append(got, r.Snippet)
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 0 {
+		t.Errorf("Incorrect results; expected none, got '%v'", got)
+	}
+
+	// Invalid queries fail without reading any files
+	if err := edit.SearchPaths(paths, nil, alice.Cache, nil, &edit.Query{Text: "(", Regex: true}, nil, nil, nil); err == nil {
+		t.Error("Expected error")
+	}
+}
+
+func TestSearchPanel(t *testing.T) {
+	test.NewApp()
+	var selected *edit.SearchResult
+	p := edit.NewSearchPanel(func(query *edit.Query, done <-chan struct{}, callback func(*edit.SearchResult)) error {
+		results, err := edit.SearchText("id", []string{"a"}, []rune("one two one"), query)
+		for _, r := range results {
+			callback(r)
+		}
+		return err
+	})
+	p.OnSelect = func(r *edit.SearchResult) {
+		selected = r
+	}
+	p.Query.SetText("one")
+	p.Wait()
+	if got := len(p.Results); got != 2 {
+		t.Fatalf("Incorrect results; expected '%d', got '%d'", 2, got)
+	}
+	if got := p.Status.Text; got != "2 results" {
+		t.Errorf("Incorrect status; expected '%s', got '%s'", "2 results", got)
+	}
+	test.Tap(p.Box.Children[1].(*widget.Button))
+	if selected == nil || selected.Start != 8 {
+		t.Errorf("Incorrect selection; expected '%d', got '%v'", 8, selected)
+	}
+	p.Query.SetText("")
+	p.Wait()
+	if got := len(p.Box.Children); got != 0 {
+		t.Errorf("Incorrect results listed; expected '%d', got '%d'", 0, got)
+	}
+}
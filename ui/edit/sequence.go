@@ -0,0 +1,717 @@
+/*
+ * Copyright 2020 Aletheia Ware LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package edit
+
+import (
+	"encoding/base64"
+	"github.com/AletheiaWareLLC/bcgo"
+	"github.com/AletheiaWareLLC/labgo"
+	"github.com/golang/protobuf/proto"
+	"log"
+	"unicode/utf8"
+)
+
+const (
+	// Value of the record meta data type of a SequenceEdit
+	TYPE_SEQUENCE = "sequence"
+)
+
+type elementKey struct {
+	record string
+	index  uint32
+}
+
+// The key of the start of the sequence, as no record has an empty hash
+var rootKey = elementKey{}
+
+type element struct {
+	key      elementKey
+	rune     rune
+	clock    uint64
+	children []*element // Runes inserted after this one, in order
+	visible  bool       // Whether every removal of the rune has been cancelled
+	shown    bool       // Whether the rune is held in the text of the sequence
+}
+
+// Sequence is a replicated sequence of runes in which every rune has a unique id, so edits converge whatever order they are integrated in, without being ordered or transformed.
+// Runes inserted after the same rune are ordered by clock, then record hash, latest first, so an insertion made after seeing another is placed before it.
+type Sequence struct {
+	Clock    uint64
+	elements map[elementKey]*element
+	root     *element                         // Start of the sequence, holding the runes inserted at the start
+	held     map[elementKey][]*element        // Runes inserted after runes which are not yet known, by the key of the rune they follow
+	removed  map[elementKey][]string          // Hashes of the records which removed each rune
+	restored map[elementKey]map[string]string // Hashes of the records which cancelled the removals of each rune, by the hash of the remover
+	records  map[string]bool
+	aliases  map[string]string // Hashes of the deltas records made from deltas hold their runes under, by record hash
+	clocks   map[string]uint64 // Clocks of the records, by hash
+	sources  map[string]string // Ids of the deltas the records were made from, by hash
+	deltas   map[string]string // Hashes of the records made from deltas, by delta id
+	text     *Rope             // Runes which have not been removed, as of the last call to Text
+	changed  bool              // Whether records have been integrated since the last call to Text
+}
+
+func NewSequence() *Sequence {
+	return &Sequence{
+		elements: make(map[elementKey]*element),
+		root:     &element{},
+		held:     make(map[elementKey][]*element),
+		removed:  make(map[elementKey][]string),
+		restored: make(map[elementKey]map[string]string),
+		records:  make(map[string]bool),
+		aliases:  make(map[string]string),
+		clocks:   make(map[string]uint64),
+		sources:  make(map[string]string),
+		deltas:   make(map[string]string),
+		text:     &Rope{},
+	}
+}
+
+// Has returns true if the record with the given hash has been integrated.
+func (s *Sequence) Has(hash []byte) bool {
+	return s.records[string(hash)]
+}
+
+// DeltaRecord returns the hash of the integrated record made from the delta with the given id, or false if there is none.
+func (s *Sequence) DeltaRecord(id string) ([]byte, bool) {
+	record, ok := s.deltas[id]
+	return []byte(record), ok
+}
+
+// Source returns the id of the delta the record with the given hash was made from, or an empty string if it was written as an edit.
+// Records migrated from deltas are mined by whoever migrated them, so the creator of the delta is the author of the edit.
+func (s *Sequence) Source(hash []byte) string {
+	return s.sources[s.identity(string(hash))]
+}
+
+// Len returns the number of records integrated, counting those made from the same delta once.
+func (s *Sequence) Len() int {
+	return len(s.clocks)
+}
+
+// Integrate applies the given edit, held by the record with the given hash.
+// Insertions after runes which are not yet known are held until those runes are integrated.
+// An edit made from a delta holds its runes under the hash of the delta, rather than of the record, so edits made from the same delta, such as by collaborators migrating a file at once, hold the same runes, and only the first is applied.
+func (s *Sequence) Integrate(hash []byte, edit *SequenceEdit) {
+	record := string(hash)
+	if s.records[record] {
+		return
+	}
+	s.records[record] = true
+	if len(edit.Delta) > 0 {
+		id := base64.RawURLEncoding.EncodeToString(edit.Delta)
+		source := string(edit.Delta)
+		s.aliases[record] = source
+		if _, ok := s.deltas[id]; ok {
+			return
+		}
+		record = source
+		s.records[record] = true
+		s.sources[record] = id
+		s.deltas[id] = record
+	}
+	s.changed = true
+	s.clocks[record] = edit.Clock
+	if edit.Clock > s.Clock {
+		s.Clock = edit.Clock
+	}
+	var index uint32
+	for _, insert := range edit.Insert {
+		parent := rootKey
+		if insert.After != nil {
+			parent = s.key(record, insert.After.Record, insert.After.Index)
+		}
+		for _, r := range insert.Text {
+			k := elementKey{record, index}
+			el := &element{
+				key:      k,
+				rune:     r,
+				clock:    edit.Clock,
+				children: s.held[k],
+				visible:  s.isVisible(k),
+			}
+			delete(s.held, k)
+			s.elements[k] = el
+			s.addChild(parent, el)
+			parent = k
+			index++
+		}
+	}
+	for _, remove := range edit.Remove {
+		for i := remove.Start; i < remove.End; i++ {
+			k := s.key(record, remove.Record, i)
+			s.removed[k] = append(s.removed[k], record)
+			s.updateVisible(k)
+		}
+	}
+	for _, restore := range edit.Restore {
+		for i := restore.Start; i < restore.End; i++ {
+			k := s.key(record, restore.Record, i)
+			restored, ok := s.restored[k]
+			if !ok {
+				restored = make(map[string]string)
+				s.restored[k] = restored
+			}
+			restored[s.identity(string(restore.Remover))] = record
+			s.updateVisible(k)
+		}
+	}
+}
+
+// IntegrateDeltas converts the given deltas which no integrated record was made from, such as those written by editors which had not seen the migration, into edits, and integrates them, returning their ids.
+// Each delta is applied to the text its author saw, that made by the records converted from the deltas it was written after, so every replica converts it into the same edit, held under the hash of the delta record.
+func (s *Sequence) IntegrateDeltas(order []string, parents map[string][]string, deltas map[string]*labgo.Delta) []string {
+	var ids []string
+	for _, id := range order {
+		delta, ok := deltas[id]
+		if _, converted := s.deltas[id]; !ok || converted {
+			continue
+		}
+		hash, err := base64.RawURLEncoding.DecodeString(id)
+		if err != nil {
+			log.Println(err)
+			continue
+		}
+		ancestors := Ancestors(parents, id)
+		// Runes the author saw are those inserted, and not removed, by the deltas before it
+		var keys []elementKey
+		var text []byte
+		s.walk(func(el *element) bool {
+			if !ancestors[s.sources[el.key.record]] {
+				return true
+			}
+			for _, r := range s.removed[el.key] {
+				if ancestors[s.sources[r]] {
+					return true
+				}
+			}
+			keys = append(keys, el.key)
+			text = append(text, string(el.rune)...)
+			return true
+		})
+		var clock uint64
+		for record, source := range s.sources {
+			if ancestors[source] && s.clocks[record] > clock {
+				clock = s.clocks[record]
+			}
+		}
+		edit := &SequenceEdit{
+			Clock: clock + 1,
+			Delta: hash,
+		}
+		length := uint64(len(text))
+		start := delta.Offset
+		if start > length {
+			start = length
+		}
+		end := start + uint64(len(delta.Remove))
+		if end > length {
+			end = length
+		}
+		s.addSpan(edit, keys, ByteToRuneOffset(text, start), uint64(utf8.RuneCount(text[start:end])), string(delta.Add))
+		s.Integrate(hash, edit)
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// key returns the key of the given rune, where an empty hash refers to the given record.
+func (s *Sequence) key(record string, hash []byte, index uint32) elementKey {
+	if len(hash) > 0 {
+		record = s.identity(string(hash))
+	}
+	return elementKey{record, index}
+}
+
+// identity returns the hash the record with the given hash holds its runes under, which is that of the delta it was made from, if any.
+func (s *Sequence) identity(record string) string {
+	if source, ok := s.aliases[record]; ok {
+		return source
+	}
+	return record
+}
+
+// addChild inserts the given rune among the children of the given parent, keeping them latest first, or holds it until the parent is integrated.
+func (s *Sequence) addChild(parent elementKey, el *element) {
+	var children []*element
+	p, ok := s.elements[parent]
+	if parent == rootKey {
+		p, ok = s.root, true
+	}
+	if ok {
+		children = p.children
+	} else {
+		children = s.held[parent]
+	}
+	i := 0
+	for i < len(children) && before(children[i], el) {
+		i++
+	}
+	children = append(children, nil)
+	copy(children[i+1:], children[i:])
+	children[i] = el
+	if ok {
+		p.children = children
+	} else {
+		s.held[parent] = children
+	}
+}
+
+// before returns true if a comes before b among runes inserted after the same rune.
+func before(a, b *element) bool {
+	if a.clock != b.clock {
+		return a.clock > b.clock
+	}
+	if a.key.record != b.key.record {
+		return a.key.record > b.key.record
+	}
+	return a.key.index > b.key.index
+}
+
+// walk calls the given function with every rune in order, including those removed, until the function returns false.
+func (s *Sequence) walk(f func(*element) bool) {
+	var stack []*element
+	push := func(parent *element) {
+		for i := len(parent.children) - 1; i >= 0; i-- {
+			stack = append(stack, parent.children[i])
+		}
+	}
+	push(s.root)
+	for len(stack) > 0 {
+		el := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if !f(el) {
+			return
+		}
+		push(el)
+	}
+}
+
+// updateVisible updates whether the given rune, if integrated, is visible, once its removals have changed.
+func (s *Sequence) updateVisible(k elementKey) {
+	if el, ok := s.elements[k]; ok {
+		el.visible = s.isVisible(k)
+	}
+}
+
+// isVisible returns true if every removal of the given rune has been cancelled.
+func (s *Sequence) isVisible(k elementKey) bool {
+	for _, r := range s.removed[k] {
+		if _, ok := s.restored[k][r]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// restorable returns true if the given rune was not inserted by the given records, and cancelling the removals made by the records would show it again.
+func (s *Sequence) restorable(k elementKey, records map[string]bool) bool {
+	if records[k.record] {
+		return false
+	}
+	removed := false
+	for _, r := range s.removed[k] {
+		if _, ok := s.restored[k][r]; ok {
+			continue
+		}
+		if !records[r] {
+			return false
+		}
+		removed = true
+	}
+	return removed
+}
+
+// restoredBy returns true if any of the given records cancelled a removal of the given rune.
+func (s *Sequence) restoredBy(k elementKey, records map[string]bool) bool {
+	for _, r := range s.restored[k] {
+		if records[r] {
+			return true
+		}
+	}
+	return false
+}
+
+// visible returns the keys of the runes which have not been removed, in order.
+func (s *Sequence) visible() []elementKey {
+	var keys []elementKey
+	s.walk(func(el *element) bool {
+		if el.visible {
+			keys = append(keys, el.key)
+		}
+		return true
+	})
+	return keys
+}
+
+// Runes returns the runes which have not been removed, in order.
+func (s *Sequence) Runes() []rune {
+	return s.Text().Runes()
+}
+
+// Text returns the runes which have not been removed, as a rope changed only where records integrated since the last call inserted or removed runes.
+// The rope is kept by the sequence, so must be copied before it is modified.
+func (s *Sequence) Text() *Rope {
+	if !s.changed {
+		return s.text
+	}
+	// Runes to insert, or the number to remove, at the offset
+	var offset, remove int
+	var insert []rune
+	flush := func() {
+		s.text.Remove(offset, offset+remove)
+		s.text.Insert(offset, insert)
+		offset += len(insert)
+		remove = 0
+		insert = nil
+	}
+	s.walk(func(el *element) bool {
+		switch {
+		case el.shown && el.visible:
+			flush()
+			offset++
+		case el.shown:
+			if len(insert) > 0 {
+				flush()
+			}
+			remove++
+			el.shown = false
+		case el.visible:
+			if remove > 0 {
+				flush()
+			}
+			insert = append(insert, el.rune)
+			el.shown = true
+		}
+		return true
+	})
+	flush()
+	s.changed = false
+	return s.text
+}
+
+// Records returns the hash of the record which inserted each rune which has not been removed, or of the delta the record was made from, in order.
+func (s *Sequence) Records() [][]byte {
+	var records [][]byte
+	s.walk(func(el *element) bool {
+		if el.visible {
+			records = append(records, []byte(el.key.record))
+		}
+		return true
+	})
+	return records
+}
+
+// Revert returns the spans, in bytes of the text, which revert the records with the given hashes, by removing the runes they inserted or restored, and inserting again those they removed beside the runes they were removed from.
+// Runes also removed by other records are left removed.
+func (s *Sequence) Revert(hashes [][]byte) []*Span {
+	records := s.recordSet(hashes)
+	var spans []*Span
+	var offset uint64
+	// add extends the last span if it ends at the given offset, or adds a new span
+	add := func() *Span {
+		if last := len(spans) - 1; last >= 0 && spans[last].Offset+spans[last].Remove == offset {
+			return spans[last]
+		}
+		span := &Span{
+			Offset: offset,
+		}
+		spans = append(spans, span)
+		return span
+	}
+	s.walk(func(el *element) bool {
+		k, r := el.key, el.rune
+		if el.visible {
+			if records[k.record] || s.restoredBy(k, records) {
+				add().Remove += uint64(utf8.RuneLen(r))
+			}
+			offset += uint64(utf8.RuneLen(r))
+			return true
+		}
+		if s.restorable(k, records) {
+			span := add()
+			span.Add = append(span.Add, string(r)...)
+		}
+		return true
+	})
+	return spans
+}
+
+// Restore replaces the insertion made by the given edit, if its text is that of the runes, removed by the records with the given hashes, between the rune it is inserted after and the next rune left in place, with the cancellation of those removals.
+// Reverting a removal so shows the removed runes again, rather than copies of them, so the records which inserted them can still be reverted.
+// The edit must have been made by Edit.
+func (s *Sequence) Restore(edit *SequenceEdit, hashes [][]byte) {
+	if len(edit.Insert) != 1 {
+		return
+	}
+	records := s.recordSet(hashes)
+	insert := edit.Insert[0]
+	anchor := rootKey
+	if insert.After != nil {
+		anchor = s.key("", insert.After.Record, insert.After.Index)
+	}
+	removing := make(map[elementKey]bool)
+	for _, remove := range edit.Remove {
+		for i := remove.Start; i < remove.End; i++ {
+			removing[elementKey{string(remove.Record), i}] = true
+		}
+	}
+	var keys []elementKey
+	var text []rune
+	found := anchor == rootKey
+	s.walk(func(el *element) bool {
+		if !found {
+			found = el.key == anchor
+			return true
+		}
+		if el.visible && !removing[el.key] {
+			return false
+		}
+		if s.restorable(el.key, records) {
+			keys = append(keys, el.key)
+			text = append(text, el.rune)
+		}
+		return true
+	})
+	if len(keys) == 0 || string(text) != insert.Text {
+		return
+	}
+	edit.Insert = nil
+	for _, k := range keys {
+		for _, r := range s.removed[k] {
+			if _, ok := s.restored[k][r]; ok {
+				continue
+			}
+			if last := len(edit.Restore) - 1; last >= 0 && string(edit.Restore[last].Record) == k.record && edit.Restore[last].End == k.index && string(edit.Restore[last].Remover) == r {
+				edit.Restore[last].End++
+			} else {
+				edit.Restore = append(edit.Restore, &SequenceRestore{
+					Record:  []byte(k.record),
+					Start:   k.index,
+					End:     k.index + 1,
+					Remover: []byte(r),
+				})
+			}
+		}
+	}
+}
+
+// recordSet returns the set of the hashes the records with the given hashes hold their runes under.
+func (s *Sequence) recordSet(hashes [][]byte) map[string]bool {
+	records := make(map[string]bool, len(hashes))
+	for _, h := range hashes {
+		records[s.identity(string(h))] = true
+	}
+	return records
+}
+
+// anchor returns the key of the rune before the given offset, which stays in place as the sequence changes around it.
+func (s *Sequence) anchor(offset uint64) elementKey {
+	if offset == 0 {
+		return rootKey
+	}
+	keys := s.visible()
+	if length := uint64(len(keys)); offset > length {
+		offset = length
+	}
+	if offset == 0 {
+		return rootKey
+	}
+	return keys[offset-1]
+}
+
+// offset returns the offset after the given anchor, or zero if the anchor is unknown.
+func (s *Sequence) offset(anchor elementKey) uint64 {
+	if anchor == rootKey {
+		return 0
+	}
+	var count uint64
+	found := false
+	s.walk(func(el *element) bool {
+		if el.visible {
+			count++
+		}
+		found = el.key == anchor
+		return !found
+	})
+	if !found {
+		return 0
+	}
+	return count
+}
+
+// HasElement returns true if the given rune has been integrated, or is unset, referring to the start of the text.
+func (s *Sequence) HasElement(id *ElementId) bool {
+	if id == nil {
+		return true
+	}
+	_, ok := s.elements[s.key("", id.Record, id.Index)]
+	return ok
+}
+
+// elementAnchor returns the id of the rune before the given offset, or nil at the start of the text.
+func (s *Sequence) elementAnchor(offset uint64) *ElementId {
+	k := s.anchor(offset)
+	if k == rootKey {
+		return nil
+	}
+	return &ElementId{
+		Record: []byte(k.record),
+		Index:  k.index,
+	}
+}
+
+// elementOffset returns the offset after the given rune, or zero if it is unset or unknown.
+func (s *Sequence) elementOffset(id *ElementId) uint64 {
+	if id == nil {
+		return 0
+	}
+	return s.offset(s.key("", id.Record, id.Index))
+}
+
+// Edit returns an edit which removes the given number of runes at the given offset, and inserts the given text in their place.
+// The edit is not integrated, as that requires the hash of the record holding it.
+func (s *Sequence) Edit(offset, remove uint64, add string) *SequenceEdit {
+	edit := &SequenceEdit{
+		Clock: s.Clock + 1,
+	}
+	s.addSpan(edit, s.visible(), offset, remove, add)
+	return edit
+}
+
+// addSpan adds to the given edit the removal of the given number of the given runes at the given offset, and the insertion of the given text in their place.
+func (s *Sequence) addSpan(edit *SequenceEdit, keys []elementKey, offset, remove uint64, add string) {
+	length := uint64(len(keys))
+	if offset > length {
+		offset = length
+	}
+	end := offset + remove
+	if end > length {
+		end = length
+	}
+	if add != "" {
+		insert := &SequenceInsert{
+			Text: add,
+		}
+		if offset > 0 {
+			after := keys[offset-1]
+			insert.After = &ElementId{
+				Record: []byte(after.record),
+				Index:  after.index,
+			}
+		}
+		edit.Insert = append(edit.Insert, insert)
+	}
+	for _, k := range keys[offset:end] {
+		if last := len(edit.Remove) - 1; last >= 0 && string(edit.Remove[last].Record) == k.record && edit.Remove[last].End == k.index {
+			edit.Remove[last].End++
+		} else {
+			edit.Remove = append(edit.Remove, &SequenceRemove{
+				Record: []byte(k.record),
+				Start:  k.index,
+				End:    k.index + 1,
+			})
+		}
+	}
+}
+
+// DeltasToSequence replays the given deltas into a new Sequence, passing the edit made from each delta to the given callback, with the id of the delta, and the callback returns the hash of the record holding the edit.
+func DeltasToSequence(channel string, entries map[string]*bcgo.BlockEntry, deltas map[string]*labgo.Delta, callback func(string, *SequenceEdit) ([]byte, error)) (*Sequence, error) {
+	order := OrderDeltas(channel, entries)
+	_, executed := ReplayDeltas(order, Parents(channel, entries), deltas)
+	sequence := NewSequence()
+	for _, id := range order {
+		hash, err := base64.RawURLEncoding.DecodeString(id)
+		if err != nil {
+			return nil, err
+		}
+		edit := &SequenceEdit{
+			Clock: sequence.Clock + 1,
+			Delta: hash,
+		}
+		// Spans are in offsets of the buffer before the delta, so are all made against the same runes
+		keys := sequence.visible()
+		text := sequence.Text()
+		spans := sortSpans(executed[id])
+		for i := len(spans) - 1; i >= 0; i-- {
+			span := spans[i]
+			start := text.ByteToRune(int(span.Offset))
+			end := text.ByteToRune(int(span.Offset + span.Remove))
+			sequence.addSpan(edit, keys, uint64(start), uint64(end-start), string(span.Add))
+		}
+		record, err := callback(id, edit)
+		if err != nil {
+			return nil, err
+		}
+		sequence.Integrate(record, edit)
+	}
+	return sequence, nil
+}
+
+// MigrateDeltas replays the labgo.Deltas on the given channel into records of type TYPE_SEQUENCE, mines them onto the channel, and returns how many were written.
+// Channels which already hold records of type TYPE_SEQUENCE are left unchanged, and records written by collaborators migrating the channel at once hold the same runes, so the text is not repeated.
+func MigrateDeltas(node *bcgo.Node, listener bcgo.MiningListener, channel *bcgo.Channel) (int, error) {
+	entries := make(map[string]*bcgo.BlockEntry)
+	deltas := make(map[string]*labgo.Delta)
+	migrated := false
+	if err := bcgo.Read(channel.Name, channel.Head, nil, node.Cache, node.Network, "", nil, nil, func(entry *bcgo.BlockEntry, key, data []byte) error {
+		if entry.Record.Meta[META_TYPE] == TYPE_SEQUENCE {
+			migrated = true
+			return bcgo.StopIterationError{}
+		}
+		if entry.Record.Meta[META_TYPE] == TYPE_CHECKPOINT {
+			return nil
+		}
+		// Unmarshal as Delta
+		delta := &labgo.Delta{}
+		if err := proto.Unmarshal(data, delta); err != nil {
+			return err
+		}
+		id := base64.RawURLEncoding.EncodeToString(entry.RecordHash)
+		deltas[id] = delta
+		entries[id] = entry
+		return nil
+	}); err != nil {
+		if _, ok := err.(bcgo.StopIterationError); !ok {
+			return 0, err
+		}
+	}
+	if migrated || len(deltas) == 0 {
+		return 0, nil
+	}
+	var records []*bcgo.BlockEntry
+	if _, err := DeltasToSequence(channel.Name, entries, deltas, func(id string, edit *SequenceEdit) ([]byte, error) {
+		// Keep the time of the original edit, the edit names the delta it came from, and so its author
+		hash, record, err := ProtoToRecord(node.Alias, node.Key, entries[id].Record.Timestamp, nil, map[string]string{
+			META_TYPE: TYPE_SEQUENCE,
+		}, edit)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, &bcgo.BlockEntry{
+			RecordHash: hash,
+			Record:     record,
+		})
+		return hash, nil
+	}); err != nil {
+		return 0, err
+	}
+	if err := Mine(node, listener, channel, records...); err != nil {
+		return 0, err
+	}
+	return len(records), nil
+}
@@ -0,0 +1,313 @@
+/*
+ * Copyright 2020 Aletheia Ware LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package edit_test
+
+import (
+	"fmt"
+	"github.com/AletheiaWareLLC/bcgo"
+	"github.com/AletheiaWareLLC/labfynego/ui/edit"
+	"github.com/AletheiaWareLLC/labgo"
+	"github.com/golang/protobuf/proto"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type sequenceEdit struct {
+	hash []byte
+	edit *edit.SequenceEdit
+}
+
+// write makes an edit to the given sequence, integrates it, and returns it.
+func write(s *edit.Sequence, hash string, offset, remove uint64, add string) *sequenceEdit {
+	e := &sequenceEdit{[]byte(hash), s.Edit(offset, remove, add)}
+	s.Integrate(e.hash, e.edit)
+	return e
+}
+
+// restore makes an edit to the given sequence, restoring the runes removed by the given records, integrates it, and returns it.
+func restore(s *edit.Sequence, hash string, offset, remove uint64, add string, reverted ...string) *sequenceEdit {
+	var hashes [][]byte
+	for _, r := range reverted {
+		hashes = append(hashes, []byte(r))
+	}
+	e := &sequenceEdit{[]byte(hash), s.Edit(offset, remove, add)}
+	s.Restore(e.edit, hashes)
+	s.Integrate(e.hash, e.edit)
+	return e
+}
+
+func integrate(s *edit.Sequence, edits ...*sequenceEdit) {
+	for _, e := range edits {
+		s.Integrate(e.hash, e.edit)
+	}
+}
+
+func TestSequence(t *testing.T) {
+	for name, tt := range map[string]struct {
+		edits func() []*sequenceEdit
+		want  string
+	}{
+		"typing": {
+			edits: func() []*sequenceEdit {
+				s := edit.NewSequence()
+				return []*sequenceEdit{
+					write(s, "a", 0, 0, "Hello"),
+					write(s, "b", 5, 0, " World"),
+					write(s, "c", 5, 0, ","),
+					write(s, "d", 7, 5, "Grüße"),
+				}
+			},
+			want: "Hello, Grüße",
+		},
+		"concurrent_inserts": {
+			edits: func() []*sequenceEdit {
+				alice := edit.NewSequence()
+				a := write(alice, "a", 0, 0, "Hello World")
+				bob := edit.NewSequence()
+				integrate(bob, a)
+				b := write(alice, "b", 5, 0, ",")
+				c := write(bob, "c", 5, 0, " there")
+				d := write(bob, "d", 17, 0, "!")
+				return []*sequenceEdit{a, b, c, d}
+			},
+			want: "Hello there, World!",
+		},
+		"insert_within_removal": {
+			edits: func() []*sequenceEdit {
+				alice := edit.NewSequence()
+				a := write(alice, "a", 0, 0, "Hello World")
+				bob := edit.NewSequence()
+				integrate(bob, a)
+				b := write(alice, "b", 6, 5, "")
+				c := write(bob, "c", 8, 0, "Big ")
+				return []*sequenceEdit{a, b, c}
+			},
+			want: "Hello Big ",
+		},
+		"insert_after_seen": {
+			edits: func() []*sequenceEdit {
+				alice := edit.NewSequence()
+				a := write(alice, "a", 0, 0, "ac")
+				b := write(alice, "b", 1, 0, "b")
+				bob := edit.NewSequence()
+				integrate(bob, a, b)
+				c := write(bob, "c", 1, 0, "_")
+				return []*sequenceEdit{a, b, c}
+			},
+			want: "a_bc",
+		},
+		"restore_concurrent_removal": {
+			edits: func() []*sequenceEdit {
+				alice := edit.NewSequence()
+				a := write(alice, "a", 0, 0, "Hello World")
+				bob := edit.NewSequence()
+				integrate(bob, a)
+				b := write(alice, "b", 6, 5, "")
+				c := restore(alice, "c", 6, 0, "World", "b")
+				d := write(bob, "d", 6, 3, "")
+				return []*sequenceEdit{a, b, c, d}
+			},
+			want: "Hello ld",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			edits := tt.edits()
+			// Integrate in every rotation of the edits, including before the runes they refer to
+			for i := range edits {
+				s := edit.NewSequence()
+				integrate(s, edits[i:]...)
+				integrate(s, edits[:i]...)
+				if got := string(s.Runes()); got != tt.want {
+					t.Fatalf("Incorrect text from rotation %d; expected '%s', got '%s'", i, tt.want, got)
+				}
+			}
+		})
+	}
+}
+
+func TestSequenceEdit_Marshal(t *testing.T) {
+	s := edit.NewSequence()
+	write(s, "a", 0, 0, "Hello World")
+	want := s.Edit(5, 6, "!")
+	data, err := proto.Marshal(want)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := &edit.SequenceEdit{}
+	if err := proto.Unmarshal(data, got); err != nil {
+		t.Fatal(err)
+	}
+	if !proto.Equal(got, want) {
+		t.Errorf("Incorrect edit; expected '%v', got '%v'", want, got)
+	}
+}
+
+func TestDeltasToSequence(t *testing.T) {
+	entries := make(map[string]*bcgo.BlockEntry)
+	deltas := make(map[string]*labgo.Delta)
+	makeDelta(t, entries, deltas, "a", "alice", 10, &labgo.Delta{Add: []byte("Hello World")})
+	makeDelta(t, entries, deltas, "b", "alice", 20, &labgo.Delta{Offset: 5, Add: []byte(",")}, "a")
+	makeDelta(t, entries, deltas, "c", "bob", 20, &labgo.Delta{Offset: 6, Remove: []byte("World"), Add: []byte("世界")}, "a")
+	makeDelta(t, entries, deltas, "d", "bob", 30, &labgo.Delta{Offset: 0, Remove: []byte("Hello"), Add: []byte("Grüße")}, "b", "c")
+	order := edit.OrderDeltas(testChannel, entries)
+	want, _ := edit.ReplayDeltas(order, edit.Parents(testChannel, entries), deltas)
+
+	var ids []string
+	var records [][]byte
+	s, err := edit.DeltasToSequence(testChannel, entries, deltas, func(id string, e *edit.SequenceEdit) ([]byte, error) {
+		ids = append(ids, id)
+		record := []byte(fmt.Sprintf("record%d", len(records)))
+		records = append(records, record)
+		return record, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := string(s.Runes()); got != string(want) {
+		t.Errorf("Incorrect text; expected '%s', got '%s'", want, got)
+	}
+	if !reflect.DeepEqual(ids, order) {
+		t.Errorf("Incorrect ids; expected '%v', got '%v'", order, ids)
+	}
+	for i, record := range records {
+		if got := s.Source(record); got != ids[i] {
+			t.Errorf("Incorrect source; expected '%s', got '%s'", ids[i], got)
+		}
+		if _, ok := s.DeltaRecord(ids[i]); !ok {
+			t.Errorf("Expected delta '%s' to be held", ids[i])
+		}
+	}
+}
+
+func TestDeltasToSequence_Concurrent(t *testing.T) {
+	entries := make(map[string]*bcgo.BlockEntry)
+	deltas := make(map[string]*labgo.Delta)
+	makeDelta(t, entries, deltas, "a", "alice", 10, &labgo.Delta{Add: []byte("Hello")})
+	makeDelta(t, entries, deltas, "b", "alice", 20, &labgo.Delta{Offset: 5, Add: []byte(" World")}, "a")
+	// Alice and Bob both migrate the deltas, into records with different hashes
+	var migrations [][]*sequenceEdit
+	for _, migrator := range []string{"alice", "bob"} {
+		var migrated []*sequenceEdit
+		if _, err := edit.DeltasToSequence(testChannel, entries, deltas, func(id string, e *edit.SequenceEdit) ([]byte, error) {
+			record := []byte(fmt.Sprintf("%s%d", migrator, len(migrated)))
+			migrated = append(migrated, &sequenceEdit{record, e})
+			return record, nil
+		}); err != nil {
+			t.Fatal(err)
+		}
+		migrations = append(migrations, migrated)
+	}
+	alice := edit.NewSequence()
+	integrate(alice, migrations[0]...)
+	// Alice edits the text before seeing Bob's migration
+	e := write(alice, "e", 11, 0, "!")
+	integrate(alice, migrations[1]...)
+	bob := edit.NewSequence()
+	integrate(bob, migrations[1]...)
+	integrate(bob, e)
+	integrate(bob, migrations[0]...)
+	want := "Hello World!"
+	for name, s := range map[string]*edit.Sequence{"alice": alice, "bob": bob} {
+		if got := string(s.Runes()); got != want {
+			t.Errorf("Incorrect text for %s; expected '%s', got '%s'", name, want, got)
+		}
+		if got := s.Len(); got != 3 {
+			t.Errorf("Incorrect length for %s; expected '%d', got '%d'", name, 3, got)
+		}
+		// Either migration names the delta it came from
+		if got := s.Source([]byte("bob1")); got != id("b") {
+			t.Errorf("Incorrect source for %s; expected '%s', got '%s'", name, id("b"), got)
+		}
+	}
+}
+
+func TestSequence_IntegrateDeltas(t *testing.T) {
+	entries := make(map[string]*bcgo.BlockEntry)
+	deltas := make(map[string]*labgo.Delta)
+	makeDelta(t, entries, deltas, "a", "alice", 10, &labgo.Delta{Add: []byte("Hello World")})
+	makeDelta(t, entries, deltas, "b", "alice", 20, &labgo.Delta{Offset: 5, Add: []byte(",")}, "a")
+	// Migrate the deltas written so far
+	var migrated []*sequenceEdit
+	if _, err := edit.DeltasToSequence(testChannel, entries, deltas, func(id string, e *edit.SequenceEdit) ([]byte, error) {
+		record := []byte(fmt.Sprintf("record%d", len(migrated)))
+		migrated = append(migrated, &sequenceEdit{record, e})
+		return record, nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	alice := edit.NewSequence()
+	integrate(alice, migrated...)
+	// Alice adds to the migrated text
+	e := write(alice, "e", 12, 0, "!")
+	// Bob had not seen the migration, nor Alice's comma
+	makeDelta(t, entries, deltas, "c", "bob", 20, &labgo.Delta{Offset: 6, Remove: []byte("World"), Add: []byte("世界")}, "a")
+	makeDelta(t, entries, deltas, "d", "bob", 30, &labgo.Delta{Offset: 0, Remove: []byte("Hello"), Add: []byte("Grüße")}, "c")
+	order := edit.OrderDeltas(testChannel, entries)
+	parents := edit.Parents(testChannel, entries)
+	if got, want := alice.IntegrateDeltas(order, parents, deltas), []string{id("c"), id("d")}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Incorrect ids; expected '%v', got '%v'", want, got)
+	}
+	// Converted deltas are only integrated once
+	if got := alice.IntegrateDeltas(order, parents, deltas); len(got) != 0 {
+		t.Errorf("Incorrect ids; expected none, got '%v'", got)
+	}
+	want := "Grüße, 世界!"
+	if got := string(alice.Runes()); got != want {
+		t.Errorf("Incorrect text; expected '%s', got '%s'", want, got)
+	}
+	if got := alice.Source([]byte("c")); got != id("c") {
+		t.Errorf("Incorrect source; expected '%s', got '%s'", id("c"), got)
+	}
+	if got := alice.Source(e.hash); got != "" {
+		t.Errorf("Incorrect source; expected none, got '%s'", got)
+	}
+	// Converting the deltas before Alice's edit gives the same text
+	bob := edit.NewSequence()
+	integrate(bob, migrated...)
+	bob.IntegrateDeltas(order, parents, deltas)
+	integrate(bob, e)
+	if got := string(bob.Runes()); got != want {
+		t.Errorf("Incorrect text; expected '%s', got '%s'", want, got)
+	}
+}
+
+// BenchmarkSequence_Integrate measures integrating an edit from a collaborator into a large file, and updating its text.
+func BenchmarkSequence_Integrate(b *testing.B) {
+	text := strings.Repeat("Grüße, 世界!\n", 10000)
+	s := edit.NewSequence()
+	s.Integrate([]byte("base"), &edit.SequenceEdit{
+		Clock: 1,
+		Insert: []*edit.SequenceInsert{
+			{Text: text},
+		},
+	})
+	s.Text()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		s.Integrate([]byte(fmt.Sprintf("e%d", i)), &edit.SequenceEdit{
+			Clock: uint64(i + 2),
+			Insert: []*edit.SequenceInsert{
+				{
+					After: &edit.ElementId{Record: []byte("base"), Index: uint32(len(text) / 4)},
+					Text:  "x",
+				},
+			},
+		})
+		s.Text()
+	}
+}
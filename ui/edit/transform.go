@@ -0,0 +1,187 @@
+/*
+ * Copyright 2020 Aletheia Ware LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package edit
+
+import (
+	"github.com/AletheiaWareLLC/labgo"
+	"sort"
+)
+
+// Span replaces Remove bytes at Offset with Add.
+type Span struct {
+	Offset uint64
+	Remove uint64
+	Add    []byte
+}
+
+// DeltaToSpan returns the span changed by the given delta.
+func DeltaToSpan(delta *labgo.Delta) *Span {
+	return &Span{
+		Offset: delta.Offset,
+		Remove: uint64(len(delta.Remove)),
+		Add:    delta.Add,
+	}
+}
+
+// TransformSpan returns the spans which make the change intended by a once b has been applied.
+// Both a and b must apply to the same buffer. If b was applied within the region removed by a, the region is split to keep the text added by b.
+func TransformSpan(a, b *Span) []*Span {
+	aEnd := a.Offset + a.Remove
+	bEnd := b.Offset + b.Remove
+	bAdd := uint64(len(b.Add))
+	if bEnd <= a.Offset {
+		// b is before a, including insertions at the same offset, so shift a
+		return []*Span{
+			&Span{
+				Offset: a.Offset + bAdd - b.Remove,
+				Remove: a.Remove,
+				Add:    a.Add,
+			},
+		}
+	}
+	if aEnd <= b.Offset {
+		// a is before b
+		return []*Span{a}
+	}
+	// a and b overlap, remove what is left of a either side of b
+	var spans []*Span
+	if a.Offset < b.Offset {
+		spans = append(spans, &Span{
+			Offset: a.Offset,
+			Remove: b.Offset - a.Offset,
+			Add:    a.Add,
+		})
+	}
+	start := a.Offset
+	if start < bEnd {
+		start = bEnd
+	}
+	right := &Span{
+		Offset: b.Offset + bAdd + start - bEnd,
+	}
+	if aEnd > bEnd {
+		right.Remove = aEnd - bEnd
+	}
+	if len(spans) == 0 {
+		right.Add = a.Add
+	}
+	if right.Remove > 0 || len(right.Add) > 0 {
+		spans = append(spans, right)
+	}
+	return spans
+}
+
+// TransformSpans returns the spans which make the changes intended by a once b has been applied.
+// The spans in a must not overlap, nor those in b, and both must apply to the same buffer.
+func TransformSpans(a, b []*Span) []*Span {
+	for _, s := range sortSpans(b) {
+		var result []*Span
+		for _, t := range a {
+			result = append(result, TransformSpan(t, s)...)
+		}
+		a = result
+	}
+	return a
+}
+
+// TransformOffset returns the given offset moved to the same place in the buffer once the given spans have been applied.
+// An offset within a removed region moves to the end of the text which replaced it.
+func TransformOffset(offset uint64, spans []*Span) uint64 {
+	for _, s := range sortSpans(spans) {
+		if end := s.Offset + s.Remove; end <= offset {
+			offset = offset + uint64(len(s.Add)) - s.Remove
+		} else if s.Offset < offset {
+			offset = s.Offset + uint64(len(s.Add))
+		}
+	}
+	return offset
+}
+
+// ApplySpans returns the given buffer with the given non-overlapping spans applied.
+func ApplySpans(spans []*Span, buffer []byte) []byte {
+	for _, s := range sortSpans(spans) {
+		buffer = labgo.DeltaToBuffer(&labgo.Delta{
+			Offset: s.Offset,
+			Remove: make([]byte, s.Remove),
+			Add:    s.Add,
+		}, buffer)
+	}
+	return buffer
+}
+
+// ApplySpansToRope applies the given non-overlapping spans, in bytes of its text, to the given rope.
+func ApplySpansToRope(spans []*Span, rope *Rope) {
+	for _, s := range sortSpans(spans) {
+		start := rope.ByteToRune(int(s.Offset))
+		rope.Remove(start, rope.ByteToRune(int(s.Offset+s.Remove)))
+		rope.Insert(start, []rune(string(s.Add)))
+	}
+}
+
+// sortSpans returns a copy of the given spans ordered from the end of the buffer to the start, so applying each leaves the offsets of the rest unchanged.
+func sortSpans(spans []*Span) []*Span {
+	sorted := append([]*Span{}, spans...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].Offset != sorted[j].Offset {
+			return sorted[i].Offset > sorted[j].Offset
+		}
+		// Remove before inserting at the same offset
+		return sorted[i].Remove > sorted[j].Remove
+	})
+	return sorted
+}
+
+// Ancestors returns the set of ids which the given id was written after, directly or indirectly.
+func Ancestors(parents map[string][]string, id string) map[string]bool {
+	ancestors := make(map[string]bool)
+	queue := []string{id}
+	for len(queue) > 0 {
+		for _, p := range parents[queue[0]] {
+			if !ancestors[p] {
+				ancestors[p] = true
+				queue = append(queue, p)
+			}
+		}
+		queue = queue[1:]
+	}
+	return ancestors
+}
+
+// Heads returns the ids, in the given order, which no other id in the order was written after.
+func Heads(order []string, parents map[string][]string) []string {
+	written := make(map[string]bool)
+	for _, id := range order {
+		for _, p := range parents[id] {
+			written[p] = true
+		}
+	}
+	var heads []string
+	for _, id := range order {
+		if !written[id] {
+			heads = append(heads, id)
+		}
+	}
+	return heads
+}
+
+// ReplayDeltas applies the given deltas in the given order, and returns the resulting buffer along with the spans each delta applied.
+// Each delta not written after every delta before it is placed in the text its author saw, so that concurrent edits land where their authors intended.
+func ReplayDeltas(order []string, parents map[string][]string, deltas map[string]*labgo.Delta) ([]byte, map[string][]*Span) {
+	r := NewReplay()
+	r.Apply(order, parents, deltas)
+	return []byte(r.Buffer.String()), r.Executed
+}
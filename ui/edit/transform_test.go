@@ -0,0 +1,180 @@
+/*
+ * Copyright 2020 Aletheia Ware LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package edit_test
+
+import (
+	"github.com/AletheiaWareLLC/bcgo"
+	"github.com/AletheiaWareLLC/labfynego/ui/edit"
+	"github.com/AletheiaWareLLC/labgo"
+	"reflect"
+	"testing"
+)
+
+func TestTransformSpan(t *testing.T) {
+	for name, tt := range map[string]struct {
+		a, b *edit.Span
+		want []*edit.Span
+	}{
+		"before": {
+			a:    &edit.Span{Offset: 1, Add: []byte("x")},
+			b:    &edit.Span{Offset: 5, Remove: 2, Add: []byte("y")},
+			want: []*edit.Span{&edit.Span{Offset: 1, Add: []byte("x")}},
+		},
+		"after": {
+			a:    &edit.Span{Offset: 8, Remove: 1},
+			b:    &edit.Span{Offset: 2, Remove: 2, Add: []byte("yyy")},
+			want: []*edit.Span{&edit.Span{Offset: 9, Remove: 1}},
+		},
+		"same_insert_offset": {
+			a:    &edit.Span{Offset: 3, Add: []byte("x")},
+			b:    &edit.Span{Offset: 3, Add: []byte("yy")},
+			want: []*edit.Span{&edit.Span{Offset: 5, Add: []byte("x")}},
+		},
+		"overlapping_removals": {
+			a:    &edit.Span{Offset: 4, Remove: 5},
+			b:    &edit.Span{Offset: 3, Remove: 4},
+			want: []*edit.Span{&edit.Span{Offset: 3, Remove: 2}},
+		},
+		"insert_within_removal": {
+			a: &edit.Span{Offset: 6, Remove: 5, Add: []byte("x")},
+			b: &edit.Span{Offset: 8, Add: []byte("yy")},
+			want: []*edit.Span{
+				&edit.Span{Offset: 6, Remove: 2, Add: []byte("x")},
+				&edit.Span{Offset: 10, Remove: 3},
+			},
+		},
+		"removed_insert": {
+			a:    &edit.Span{Offset: 8, Add: []byte("x")},
+			b:    &edit.Span{Offset: 6, Remove: 5},
+			want: []*edit.Span{&edit.Span{Offset: 6, Add: []byte("x")}},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			if got := edit.TransformSpan(tt.a, tt.b); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Incorrect spans; expected '%v', got '%v'", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestReplayDeltas(t *testing.T) {
+	for name, tt := range map[string]struct {
+		deltas func(*testing.T, map[string]*bcgo.BlockEntry, map[string]*labgo.Delta)
+		want   string
+	}{
+		"sequential": {
+			deltas: func(t *testing.T, e map[string]*bcgo.BlockEntry, d map[string]*labgo.Delta) {
+				makeDelta(t, e, d, "a", "alice", 10, &labgo.Delta{Add: []byte("Hello World")})
+				makeDelta(t, e, d, "b", "bob", 20, &labgo.Delta{Offset: 5, Add: []byte(",")}, "a")
+				makeDelta(t, e, d, "c", "alice", 30, &labgo.Delta{Offset: 12, Add: []byte("!")}, "b")
+			},
+			want: "Hello, World!",
+		},
+		"legacy": {
+			deltas: func(t *testing.T, e map[string]*bcgo.BlockEntry, d map[string]*labgo.Delta) {
+				makeDelta(t, e, d, "a", "alice", 10, &labgo.Delta{Add: []byte("Hello")})
+				makeDelta(t, e, d, "b", "alice", 20, &labgo.Delta{Offset: 5, Add: []byte(" World")})
+			},
+			want: "Hello World",
+		},
+		"concurrent_inserts": {
+			deltas: func(t *testing.T, e map[string]*bcgo.BlockEntry, d map[string]*labgo.Delta) {
+				makeDelta(t, e, d, "a", "alice", 10, &labgo.Delta{Add: []byte("Hello World")})
+				makeDelta(t, e, d, "b", "alice", 20, &labgo.Delta{Offset: 5, Add: []byte(",")}, "a")
+				makeDelta(t, e, d, "c", "bob", 20, &labgo.Delta{Offset: 11, Add: []byte("!")}, "a")
+			},
+			want: "Hello, World!",
+		},
+		"concurrent_removals": {
+			deltas: func(t *testing.T, e map[string]*bcgo.BlockEntry, d map[string]*labgo.Delta) {
+				makeDelta(t, e, d, "a", "alice", 10, &labgo.Delta{Add: []byte("Hello World")})
+				makeDelta(t, e, d, "b", "alice", 20, &labgo.Delta{Offset: 3, Remove: []byte("lo W")}, "a")
+				makeDelta(t, e, d, "c", "bob", 20, &labgo.Delta{Offset: 4, Remove: []byte("o Wor")}, "a")
+			},
+			want: "Helld",
+		},
+		"insert_within_removal": {
+			deltas: func(t *testing.T, e map[string]*bcgo.BlockEntry, d map[string]*labgo.Delta) {
+				makeDelta(t, e, d, "a", "alice", 10, &labgo.Delta{Add: []byte("Hello World")})
+				makeDelta(t, e, d, "b", "alice", 20, &labgo.Delta{Offset: 8, Add: []byte("Big ")}, "a")
+				makeDelta(t, e, d, "c", "bob", 20, &labgo.Delta{Offset: 6, Remove: []byte("World")}, "a")
+			},
+			want: "Hello Big ",
+		},
+		"merge": {
+			deltas: func(t *testing.T, e map[string]*bcgo.BlockEntry, d map[string]*labgo.Delta) {
+				makeDelta(t, e, d, "a", "alice", 10, &labgo.Delta{Add: []byte("Hello World")})
+				makeDelta(t, e, d, "b", "alice", 20, &labgo.Delta{Offset: 5, Add: []byte(",")}, "a")
+				makeDelta(t, e, d, "c", "bob", 20, &labgo.Delta{Offset: 11, Add: []byte("!")}, "a")
+				makeDelta(t, e, d, "d", "bob", 30, &labgo.Delta{Offset: 7, Remove: []byte("World"), Add: []byte("Lab")}, "b", "c")
+				makeDelta(t, e, d, "e", "alice", 30, &labgo.Delta{Offset: 0, Add: []byte(">")}, "b", "c")
+			},
+			want: ">Hello, Lab!",
+		},
+		"unseen_sibling_descendant": {
+			deltas: func(t *testing.T, e map[string]*bcgo.BlockEntry, d map[string]*labgo.Delta) {
+				makeDelta(t, e, d, "a", "alice", 10, &labgo.Delta{Add: []byte("ac")})
+				makeDelta(t, e, d, "b", "alice", 20, &labgo.Delta{Offset: 0, Add: []byte("<")}, "a")
+				makeDelta(t, e, d, "c", "alice", 30, &labgo.Delta{Offset: 1, Add: []byte("<")}, "b")
+				makeDelta(t, e, d, "d", "bob", 20, &labgo.Delta{Offset: 1, Add: []byte("b")}, "a")
+			},
+			want: "<<abc",
+		},
+		"concurrent_descendant": {
+			deltas: func(t *testing.T, e map[string]*bcgo.BlockEntry, d map[string]*labgo.Delta) {
+				makeDelta(t, e, d, "a", "alice", 10, &labgo.Delta{Add: []byte("hello")})
+				makeDelta(t, e, d, "b", "aaron", 20, &labgo.Delta{Offset: 5, Add: []byte("X")}, "a")
+				makeDelta(t, e, d, "c", "alice", 20, &labgo.Delta{Offset: 0, Add: []byte("123")}, "a")
+				// Written after seeing "123hello", so lands in it rather than in the text after aaron's delta
+				makeDelta(t, e, d, "d", "alice", 30, &labgo.Delta{Offset: 6, Add: []byte("Y")}, "c")
+			},
+			want: "123helYloX",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			// Map iteration order is random, so repeat to check the result is deterministic
+			for i := 0; i < 10; i++ {
+				entries := make(map[string]*bcgo.BlockEntry)
+				deltas := make(map[string]*labgo.Delta)
+				tt.deltas(t, entries, deltas)
+				order := edit.OrderDeltas(testChannel, entries)
+				buffer, _ := edit.ReplayDeltas(order, edit.Parents(testChannel, entries), deltas)
+				if got := string(buffer); got != tt.want {
+					t.Fatalf("Incorrect buffer; expected '%s', got '%s'", tt.want, got)
+				}
+			}
+		})
+	}
+}
+
+func TestHeads(t *testing.T) {
+	entries := make(map[string]*bcgo.BlockEntry)
+	makeEntry(t, entries, "a", "alice", 10)
+	makeEntry(t, entries, "b", "alice", 20, "a")
+	makeEntry(t, entries, "c", "bob", 20, "a")
+	order := edit.OrderDeltas(testChannel, entries)
+	want := []string{id("b"), id("c")}
+	if got := edit.Heads(order, edit.Parents(testChannel, entries)); !reflect.DeepEqual(got, want) {
+		t.Errorf("Incorrect heads; expected '%v', got '%v'", want, got)
+	}
+}
+
+func makeDelta(t *testing.T, entries map[string]*bcgo.BlockEntry, deltas map[string]*labgo.Delta, hash string, creator string, timestamp uint64, delta *labgo.Delta, parents ...string) {
+	t.Helper()
+	makeEntry(t, entries, hash, creator, timestamp, parents...)
+	deltas[id(hash)] = delta
+}
@@ -17,46 +17,433 @@
 package edit
 
 import (
-	"encoding/base64"
+	"fmt"
 	"fyne.io/fyne"
+	"fyne.io/fyne/canvas"
+	"fyne.io/fyne/layout"
+	"fyne.io/fyne/theme"
 	"fyne.io/fyne/widget"
 	"github.com/AletheiaWareLLC/bcgo"
-	"github.com/AletheiaWareLLC/labgo"
-	"github.com/golang/protobuf/proto"
+	"github.com/AletheiaWareLLC/labfynego/ui/data"
+	"image/color"
 	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
 )
 
-func NewTree(paths *bcgo.Channel, cache bcgo.Cache, network bcgo.Network, callback func(id string, path ...string)) fyne.CanvasObject {
-	tree := widget.NewVBox()
+const (
+	// Width by which each level of the tree is indented
+	TREE_INDENT = 16
+	// Number of characters of its id shown after the name of each file which shares its path with another
+	TREE_ID_LENGTH = 8
+)
+
+var (
+	codeIcon  = theme.NewThemedResource(data.FileCodeIcon, nil)
+	imageIcon = theme.NewThemedResource(data.FileImageIcon, nil)
+	textIcon  = theme.NewThemedResource(data.FileTextIcon, nil)
+)
+
+// ICONS are the icons shown beside files in the tree, by the extension of their name.
+var ICONS = map[string]fyne.Resource{
+	".c":        codeIcon,
+	".go":       codeIcon,
+	".h":        codeIcon,
+	".java":     codeIcon,
+	".js":       codeIcon,
+	".json":     codeIcon,
+	".proto":    codeIcon,
+	".py":       codeIcon,
+	".sh":       codeIcon,
+	".gif":      imageIcon,
+	".jpeg":     imageIcon,
+	".jpg":      imageIcon,
+	".png":      imageIcon,
+	".svg":      imageIcon,
+	".markdown": textIcon,
+	".md":       textIcon,
+	".txt":      textIcon,
+}
+
+// IconForPath returns the icon for the file at the given path, or theme.FileIcon if there is none for its extension.
+func IconForPath(path ...string) fyne.Resource {
+	if len(path) > 0 {
+		if icon, ok := ICONS[strings.ToLower(filepath.Ext(path[len(path)-1]))]; ok {
+			return icon
+		}
+	}
+	return theme.FileIcon()
+}
+
+// TreeNode is a directory, or a file of an experiment, in a Tree.
+type TreeNode struct {
+	Name     string
+	Path     []string    // Names of the directories holding the node, and of the node
+	Id       string      // Id of the file, or empty if the node is a directory
+	Children []*TreeNode // Directories before files, each sorted by name
+}
+
+// IsDirectory returns true if the node is a directory.
+func (n *TreeNode) IsDirectory() bool {
+	return n.Id == ""
+}
+
+// Files returns the node if it is a file, otherwise the files beneath it.
+func (n *TreeNode) Files() []*TreeNode {
+	if !n.IsDirectory() {
+		return []*TreeNode{n}
+	}
+	var files []*TreeNode
+	for _, c := range n.Children {
+		files = append(files, c.Files()...)
+	}
+	return files
+}
+
+// directory returns the child directory with the given name, adding it if there is none.
+func (n *TreeNode) directory(name string) *TreeNode {
+	for _, c := range n.Children {
+		if c.IsDirectory() && c.Name == name {
+			return c
+		}
+	}
+	path := make([]string, len(n.Path), len(n.Path)+1)
+	copy(path, n.Path)
+	child := &TreeNode{
+		Name: name,
+		Path: append(path, name),
+	}
+	n.Children = append(n.Children, child)
+	return child
+}
+
+// sort orders the children of the node, and of each directory beneath it.
+func (n *TreeNode) sort() {
+	sort.Slice(n.Children, func(i, j int) bool {
+		a, b := n.Children[i], n.Children[j]
+		if a.IsDirectory() != b.IsDirectory() {
+			return a.IsDirectory()
+		}
+		if x, y := strings.ToLower(a.Name), strings.ToLower(b.Name); x != y {
+			return x < y
+		}
+		if a.Name != b.Name {
+			return a.Name < b.Name
+		}
+		return a.Id < b.Id
+	})
+	for _, c := range n.Children {
+		c.sort()
+	}
+}
+
+// disambiguate adds the start of their ids to the names of files which share their path with another, in the node and each directory beneath it.
+// The caller must sort the node first, so files sharing a path are beside each other.
+func (n *TreeNode) disambiguate() {
+	for i, c := range n.Children {
+		if c.IsDirectory() {
+			c.disambiguate()
+			continue
+		}
+		if (i > 0 && n.Children[i-1].sharesPath(c)) || (i+1 < len(n.Children) && n.Children[i+1].sharesPath(c)) {
+			id := c.Id
+			if len(id) > TREE_ID_LENGTH {
+				id = id[:TREE_ID_LENGTH]
+			}
+			c.Name = fmt.Sprintf("%s (%s)", c.Path[len(c.Path)-1], id)
+		}
+	}
+}
+
+// sharesPath returns true if the given node is a different file with the same path as this one.
+func (n *TreeNode) sharesPath(o *TreeNode) bool {
+	return !n.IsDirectory() && len(n.Path) > 0 && n.Id != o.Id && pathKey(n.Path) == pathKey(o.Path)
+}
+
+// BuildTree returns the root directory of a tree holding the file with each id at its path, where all but the last element of a path name directories.
+// Files without a path are named by their id.
+func BuildTree(paths map[string][]string) *TreeNode {
+	root := &TreeNode{}
+	for id, path := range paths {
+		name := id
+		parent := root
+		if len(path) > 0 {
+			name = path[len(path)-1]
+			for _, p := range path[:len(path)-1] {
+				parent = parent.directory(p)
+			}
+		}
+		parent.Children = append(parent.Children, &TreeNode{
+			Name: name,
+			Path: path,
+			Id:   id,
+		})
+	}
+	root.sort()
+	root.disambiguate()
+	return root
+}
+
+// pathKey returns the key of the directory at the given path in Expanded.
+func pathKey(path []string) string {
+	return strings.Join(path, string(os.PathSeparator))
+}
+
+// TreeButton is the button of a node in a Tree, which shows the menu of the node when tapped with the secondary button.
+type TreeButton struct {
+	widget.Button
+	Node *TreeNode
+	tree *Tree
+}
+
+func newTreeButton(tree *Tree, node *TreeNode) *TreeButton {
+	b := &TreeButton{
+		Button: widget.Button{
+			Text: node.Name,
+		},
+		Node: node,
+		tree: tree,
+	}
+	b.ExtendBaseWidget(b)
+	return b
+}
+
+func (b *TreeButton) TappedSecondary(event *fyne.PointEvent) {
+	menu := b.tree.Menu(b.Node)
+	if menu == nil {
+		return
+	}
+	driver := fyne.CurrentApp().Driver()
+	c := driver.CanvasForObject(b)
+	if c == nil {
+		return
+	}
+	position := driver.AbsolutePositionForObject(b).Add(event.Position)
+	widget.ShowPopUpMenuAtPosition(menu, c, position)
+}
+
+// Tree shows the files of an experiment in their directories, which expand and collapse when tapped.
+type Tree struct {
+	Box      *widget.Box
+	Scroll   *widget.ScrollContainer
+	Buttons  map[string]*widget.Button // Buttons of the files shown, by id
+	Root     *TreeNode
+	Paths    map[string][]string // Current paths of the files, by id
+	Latest   map[string]string   // Ids of the latest change records for the files changed, by id
+	Expanded map[string]bool     // Directories which are expanded, by their path joined with os.PathSeparator
+	Selected string
+
+	// OnRename, if set, is offered in the menu of each node, and called with the node to rename or move
+	OnRename func(node *TreeNode)
+
+	// OnDelete, if set, is offered in the menu of each node, and called with the node to delete
+	OnDelete func(node *TreeNode)
+
+	lock     sync.Mutex
+	reading  sync.Mutex // Held while the channels are read, so an earlier read does not replace the paths of a later one
+	callback func(id string, path ...string)
+	rows     map[string]fyne.CanvasObject // Rows holding the buttons of the files shown, by id
+	format   string                       // Format of the files
+	formatId string                       // Id of the record which set the format
+}
+
+// NewTree returns a tree of the files of the given path channel, as renamed and deleted by the given change channel, which may be nil.
+func NewTree(paths, changes *bcgo.Channel, cache bcgo.Cache, network bcgo.Network, callback func(id string, path ...string)) *Tree {
+	tree := &Tree{
+		Box:      widget.NewVBox(),
+		Buttons:  make(map[string]*widget.Button),
+		Root:     &TreeNode{},
+		Paths:    make(map[string][]string),
+		Latest:   make(map[string]string),
+		Expanded: make(map[string]bool),
+		callback: callback,
+		format:   FORMAT_DELTA,
+	}
+	tree.Scroll = widget.NewVScrollContainer(tree.Box)
 	if paths != nil {
 		trigger := func() {
-			var objects []fyne.CanvasObject
-			if err := bcgo.Read(paths.Name, paths.Head, nil, cache, network, "", nil, nil, func(entry *bcgo.BlockEntry, key, data []byte) error {
-				id := base64.RawURLEncoding.EncodeToString(entry.RecordHash)
-				// Unmarshal as Path
-				p := &labgo.Path{}
-				if err := proto.Unmarshal(data, p); err != nil {
-					return err
-				}
-				name := id
-				if len(p.Path) > 0 {
-					name = p.Path[len(p.Path)-1]
+			tree.reading.Lock()
+			defer tree.reading.Unlock()
+			entries, err := ReadPathEntries(paths, cache, network)
+			if err != nil {
+				log.Println(err)
+				return
+			}
+			changeEntries := make(map[string]*bcgo.BlockEntry)
+			changeName := ""
+			if changes != nil {
+				changeEntries, err = ReadPathEntries(changes, cache, network)
+				if err != nil {
+					log.Println(err)
+					return
 				}
-				objects = append(objects, &widget.Button{
-					Text: name,
-					OnTapped: func() {
-						callback(id, p.Path...)
-					},
-				})
-				return nil
-			}); err != nil {
+				changeName = changes.Name
+			}
+			files, latest, err := ResolvePathEntries(paths.Name, entries, changeName, changeEntries)
+			if err != nil {
 				log.Println(err)
+				return
 			}
-			tree.Children = objects
-			tree.Refresh()
+			format, formatId := ResolveFormat(paths.Name, entries, changeName, changeEntries)
+			tree.lock.Lock()
+			tree.Latest = latest
+			tree.format = format
+			tree.formatId = formatId
+			tree.lock.Unlock()
+			tree.SetPaths(files)
 		}
 		paths.AddTrigger(trigger)
+		if changes != nil {
+			changes.AddTrigger(trigger)
+		}
 		trigger()
 	}
 	return tree
 }
+
+// Format returns the format of the files, and the id of the record which set it, or FORMAT_DELTA and an empty id if none has.
+func (t *Tree) Format() (string, string) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	return t.format, t.formatId
+}
+
+// Files returns the current paths of the files, and the ids of the latest change records for those changed, by id.
+// The maps are replaced, rather than modified, when the paths change, so must not be modified.
+func (t *Tree) Files() (map[string][]string, map[string]string) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	return t.Paths, t.Latest
+}
+
+// SetPaths shows the files with the given paths, by id.
+func (t *Tree) SetPaths(paths map[string][]string) {
+	t.lock.Lock()
+	t.Paths = paths
+	t.Root = BuildTree(paths)
+	t.lock.Unlock()
+	t.render()
+}
+
+// Toggle expands the directory at the given path if it is collapsed, otherwise collapses it.
+func (t *Tree) Toggle(path ...string) {
+	t.lock.Lock()
+	key := pathKey(path)
+	if t.Expanded[key] {
+		delete(t.Expanded, key)
+	} else {
+		t.Expanded[key] = true
+	}
+	t.lock.Unlock()
+	t.render()
+}
+
+// Menu returns the menu of actions on the given node, or nil if there are none.
+func (t *Tree) Menu(node *TreeNode) *fyne.Menu {
+	var items []*fyne.MenuItem
+	if !node.IsDirectory() {
+		items = append(items, fyne.NewMenuItem("Open", func() {
+			t.callback(node.Id, node.Path...)
+		}))
+	}
+	if t.OnRename != nil {
+		items = append(items, fyne.NewMenuItem("Rename or Move", func() {
+			t.OnRename(node)
+		}))
+	}
+	if t.OnDelete != nil {
+		items = append(items, fyne.NewMenuItem("Delete", func() {
+			t.OnDelete(node)
+		}))
+	}
+	if len(items) == 0 {
+		return nil
+	}
+	return fyne.NewMenu("", items...)
+}
+
+// render lists the nodes beneath the root, and beneath each expanded directory, indented by depth.
+func (t *Tree) render() {
+	t.lock.Lock()
+	var objects []fyne.CanvasObject
+	buttons := make(map[string]*widget.Button)
+	rows := make(map[string]fyne.CanvasObject)
+	var add func(node *TreeNode, depth int)
+	add = func(node *TreeNode, depth int) {
+		for _, c := range node.Children {
+			child := c
+			button := newTreeButton(t, child)
+			expanded := false
+			if child.IsDirectory() {
+				expanded = t.Expanded[pathKey(child.Path)]
+				button.Icon = theme.FolderIcon()
+				if expanded {
+					button.Icon = theme.FolderOpenIcon()
+				}
+				button.OnTapped = func() {
+					t.Toggle(child.Path...)
+				}
+			} else {
+				button.Icon = IconForPath(child.Path...)
+				button.OnTapped = func() {
+					t.callback(child.Id, child.Path...)
+				}
+				if child.Id == t.Selected {
+					button.Style = widget.PrimaryButton
+				}
+				buttons[child.Id] = &button.Button
+			}
+			indent := canvas.NewRectangle(color.Transparent)
+			indent.SetMinSize(fyne.NewSize(depth*TREE_INDENT, 0))
+			row := fyne.NewContainerWithLayout(layout.NewHBoxLayout(), indent, button)
+			if !child.IsDirectory() {
+				rows[child.Id] = row
+			}
+			objects = append(objects, row)
+			if expanded {
+				add(child, depth+1)
+			}
+		}
+	}
+	add(t.Root, 0)
+	t.Buttons = buttons
+	t.rows = rows
+	t.Box.Children = objects
+	t.lock.Unlock()
+	t.Box.Refresh()
+}
+
+// Reveal highlights the entry for the given file id, expanding the directories holding it, and scrolls the tree to show it.
+func (t *Tree) Reveal(id string) {
+	log.Println("Reveal:", id)
+	t.lock.Lock()
+	t.Selected = id
+	path := t.Paths[id]
+	for i := 1; i < len(path); i++ {
+		t.Expanded[pathKey(path[:i])] = true
+	}
+	t.lock.Unlock()
+	t.render()
+	t.lock.Lock()
+	row, ok := t.rows[id]
+	t.lock.Unlock()
+	if !ok {
+		return
+	}
+	top := row.Position().Y
+	bottom := top + row.Size().Height
+	height := t.Scroll.Size().Height
+	if top < t.Scroll.Offset.Y {
+		t.Scroll.Offset.Y = top
+	} else if bottom > t.Scroll.Offset.Y+height {
+		t.Scroll.Offset.Y = bottom - height
+	}
+	t.Scroll.Refresh()
+}
+
+func (t *Tree) CanvasObject() fyne.CanvasObject {
+	return t.Scroll
+}
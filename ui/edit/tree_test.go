@@ -0,0 +1,141 @@
+/*
+ * Copyright 2020 Aletheia Ware LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package edit_test
+
+import (
+	"fyne.io/fyne"
+	"fyne.io/fyne/test"
+	"fyne.io/fyne/theme"
+	"fyne.io/fyne/widget"
+	"github.com/AletheiaWareLLC/labfynego/ui/edit"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// treeNames returns the name of each node beneath the given node, indented by depth, with a slash after directories.
+func treeNames(node *edit.TreeNode, depth int) (names []string) {
+	for _, c := range node.Children {
+		name := strings.Repeat(" ", depth) + c.Name
+		if c.IsDirectory() {
+			name += "/"
+		}
+		names = append(names, name)
+		names = append(names, treeNames(c, depth+1)...)
+	}
+	return
+}
+
+// shownNames returns the text of each button listed by the given tree.
+func shownNames(tree *edit.Tree) (names []string) {
+	for _, o := range tree.Box.Children {
+		for _, c := range o.(*fyne.Container).Objects {
+			if b, ok := c.(*edit.TreeButton); ok {
+				names = append(names, b.Text)
+			}
+		}
+	}
+	return
+}
+
+func TestBuildTree(t *testing.T) {
+	root := edit.BuildTree(map[string][]string{
+		"1": {"src", "pkg", "foo.go"},
+		"2": {"src", "main.go"},
+		"3": {"README.md"},
+		"4": {"src", "pkg", "Bar.go"},
+		"5": {"docs", "index.md"},
+		"6": nil,
+		"7": {"src", "main.go"},
+	})
+	want := []string{
+		"docs/",
+		" index.md",
+		"src/",
+		" pkg/",
+		"  Bar.go",
+		"  foo.go",
+		" main.go (2)",
+		" main.go (7)",
+		"6",
+		"README.md",
+	}
+	if got := treeNames(root, 0); !reflect.DeepEqual(got, want) {
+		t.Errorf("Incorrect tree; expected '%v', got '%v'", want, got)
+	}
+	if got := root.Children[1].Children[0].Path; !reflect.DeepEqual(got, []string{"src", "pkg"}) {
+		t.Errorf("Incorrect path; expected '%v', got '%v'", []string{"src", "pkg"}, got)
+	}
+}
+
+func TestIconForPath(t *testing.T) {
+	for name, tt := range map[string]struct {
+		path []string
+		want fyne.Resource
+	}{
+		"code":    {[]string{"src", "main.go"}, edit.ICONS[".go"]},
+		"image":   {[]string{"logo.PNG"}, edit.ICONS[".png"]},
+		"text":    {[]string{"README.md"}, edit.ICONS[".md"]},
+		"unknown": {[]string{"data.bin"}, theme.FileIcon()},
+		"empty":   {nil, theme.FileIcon()},
+	} {
+		t.Run(name, func(t *testing.T) {
+			if got := edit.IconForPath(tt.path...); got != tt.want {
+				t.Errorf("Incorrect icon; expected '%v', got '%v'", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestTree(t *testing.T) {
+	test.NewApp()
+	var selected string
+	tree := edit.NewTree(nil, nil, nil, nil, func(id string, path ...string) {
+		selected = id
+	})
+	tree.SetPaths(map[string][]string{
+		"1": {"src", "pkg", "foo.go"},
+		"2": {"src", "main.go"},
+		"3": {"README.md"},
+	})
+	for _, tt := range []struct {
+		action func()
+		want   []string
+	}{
+		// Directories start collapsed
+		{func() {}, []string{"src", "README.md"}},
+		{func() { tree.Toggle("src") }, []string{"src", "pkg", "main.go", "README.md"}},
+		{func() { tree.Toggle("src") }, []string{"src", "README.md"}},
+		// Revealing a file expands the directories holding it
+		{func() { tree.Reveal("1") }, []string{"src", "pkg", "foo.go", "main.go", "README.md"}},
+	} {
+		tt.action()
+		if got := shownNames(tree); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Incorrect tree; expected '%v', got '%v'", tt.want, got)
+		}
+	}
+	if got := tree.Buttons["1"].Style; got != widget.PrimaryButton {
+		t.Errorf("Incorrect style of selected file; expected '%v', got '%v'", widget.PrimaryButton, got)
+	}
+	if got := tree.Buttons["2"].Style; got != widget.DefaultButton {
+		t.Errorf("Incorrect style of other file; expected '%v', got '%v'", widget.DefaultButton, got)
+	}
+	test.Tap(tree.Buttons["2"])
+	if selected != "2" {
+		t.Errorf("Incorrect file selected; expected '%s', got '%s'", "2", selected)
+	}
+}
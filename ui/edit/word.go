@@ -0,0 +1,316 @@
+/*
+ * Copyright 2020 Aletheia Ware LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package edit
+
+import (
+	"unicode"
+)
+
+// Word break classes from Unicode Standard Annex #29
+const (
+	WORD_OTHER = iota
+	WORD_CR
+	WORD_LF
+	WORD_NEWLINE
+	WORD_EXTEND
+	WORD_ZWJ
+	WORD_REGIONAL_INDICATOR
+	WORD_FORMAT
+	WORD_KATAKANA
+	WORD_HEBREW_LETTER
+	WORD_ALETTER
+	WORD_SINGLE_QUOTE
+	WORD_DOUBLE_QUOTE
+	WORD_MID_NUM_LET
+	WORD_MID_LETTER
+	WORD_MID_NUM
+	WORD_NUMERIC
+	WORD_EXTEND_NUM_LET
+	WORD_SEGMENT_SPACE
+)
+
+// Scripts written without spaces, which Unicode segments with a dictionary rather than with rules
+var dictionaryScripts = []*unicode.RangeTable{
+	unicode.Han,
+	unicode.Hiragana,
+	unicode.Thai,
+	unicode.Lao,
+	unicode.Myanmar,
+	unicode.Khmer,
+	unicode.Tai_Le,
+	unicode.New_Tai_Lue,
+	unicode.Tai_Tham,
+	unicode.Tai_Viet,
+}
+
+// WordClass returns the word break class of the given rune.
+// Classes are derived from the general categories and scripts known to the unicode package, rather than from the Unicode word break property file, so a few rarely used runes may be classified differently.
+func WordClass(r rune) int {
+	switch r {
+	case '\r':
+		return WORD_CR
+	case '\n':
+		return WORD_LF
+	case '\v', '\f', 0x85, 0x2028, 0x2029:
+		return WORD_NEWLINE
+	case 0x200C:
+		return WORD_EXTEND
+	case 0x200D:
+		return WORD_ZWJ
+	case '\'':
+		return WORD_SINGLE_QUOTE
+	case '"':
+		return WORD_DOUBLE_QUOTE
+	case '.', 0x2018, 0x2019, 0x2024, 0xFE52, 0xFF07, 0xFF0E:
+		return WORD_MID_NUM_LET
+	case ':', 0xB7, 0x387, 0x55F, 0x5F4, 0x2027, 0xFE13, 0xFE55, 0xFF1A:
+		return WORD_MID_LETTER
+	case ',', ';', 0x37E, 0x589, 0x60C, 0x60D, 0x66C, 0x7F8, 0x2044, 0xFE10, 0xFE14, 0xFE50, 0xFE54, 0xFF0C, 0xFF1B:
+		return WORD_MID_NUM
+	case 0x66B:
+		return WORD_NUMERIC
+	case 0x202F:
+		return WORD_EXTEND_NUM_LET
+	case 0xA0, 0x2007:
+		return WORD_OTHER
+	case 0x3031, 0x3032, 0x3033, 0x3034, 0x3035, 0x309B, 0x309C, 0x30A0, 0x30FC, 0xFF70:
+		return WORD_KATAKANA
+	}
+	switch {
+	case r >= 0x1F1E6 && r <= 0x1F1FF:
+		return WORD_REGIONAL_INDICATOR
+	case r >= 0x1F3FB && r <= 0x1F3FF:
+		// Emoji modifiers
+		return WORD_EXTEND
+	case unicode.In(r, unicode.Mn, unicode.Me, unicode.Mc):
+		return WORD_EXTEND
+	case unicode.Is(unicode.Cf, r):
+		return WORD_FORMAT
+	case unicode.Is(unicode.Nd, r):
+		return WORD_NUMERIC
+	case unicode.Is(unicode.Pc, r):
+		return WORD_EXTEND_NUM_LET
+	case unicode.Is(unicode.Zs, r):
+		return WORD_SEGMENT_SPACE
+	case unicode.Is(unicode.Katakana, r):
+		return WORD_KATAKANA
+	case unicode.In(r, dictionaryScripts...):
+		return WORD_OTHER
+	case unicode.Is(unicode.Hebrew, r) && unicode.IsLetter(r):
+		return WORD_HEBREW_LETTER
+	case unicode.IsLetter(r) || unicode.Is(unicode.Nl, r):
+		return WORD_ALETTER
+	}
+	return WORD_OTHER
+}
+
+// isPictographic reports whether the given rune is in one of the blocks holding emoji.
+// This approximates the Extended_Pictographic property, which the unicode package does not provide.
+func isPictographic(r rune) bool {
+	switch {
+	case r == 0xA9 || r == 0xAE || r == 0x203C || r == 0x2049:
+		return true
+	case r >= 0x2190 && r <= 0x21FF:
+		return unicode.Is(unicode.So, r)
+	case r >= 0x2300 && r <= 0x23FF:
+		return true
+	case r >= 0x2600 && r <= 0x27BF:
+		return true
+	case r >= 0x1F000 && r <= 0x1FAFF:
+		return !(r >= 0x1F1E6 && r <= 0x1F1FF) && !(r >= 0x1F3FB && r <= 0x1F3FF)
+	}
+	return false
+}
+
+func isIgnorable(class int) bool {
+	return class == WORD_EXTEND || class == WORD_FORMAT || class == WORD_ZWJ
+}
+
+func isNewline(class int) bool {
+	return class == WORD_CR || class == WORD_LF || class == WORD_NEWLINE
+}
+
+func isAHLetter(class int) bool {
+	return class == WORD_ALETTER || class == WORD_HEBREW_LETTER
+}
+
+func isMidNumLetQ(class int) bool {
+	return class == WORD_MID_NUM_LET || class == WORD_SINGLE_QUOTE
+}
+
+// wordText gives access to the word break classes of a rope, skipping runes ignored by rule WB4.
+type wordText struct {
+	text   *Rope
+	length int
+}
+
+func (w *wordText) class(index int) int {
+	if index < 0 || index >= w.length {
+		return WORD_OTHER
+	}
+	return WordClass(w.text.RuneAt(index))
+}
+
+// previous returns the index of the last rune before the given index which is not ignored, or -1.
+func (w *wordText) previous(index int) int {
+	index--
+	for index > 0 && isIgnorable(w.class(index)) {
+		index--
+	}
+	return index
+}
+
+// next returns the index of the first rune after the given index which is not ignored, or the length.
+func (w *wordText) next(index int) int {
+	index++
+	for index < w.length && isIgnorable(w.class(index)) {
+		index++
+	}
+	return index
+}
+
+// IsWordBoundary reports whether the default word boundary rules of Unicode Standard Annex #29 allow a break before the rune at the given index.
+// Scripts which Unicode segments with a dictionary, such as Chinese, Japanese Hiragana, and Thai, are broken between every character instead, and emoji sequences are recognized by block rather than by the Extended_Pictographic property.
+func IsWordBoundary(text *Rope, index int) bool {
+	w := &wordText{
+		text:   text,
+		length: text.Len(),
+	}
+	// WB1, WB2
+	if index <= 0 || index >= w.length {
+		return true
+	}
+	before, after := w.class(index-1), w.class(index)
+	// WB3
+	if before == WORD_CR && after == WORD_LF {
+		return false
+	}
+	// WB3a, WB3b
+	if isNewline(before) || isNewline(after) {
+		return true
+	}
+	// WB3c
+	if before == WORD_ZWJ && isPictographic(text.RuneAt(index)) {
+		return false
+	}
+	// WB3d
+	if before == WORD_SEGMENT_SPACE && after == WORD_SEGMENT_SPACE {
+		return false
+	}
+	// WB4
+	if isIgnorable(after) {
+		return false
+	}
+	b := index - 1
+	if isIgnorable(before) {
+		b = w.previous(index)
+		// Ignorable runes following the start of text or a newline stand alone, and match no rule below
+		before = w.class(b)
+	}
+	beforeBefore := w.class(w.previous(b))
+	afterAfter := w.class(w.next(index))
+	switch {
+	// WB5
+	case isAHLetter(before) && isAHLetter(after):
+		return false
+	// WB6
+	case isAHLetter(before) && (after == WORD_MID_LETTER || isMidNumLetQ(after)) && isAHLetter(afterAfter):
+		return false
+	// WB7
+	case isAHLetter(beforeBefore) && (before == WORD_MID_LETTER || isMidNumLetQ(before)) && isAHLetter(after):
+		return false
+	// WB7a
+	case before == WORD_HEBREW_LETTER && after == WORD_SINGLE_QUOTE:
+		return false
+	// WB7b
+	case before == WORD_HEBREW_LETTER && after == WORD_DOUBLE_QUOTE && afterAfter == WORD_HEBREW_LETTER:
+		return false
+	// WB7c
+	case beforeBefore == WORD_HEBREW_LETTER && before == WORD_DOUBLE_QUOTE && after == WORD_HEBREW_LETTER:
+		return false
+	// WB8, WB9, WB10
+	case (before == WORD_NUMERIC || isAHLetter(before)) && (after == WORD_NUMERIC || isAHLetter(after)):
+		return false
+	// WB11
+	case beforeBefore == WORD_NUMERIC && (before == WORD_MID_NUM || isMidNumLetQ(before)) && after == WORD_NUMERIC:
+		return false
+	// WB12
+	case before == WORD_NUMERIC && (after == WORD_MID_NUM || isMidNumLetQ(after)) && afterAfter == WORD_NUMERIC:
+		return false
+	// WB13
+	case before == WORD_KATAKANA && after == WORD_KATAKANA:
+		return false
+	// WB13a
+	case (isAHLetter(before) || before == WORD_NUMERIC || before == WORD_KATAKANA || before == WORD_EXTEND_NUM_LET) && after == WORD_EXTEND_NUM_LET:
+		return false
+	// WB13b
+	case before == WORD_EXTEND_NUM_LET && (isAHLetter(after) || after == WORD_NUMERIC || after == WORD_KATAKANA):
+		return false
+	// WB15, WB16
+	case before == WORD_REGIONAL_INDICATOR && after == WORD_REGIONAL_INDICATOR:
+		count := 0
+		for i := b; i >= 0 && w.class(i) == WORD_REGIONAL_INDICATOR; i = w.previous(i) {
+			count++
+		}
+		return count%2 == 0
+	}
+	// WB999
+	return true
+}
+
+// WordBounds returns the start and end indices of the word containing the rune at the given index, as segmented by IsWordBoundary.
+func WordBounds(text *Rope, index int) (int, int) {
+	length := text.Len()
+	if length == 0 {
+		return 0, 0
+	}
+	if index >= length {
+		index = length - 1
+	}
+	start := index
+	for !IsWordBoundary(text, start) {
+		start--
+	}
+	end := index + 1
+	for !IsWordBoundary(text, end) {
+		end++
+	}
+	return start, end
+}
+
+// NextWordIndex returns the index of the end of the word following the given index, skipping any leading spaces
+func NextWordIndex(text *Rope, index int) int {
+	length := text.Len()
+	for index < length && unicode.IsSpace(text.RuneAt(index)) {
+		index++
+	}
+	if index < length {
+		_, index = WordBounds(text, index)
+	}
+	return index
+}
+
+// PreviousWordIndex returns the index of the start of the word preceding the given index, skipping any trailing spaces
+func PreviousWordIndex(text *Rope, index int) int {
+	for index > 0 && unicode.IsSpace(text.RuneAt(index-1)) {
+		index--
+	}
+	if index > 0 {
+		index, _ = WordBounds(text, index-1)
+	}
+	return index
+}
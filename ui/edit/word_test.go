@@ -0,0 +1,144 @@
+/*
+ * Copyright 2020 Aletheia Ware LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package edit_test
+
+import (
+	"github.com/AletheiaWareLLC/labfynego/ui/edit"
+	"reflect"
+	"testing"
+)
+
+// words returns the text split at every word boundary.
+func words(text string) (words []string) {
+	rope := edit.NewRope(text)
+	runes := []rune(text)
+	start := 0
+	for i := 1; i <= len(runes); i++ {
+		if edit.IsWordBoundary(rope, i) {
+			words = append(words, string(runes[start:i]))
+			start = i
+		}
+	}
+	return
+}
+
+func TestIsWordBoundary(t *testing.T) {
+	for name, tt := range map[string]struct {
+		text string
+		want []string
+	}{
+		"empty":       {"", nil},
+		"spaces":      {"foo  bar", []string{"foo", "  ", "bar"}},
+		"apostrophe":  {"can't stop", []string{"can't", " ", "stop"}},
+		"quote":       {"'quoted'", []string{"'", "quoted", "'"}},
+		"decimal":     {"pi is 3.14", []string{"pi", " ", "is", " ", "3.14"}},
+		"thousands":   {"1,000,000.", []string{"1,000,000", "."}},
+		"full_stop":   {"foo.bar", []string{"foo.bar"}},
+		"sentence":    {"foo. bar", []string{"foo", ".", " ", "bar"}},
+		"underscore":  {"snake_case_42", []string{"snake_case_42"}},
+		"symbols":     {"a+=b", []string{"a", "+", "=", "b"}},
+		"crlf":        {"a\r\nb", []string{"a", "\r\n", "b"}},
+		"combining":   {"café au lait", []string{"café", " ", "au", " ", "lait"}},
+		"mark_after":  {"\ńa", []string{"\n", "́", "a"}},
+		"cyrillic":    {"привет мир", []string{"привет", " ", "мир"}},
+		"han":         {"你好世界", []string{"你", "好", "世", "界"}},
+		"katakana":    {"カタカナです", []string{"カタカナ", "で", "す"}},
+		"hebrew":      {"צה\"ל", []string{"צה\"ל"}},
+		"flags":       {"\U0001F1EC\U0001F1E7\U0001F1FA\U0001F1F8", []string{"\U0001F1EC\U0001F1E7", "\U0001F1FA\U0001F1F8"}},
+		"emoji_zwj":   {"\U0001F468‍\U0001F4BB", []string{"\U0001F468‍\U0001F4BB"}},
+		"emoji_skin":  {"\U0001F44D\U0001F3FD!", []string{"\U0001F44D\U0001F3FD", "!"}},
+		"format_mark": {"soft­hyphen", []string{"soft­hyphen"}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			if got := words(tt.text); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Incorrect words; expected '%q', got '%q'", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestWordBounds(t *testing.T) {
+	for name, tt := range map[string]struct {
+		text  string
+		index int
+		want  string
+	}{
+		"empty":      {"", 0, ""},
+		"start":      {"hello world", 0, "hello"},
+		"middle":     {"hello world", 2, "hello"},
+		"space":      {"hello world", 5, " "},
+		"end":        {"hello world", 11, "world"},
+		"apostrophe": {"don't panic", 1, "don't"},
+		"decimal":    {"x = 3.14;", 5, "3.14"},
+		"combining":  {"café", 4, "café"},
+		"han":        {"你好", 1, "好"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			runes := []rune(tt.text)
+			start, end := edit.WordBounds(edit.NewRope(tt.text), tt.index)
+			if got := string(runes[start:end]); got != tt.want {
+				t.Errorf("Incorrect word; expected '%q', got '%q'", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestNextWordIndex(t *testing.T) {
+	for name, tt := range map[string]struct {
+		text  string
+		index int
+		want  int
+	}{
+		"empty":       {"", 0, 0},
+		"start":       {"hello world", 0, 5},
+		"middle":      {"hello world", 2, 5},
+		"space":       {"hello world", 5, 11},
+		"end":         {"hello world", 11, 11},
+		"apostrophe":  {"don't panic", 0, 5},
+		"punctuation": {"foo(bar)", 3, 4},
+		"newline":     {"foo\n\nbar", 3, 8},
+	} {
+		t.Run(name, func(t *testing.T) {
+			if got := edit.NextWordIndex(edit.NewRope(tt.text), tt.index); got != tt.want {
+				t.Errorf("Incorrect index; expected '%d', got '%d'", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestPreviousWordIndex(t *testing.T) {
+	for name, tt := range map[string]struct {
+		text  string
+		index int
+		want  int
+	}{
+		"empty":       {"", 0, 0},
+		"start":       {"hello world", 0, 0},
+		"middle":      {"hello world", 8, 6},
+		"space":       {"hello world", 6, 0},
+		"end":         {"hello world", 11, 6},
+		"apostrophe":  {"don't panic", 5, 0},
+		"punctuation": {"foo(bar)", 4, 3},
+		"newline":     {"foo\n\nbar", 5, 0},
+	} {
+		t.Run(name, func(t *testing.T) {
+			if got := edit.PreviousWordIndex(edit.NewRope(tt.text), tt.index); got != tt.want {
+				t.Errorf("Incorrect index; expected '%d', got '%d'", tt.want, got)
+			}
+		})
+	}
+}
@@ -20,6 +20,7 @@ import (
 	"fmt"
 	"fyne.io/fyne"
 	"fyne.io/fyne/dialog"
+	"fyne.io/fyne/driver/desktop"
 	"fyne.io/fyne/layout"
 	"fyne.io/fyne/widget"
 	"github.com/AletheiaWareLLC/bcfynego/ui"
@@ -28,7 +29,14 @@ import (
 	"github.com/AletheiaWareLLC/labgo"
 	"log"
 	"os"
+	"path/filepath"
 	"strings"
+	"sync"
+)
+
+const (
+	// Preference holding whether editors show line numbers
+	PREFERENCE_LINE_NUMBERS = "line-numbers"
 )
 
 type Experiment struct {
@@ -37,14 +45,33 @@ type Experiment struct {
 	Cache      bcgo.Cache
 	Network    bcgo.Network
 	Experiment *labgo.Experiment
+	Changes    *bcgo.Channel // Records renaming and deleting the files of Experiment, and setting their format
 	Window     fyne.Window
+	Format     string
+	// LineNumbers shows line numbers beside the text of every editor
+	LineNumbers bool
+	Snapshots   edit.SnapshotStore
+	// PresenceCache holds the blocks of presence channels, which are not worth keeping on disk
+	PresenceCache *bcgo.MemoryCache
 
-	Chat    *widget.Label
-	Editors map[string]*edit.ChannelEditor
-	Items   map[string]*widget.TabItem
-	Status  *widget.Label
-	Tabber  *widget.TabContainer
-	Tree    fyne.CanvasObject
+	Chat      *widget.Label
+	Contents  map[string]*fyne.Container
+	Editors   map[string]*edit.ChannelEditor
+	Finds     map[string]*edit.FindBar
+	Histories map[string]*edit.History
+	Items     map[string]*widget.TabItem
+	Left      *fyne.Container
+	Legends   map[string]*edit.Legend
+	Scrolls   map[string]*widget.ScrollContainer
+	Search    *edit.SearchPanel
+	Status    *widget.Label
+	Tabber    *widget.TabContainer
+	Tree      *edit.Tree
+
+	// tabs guards Contents, Editors, Finds, Histories, Items, Legends and Scrolls, as files are opened into tabs in the background and closed as they are deleted
+	tabs sync.Mutex
+	// channels guards getting and adding the channels of the node, which are opened in the background
+	channels sync.Mutex
 }
 
 func NewExperiment(node *bcgo.Node, listener bcgo.MiningListener, cache bcgo.Cache, network bcgo.Network, experiment *labgo.Experiment, window fyne.Window) *Experiment {
@@ -57,19 +84,69 @@ func NewExperiment(node *bcgo.Node, listener bcgo.MiningListener, cache bcgo.Cac
 		Window:     window,
 		Tabber:     widget.NewTabContainer(),
 		Items:      make(map[string]*widget.TabItem),
+		Contents:   make(map[string]*fyne.Container),
 		Editors:    make(map[string]*edit.ChannelEditor),
+		Finds:      make(map[string]*edit.FindBar),
+		Histories:  make(map[string]*edit.History),
+		Legends:    make(map[string]*edit.Legend),
+		Scrolls:    make(map[string]*widget.ScrollContainer),
 		Chat:       widget.NewLabel("Chat"),
 		Status:     widget.NewLabel("Ready"),
+		Format:     edit.FORMAT_DELTA,
+
+		PresenceCache: bcgo.NewMemoryCache(10),
 	}
 	var channel *bcgo.Channel
 	if experiment != nil {
 		channel = experiment.Path
+		e.Changes = e.GetOrOpenChangeChannel(experiment.ID)
+	}
+	if app := fyne.CurrentApp(); app != nil {
+		e.LineNumbers = app.Preferences().Bool(PREFERENCE_LINE_NUMBERS)
+	}
+	if c, ok := cache.(*bcgo.FileCache); ok {
+		// Keep snapshots of files beside the blocks they were made from
+		store, err := edit.NewFileSnapshotStore(filepath.Join(c.Directory, "snapshot"))
+		if err != nil {
+			log.Println(err)
+		} else {
+			e.Snapshots = store
+		}
+	}
+	e.Tree = edit.NewTree(channel, e.Changes, cache, network, e.SelectPath)
+	e.Tree.OnRename = e.RenameNode
+	e.Tree.OnDelete = e.DeleteNode
+	e.updateFormat()
+	if channel != nil {
+		// Triggered after the tree has resolved the paths
+		for _, c := range []*bcgo.Channel{channel, e.Changes} {
+			c.AddTrigger(e.updateTabs)
+			c.AddTrigger(e.updateFormat)
+		}
+	}
+	e.Left = fyne.NewContainerWithLayout(layout.NewMaxLayout(), e.Tree.CanvasObject())
+	// Highlight the file in the selected tab
+	e.Tabber.OnChanged = func(item *widget.TabItem) {
+		if id, ok := e.SelectedId(); ok {
+			e.Tree.Reveal(id)
+		}
+	}
+	if window != nil {
+		window.SetOnClosed(e.Flush)
 	}
-	e.Tree = edit.NewTree(channel, cache, network, e.SelectPath)
 	return e
 }
 
+// Flush emits the pending edits of every open editor.
+func (e *Experiment) Flush() {
+	for _, editor := range e.openEditors() {
+		editor.Flush()
+	}
+}
+
 func (e *Experiment) GetOrOpenDeltaChannel(fileId string) *bcgo.Channel {
+	e.channels.Lock()
+	defer e.channels.Unlock()
 	channel, err := e.Node.GetChannel(labgo.LAB_PREFIX_FILE + fileId)
 	if err != nil {
 		log.Println(err)
@@ -90,34 +167,567 @@ func (e *Experiment) GetOrOpenDeltaChannel(fileId string) *bcgo.Channel {
 	return channel
 }
 
+func (e *Experiment) GetOrOpenChangeChannel(experimentId string) *bcgo.Channel {
+	e.channels.Lock()
+	defer e.channels.Unlock()
+	channel, err := e.Node.GetChannel(edit.CHANGE_PREFIX + experimentId)
+	if err != nil {
+		log.Println(err)
+		channel = edit.OpenChangeChannel(experimentId)
+		// Load channel
+		if err := channel.LoadCachedHead(e.Cache); err != nil {
+			log.Println(err)
+		}
+		if e.Network != nil {
+			// Pull channel from network
+			if err := channel.Pull(e.Cache, e.Network); err != nil {
+				log.Println(err)
+			}
+		}
+		// Add channel to node
+		e.Node.AddChannel(channel)
+	}
+	return channel
+}
+
+func (e *Experiment) GetOrOpenPresenceChannel(fileId string) *bcgo.Channel {
+	e.channels.Lock()
+	defer e.channels.Unlock()
+	channel, err := e.Node.GetChannel(edit.PRESENCE_PREFIX + fileId)
+	if err != nil {
+		log.Println(err)
+		channel = edit.OpenPresenceChannel(fileId)
+		if e.Network != nil {
+			// Pull channel from network
+			if err := channel.Pull(e.PresenceCache, e.Network); err != nil {
+				log.Println(err)
+			}
+		}
+		// Add channel to node
+		e.Node.AddChannel(channel)
+	}
+	return channel
+}
+
+// SetFormat records the format of new files on the change channel, so every collaborator uses it, and if it is edit.FORMAT_SEQUENCE, migrates the deltas of every file into it.
+func (e *Experiment) SetFormat(format string) {
+	log.Println("Format:", format)
+	if e.Experiment != nil {
+		_, latest := e.Tree.Format()
+		if err := edit.SetFormat(e.Node, e.Listener, e.Changes, format, latest); err != nil {
+			dialog.ShowError(err, e.Window)
+			return
+		}
+	}
+	e.setFormat(format)
+	if format != edit.FORMAT_SEQUENCE {
+		return
+	}
+	var ids []string
+	paths, _ := e.Tree.Files()
+	for id := range paths {
+		ids = append(ids, id)
+	}
+	progress := dialog.NewProgress("Migrating", "Converting deltas", e.Window)
+	progress.Show()
+	defer progress.Hide()
+	// Write pending edits before they are migrated, editors stop reading deltas once the channel holds the migrated records
+	e.Flush()
+	for i, id := range ids {
+		count, err := edit.MigrateDeltas(e.Node, e.Listener, e.GetOrOpenDeltaChannel(id))
+		if err != nil {
+			dialog.ShowError(err, e.Window)
+			return
+		}
+		log.Println("Migrated:", id, count)
+		progress.SetValue(float64(i+1) / float64(len(ids)))
+	}
+}
+
+// updateFormat uses the format recorded on the change channel, as resolved by the tree.
+func (e *Experiment) updateFormat() {
+	format, _ := e.Tree.Format()
+	e.setFormat(format)
+}
+
+// setFormat sets the format of new files, and of the records written by open editors to channels without any.
+func (e *Experiment) setFormat(format string) {
+	e.Format = format
+	for _, editor := range e.openEditors() {
+		editor.Lock()
+		editor.Format = format
+		editor.Unlock()
+	}
+}
+
 func (e *Experiment) SelectPath(id string, path ...string) {
 	log.Println("Selected:", id, path)
-	go func() {
-		editor, ok := e.Editors[id]
-		if !ok {
-			editor = edit.NewChannelEditor(e.Node, e.Listener, e.GetOrOpenDeltaChannel(id))
-			e.Editors[id] = editor
+	go e.openPath(id, path...)
+}
+
+// openPath opens the file with the given id in a tab, unless it is already open, selects the tab, and returns the editor of the file.
+func (e *Experiment) openPath(id string, path ...string) *edit.ChannelEditor {
+	// Held while the editor and tab are made, so a file opened twice at once gets one of each
+	e.tabs.Lock()
+	editor, ok := e.Editors[id]
+	if !ok {
+		editor = edit.NewChannelEditor(e.Node, e.Listener, e.GetOrOpenDeltaChannel(id), e.Snapshots)
+		editor.Format = e.Format
+		editor.ShowLineNumbers = e.LineNumbers
+		editor.OnReveal = func() {
+			e.Tree.Reveal(id)
+		}
+		editor.SetPresenceChannel(e.GetOrOpenPresenceChannel(id), e.PresenceCache)
+		editor.SetLexer(edit.LexerForPath(path...))
+		e.Editors[id] = editor
+	}
+	item, ok := e.Items[id]
+	if !ok {
+		name := id
+		if len(path) > 0 {
+			name = path[len(path)-1]
+		}
+		// Contained so the history and legend can be shown around the editor
+		scroll := widget.NewVScrollContainer(editor)
+		editor.Lock()
+		editor.Scroll = scroll
+		editor.Unlock()
+		content := fyne.NewContainerWithLayout(layout.NewMaxLayout(), scroll)
+		item = widget.NewTabItem(name, content)
+		e.Contents[id] = content
+		e.Items[id] = item
+		e.Scrolls[id] = scroll
+		e.Tabber.Append(item)
+	}
+	count := len(e.Items)
+	e.tabs.Unlock()
+	e.Tabber.SelectTab(item)
+	if count == 1 {
+		// First tab, resize tabber
+		e.Tabber.Resize(e.Tabber.MinSize())
+	}
+	return editor
+}
+
+// RenameNode asks for a new path for the given file or directory, and moves it, along with every file in the directory, to the path.
+func (e *Experiment) RenameNode(node *edit.TreeNode) {
+	entry := widget.NewEntry()
+	entry.SetText(strings.Join(node.Path, string(os.PathSeparator)))
+	dialog.ShowCustomConfirm("Rename or Move", "Move", "Cancel", entry, func(b bool) {
+		if !b {
+			return
 		}
-		item, ok := e.Items[id]
+		path := splitPath(entry.Text)
+		if len(path) == 0 {
+			dialog.ShowError(fmt.Errorf("Path is empty"), e.Window)
+			return
+		}
+		files := node.Files()
+		moved := make(map[string]bool, len(files))
+		for _, f := range files {
+			moved[f.Id] = true
+		}
+		targets := make([][]string, len(files))
+		for i, f := range files {
+			// Files in a directory keep their path within it
+			targets[i] = append(append([]string{}, path...), f.Path[len(node.Path):]...)
+			if id, ok := e.findPath(targets[i]); ok && !moved[id] {
+				dialog.ShowError(fmt.Errorf("File already exists: %s", strings.Join(targets[i], string(os.PathSeparator))), e.Window)
+				return
+			}
+		}
+		_, latest := e.Tree.Files()
+		for i, f := range files {
+			log.Println("Rename:", f.Id, f.Path, targets[i])
+			if err := edit.RenamePath(e.Node, e.Listener, e.Changes, f.Id, latest[f.Id], targets[i]); err != nil {
+				dialog.ShowError(err, e.Window)
+				return
+			}
+		}
+	}, e.Window)
+}
+
+// DeleteNode asks for confirmation, then deletes the given file, or every file in the given directory.
+func (e *Experiment) DeleteNode(node *edit.TreeNode) {
+	files := node.Files()
+	message := fmt.Sprintf("Delete %s?", node.Name)
+	if node.IsDirectory() {
+		message = fmt.Sprintf("Delete %s and the %d files in it?", node.Name, len(files))
+	}
+	dialog.ShowConfirm("Delete", message, func(b bool) {
+		if !b {
+			return
+		}
+		_, latest := e.Tree.Files()
+		for _, f := range files {
+			log.Println("Delete:", f.Id, f.Path)
+			if err := edit.DeletePath(e.Node, e.Listener, e.Changes, f.Id, latest[f.Id]); err != nil {
+				dialog.ShowError(err, e.Window)
+				return
+			}
+		}
+	}, e.Window)
+}
+
+// ImportDirectory creates a file in the experiment for every file within the directory at the given path, with its path relative to the directory, showing the progress of mining each.
+// Files matched by edit.DEFAULT_IGNORE, the given patterns, or the patterns in the .gitignore files within the directory, are skipped, as are files the experiment already has.
+func (e *Experiment) ImportDirectory(directory, patterns string) {
+	root, err := edit.ImportRoot(directory)
+	if err != nil {
+		dialog.ShowError(err, e.Window)
+		return
+	}
+	log.Println("Import:", root)
+	ignore := edit.NewIgnore()
+	if err := ignore.Add("", edit.DEFAULT_IGNORE); err != nil {
+		dialog.ShowError(err, e.Window)
+		return
+	}
+	if err := ignore.Add("", patterns); err != nil {
+		dialog.ShowError(err, e.Window)
+		return
+	}
+	listed, err := edit.ListFiles(root, ignore)
+	if err != nil {
+		dialog.ShowError(err, e.Window)
+		return
+	}
+	var paths [][]string
+	for _, path := range listed {
+		if _, ok := e.findPath(path); !ok {
+			paths = append(paths, path)
+		}
+	}
+	if len(paths) == 0 {
+		dialog.ShowInformation("Import Directory", "No new files to import", e.Window)
+		return
+	}
+	progress := dialog.NewProgress("Importing", fmt.Sprintf("Importing %d files from %s", len(paths), root), e.Window)
+	progress.Show()
+	defer progress.Hide()
+	listener := &importListener{}
+	listener.Func = func(f float64) {
+		// Each file is mined into several blocks, so the progress of the current block is scaled to its share of the current file
+		share := (float64(listener.mined) + f) / float64(listener.blocks)
+		progress.SetValue((float64(listener.index) + share) / float64(len(paths)))
+	}
+	ids, err := edit.ImportFiles(e.Node, listener, e.Experiment.Path, e.Changes, root, paths, func(i int) {
+		listener.index = i
+		listener.mined = 0
+		listener.blocks = edit.ImportBlocks(root, paths[i])
+	})
+	log.Println("Imported:", len(ids), "of", len(paths))
+	if err != nil {
+		dialog.ShowError(err, e.Window)
+	}
+}
+
+// importListener shows the progress of mining the blocks of the file being imported.
+type importListener struct {
+	ui.ProgressMiningListener
+	index  int // Index of the file being imported
+	mined  int // Number of blocks of the file which have been mined
+	blocks int // Number of blocks mined to import the file
+}
+
+func (l *importListener) OnMiningThresholdReached(channel *bcgo.Channel, hash []byte, block *bcgo.Block) {
+	l.ProgressMiningListener.OnMiningThresholdReached(channel, hash, block)
+	if l.mined+1 < l.blocks {
+		l.mined++
+	}
+}
+
+// findPath returns the id of the file with the given path, or false if there is none.
+func (e *Experiment) findPath(path []string) (string, bool) {
+	key := strings.Join(path, string(os.PathSeparator))
+	paths, _ := e.Tree.Files()
+	for id, p := range paths {
+		if strings.Join(p, string(os.PathSeparator)) == key {
+			return id, true
+		}
+	}
+	return "", false
+}
+
+// updateTabs closes the tabs of files which have been deleted, and renames the tabs of files which have been moved.
+// The editor of a deleted file writes its pending edits, and stops reading its channels, before the tab is closed.
+func (e *Experiment) updateTabs() {
+	paths, _ := e.Tree.Files()
+	var closed []*widget.TabItem
+	var editors []*edit.ChannelEditor
+	lexers := make(map[*edit.ChannelEditor][]string)
+	e.tabs.Lock()
+	for id, item := range e.Items {
+		path, ok := paths[id]
 		if !ok {
-			name := id
-			if len(path) > 0 {
-				name = path[len(path)-1]
+			log.Println("Closing:", id)
+			closed = append(closed, item)
+			if editor, ok := e.Editors[id]; ok {
+				editors = append(editors, editor)
 			}
-			item = widget.NewTabItem(name, widget.NewVScrollContainer(editor))
-			e.Items[id] = item
-			e.Tabber.Append(item)
+			delete(e.Contents, id)
+			delete(e.Editors, id)
+			delete(e.Finds, id)
+			delete(e.Histories, id)
+			delete(e.Items, id)
+			delete(e.Legends, id)
+			delete(e.Scrolls, id)
+			continue
 		}
-		e.Tabber.SelectTab(item)
-		if len(e.Items) == 1 {
-			// First tab, resize tabber
-			e.Tabber.Resize(e.Tabber.MinSize())
+		if len(path) > 0 && item.Text != path[len(path)-1] {
+			item.Text = path[len(path)-1]
+			if editor, ok := e.Editors[id]; ok {
+				lexers[editor] = path
+			}
 		}
+	}
+	e.tabs.Unlock()
+	for _, editor := range editors {
+		editor.Close()
+	}
+	for _, item := range closed {
+		e.Tabber.Remove(item)
+	}
+	for editor, path := range lexers {
+		editor.SetLexer(edit.LexerForPath(path...))
+	}
+	e.Tabber.Refresh()
+}
+
+// splitPath returns the names in the given path, without the empty names around separators, such as a leading separator.
+func splitPath(path string) (names []string) {
+	for _, n := range strings.Split(path, string(os.PathSeparator)) {
+		if n != "" {
+			names = append(names, n)
+		}
+	}
+	return
+}
+
+// ToggleHistory shows or hides the history of the file in the selected tab.
+func (e *Experiment) ToggleHistory() {
+	id, ok := e.SelectedId()
+	if !ok {
+		return
+	}
+	e.tabs.Lock()
+	if _, ok := e.Histories[id]; ok {
+		delete(e.Histories, id)
+	} else if editor, ok := e.Editors[id]; ok {
+		e.Histories[id] = edit.NewHistory(editor)
+	}
+	e.tabs.Unlock()
+	e.layoutTab(id)
+}
+
+// ToggleLineNumbers shows or hides line numbers in every editor.
+func (e *Experiment) ToggleLineNumbers() {
+	e.LineNumbers = !e.LineNumbers
+	log.Println("LineNumbers:", e.LineNumbers)
+	if app := fyne.CurrentApp(); app != nil {
+		app.Preferences().SetBool(PREFERENCE_LINE_NUMBERS, e.LineNumbers)
+	}
+	for _, editor := range e.openEditors() {
+		editor.Lock()
+		editor.ShowLineNumbers = e.LineNumbers
+		editor.Unlock()
+		editor.Refresh()
+	}
+}
+
+// ToggleBlame turns blame mode on or off for the file in the selected tab, showing a legend of the authors while on.
+func (e *Experiment) ToggleBlame() {
+	id, ok := e.SelectedId()
+	if !ok {
+		return
+	}
+	e.tabs.Lock()
+	editor, ok := e.Editors[id]
+	if !ok {
+		e.tabs.Unlock()
+		return
+	}
+	_, blame := e.Legends[id]
+	if blame {
+		delete(e.Legends, id)
+	} else {
+		e.Legends[id] = edit.NewLegend(editor)
+	}
+	e.tabs.Unlock()
+	editor.SetBlame(!blame)
+	e.layoutTab(id)
+}
+
+// ShowFind shows the find bar above the file in the selected tab, or if it is already shown, searches again.
+func (e *Experiment) ShowFind() {
+	id, ok := e.SelectedId()
+	if !ok {
+		return
+	}
+	e.tabs.Lock()
+	find, ok := e.Finds[id]
+	if ok {
+		e.tabs.Unlock()
+		find.Search()
+		e.Window.Canvas().Focus(find.Query)
+		return
+	}
+	editor, ok := e.Editors[id]
+	if !ok {
+		e.tabs.Unlock()
+		return
+	}
+	find = edit.NewFindBar(editor)
+	find.OnClose = func() {
+		e.tabs.Lock()
+		delete(e.Finds, id)
+		e.tabs.Unlock()
+		e.layoutTab(id)
+	}
+	e.Finds[id] = find
+	e.tabs.Unlock()
+	e.layoutTab(id)
+	e.Window.Canvas().Focus(find.Query)
+}
+
+// ShowSearch shows the search of every file in the experiment beneath the tree, or if it is already shown, searches again.
+func (e *Experiment) ShowSearch() {
+	if e.Search != nil {
+		e.Search.Search()
+		e.Window.Canvas().Focus(e.Search.Query)
+		return
+	}
+	search := edit.NewSearchPanel(func(query *edit.Query, done <-chan struct{}, callback func(*edit.SearchResult)) error {
+		if e.Experiment == nil {
+			return nil
+		}
+		return edit.SearchPaths(e.Experiment.Path, e.Changes, e.Cache, e.Network, query, e.FileText, done, callback)
+	})
+	search.OnSelect = e.ShowResult
+	search.OnClose = func() {
+		e.Search = nil
+		e.layoutLeft()
+	}
+	e.Search = search
+	e.layoutLeft()
+	e.Window.Canvas().Focus(search.Query)
+}
+
+// ShowResult opens the file holding the given search result in a tab, and selects the match.
+func (e *Experiment) ShowResult(result *edit.SearchResult) {
+	log.Println("Result:", result.Id, result.Path, result.Line)
+	go func() {
+		editor := e.openPath(result.Id, result.Path...)
+		editor.Select(uint64(result.Start), uint64(result.End))
 	}()
 }
 
+// FileText returns the text of the file with the given id, from its editor if it is open, so edits which have not been emitted are included, otherwise from its channel.
+func (e *Experiment) FileText(id string) ([]rune, error) {
+	if editor, ok := e.editor(id); ok {
+		editor.Lock()
+		defer editor.Unlock()
+		return editor.Buffer.Runes(), nil
+	}
+	return edit.ReadText(e.Node, e.GetOrOpenDeltaChannel(id))
+}
+
+// editor returns the editor of the file with the given id, or false if it is not open.
+func (e *Experiment) editor(id string) (*edit.ChannelEditor, bool) {
+	e.tabs.Lock()
+	defer e.tabs.Unlock()
+	editor, ok := e.Editors[id]
+	return editor, ok
+}
+
+// openEditors returns the editor of every open file.
+func (e *Experiment) openEditors() []*edit.ChannelEditor {
+	e.tabs.Lock()
+	defer e.tabs.Unlock()
+	editors := make([]*edit.ChannelEditor, 0, len(e.Editors))
+	for _, editor := range e.Editors {
+		editors = append(editors, editor)
+	}
+	return editors
+}
+
+// SelectedId returns the id of the file in the selected tab, or false if no tab is selected.
+func (e *Experiment) SelectedId() (string, bool) {
+	current := e.Tabber.CurrentTab()
+	e.tabs.Lock()
+	defer e.tabs.Unlock()
+	for id, item := range e.Items {
+		if item == current {
+			return id, true
+		}
+	}
+	return "", false
+}
+
+// layoutTab arranges the tab of the given file, with the history beside the editor and the legend and find bar above, if shown.
+func (e *Experiment) layoutTab(id string) {
+	e.tabs.Lock()
+	history, showHistory := e.Histories[id]
+	legend, showLegend := e.Legends[id]
+	find, showFind := e.Finds[id]
+	editor, ok := e.Editors[id]
+	scroll := e.Scrolls[id]
+	content := e.Contents[id]
+	e.tabs.Unlock()
+	if !ok || content == nil {
+		// Closed as the file was deleted
+		return
+	}
+	editor.OnRead = func() {
+		if showHistory {
+			history.Update()
+		}
+		if showLegend {
+			legend.Update()
+		}
+		if showFind {
+			find.Update()
+		}
+	}
+	var center fyne.CanvasObject = scroll
+	if showHistory {
+		split := widget.NewHSplitContainer(center, history.CanvasObject())
+		split.Offset = 0.6
+		center = split
+	}
+	var tops []fyne.CanvasObject
+	if showLegend {
+		tops = append(tops, legend.CanvasObject())
+	}
+	if showFind {
+		tops = append(tops, find.CanvasObject())
+	}
+	if len(tops) > 0 {
+		top := widget.NewVBox(tops...)
+		content.Layout = layout.NewBorderLayout(top, nil, nil, nil)
+		content.Objects = []fyne.CanvasObject{top, center}
+	} else {
+		content.Layout = layout.NewMaxLayout()
+		content.Objects = []fyne.CanvasObject{center}
+	}
+	content.Refresh()
+}
+
+// layoutLeft arranges the tree, with the search beneath it, if shown.
+func (e *Experiment) layoutLeft() {
+	tree := e.Tree.CanvasObject()
+	if e.Search != nil {
+		split := widget.NewVSplitContainer(tree, e.Search.CanvasObject())
+		split.Offset = 0.5
+		e.Left.Objects = []fyne.CanvasObject{split}
+	} else {
+		e.Left.Objects = []fyne.CanvasObject{tree}
+	}
+	e.Left.Refresh()
+}
+
 func (e *Experiment) CanvasObject() fyne.CanvasObject {
-	left := widget.NewVScrollContainer(e.Tree)
+	left := e.Left
 	center := e.Tabber
 	right := fyne.NewContainerWithLayout(layout.NewBorderLayout(nil, e.Status, nil, nil), e.Status, e.Chat)
 	splitter := widget.NewHSplitContainer(left, center)
@@ -140,33 +750,62 @@ func (e *Experiment) MainMenu() *fyne.MainMenu {
 					if !b {
 						return
 					}
-					path := filepath.Text
+					path := splitPath(filepath.Text)
 					log.Println(path)
-					id, _, err := labgo.CreatePath(e.Node, e.Listener, e.Experiment.Path, strings.Split(path, string(os.PathSeparator)))
+					if len(path) == 0 {
+						dialog.ShowError(fmt.Errorf("Path is empty"), e.Window)
+						return
+					}
+					if _, ok := e.findPath(path); ok {
+						dialog.ShowError(fmt.Errorf("File already exists: %s", filepath.Text), e.Window)
+						return
+					}
+					id, _, err := labgo.CreatePath(e.Node, e.Listener, e.Experiment.Path, path)
 					if err != nil {
 						dialog.ShowError(err, e.Window)
 						return
 					}
-					e.SelectPath(id, path)
+					e.SelectPath(id, path...)
 				}, e.Window)
 			}),
-			fyne.NewMenuItem("Import", func() {
-				fmt.Println("Menu File->Import")
+			fyne.NewMenuItem("Import File", func() {
+				fmt.Println("Menu File->Import File")
 				dialog.ShowFileOpen(func(reader fyne.FileReadCloser, err error) {
 					if err != nil {
 						dialog.ShowError(err, e.Window)
 						return
 					}
-					uri := reader.URI().String()
-					// TODO truncate uri to remove file:///Users/foobar/...
-					path := uri
-					log.Println(path)
-					id, _, err := labgo.CreatePathFromReader(e.Node, e.Listener, e.Experiment.Path, strings.Split(path, string(os.PathSeparator)), reader)
+					if reader == nil {
+						// Cancelled
+						return
+					}
+					defer reader.Close()
+					// Only the name is kept, as the directories holding the file are not part of the experiment
+					path := []string{reader.Name()}
+					log.Println(reader.URI(), path)
+					id, _, err := labgo.CreatePathFromReader(e.Node, e.Listener, e.Experiment.Path, path, reader)
 					if err != nil {
 						dialog.ShowError(err, e.Window)
 						return
 					}
-					e.SelectPath(id, path)
+					e.SelectPath(id, path...)
+				}, e.Window)
+			}),
+			fyne.NewMenuItem("Import Directory", func() {
+				fmt.Println("Menu File->Import Directory")
+				directory := widget.NewEntry()
+				directory.SetPlaceHolder("/path/to/directory")
+				exclude := widget.NewMultiLineEntry()
+				exclude.SetPlaceHolder("*.log\nbuild/")
+				form := widget.NewForm(
+					widget.NewFormItem("Directory", directory),
+					widget.NewFormItem("Exclude", exclude),
+				)
+				dialog.ShowCustomConfirm("Import Directory", "Import", "Cancel", form, func(b bool) {
+					if !b {
+						return
+					}
+					go e.ImportDirectory(directory.Text, exclude.Text)
 				}, e.Window)
 			}),
 			fyne.NewMenuItem("Export", func() {
@@ -188,8 +827,39 @@ func (e *Experiment) MainMenu() *fyne.MainMenu {
 			fyne.NewMenuItemSeparator(),
 			fyne.NewMenuItem("Settings", func() {
 				fmt.Println("Menu Settings")
+				labels := map[string]string{
+					"Deltas":                     edit.FORMAT_DELTA,
+					"Sequence (Converts Deltas)": edit.FORMAT_SEQUENCE,
+				}
+				format := widget.NewRadio([]string{"Deltas", "Sequence (Converts Deltas)"}, nil)
+				for label, f := range labels {
+					if f == e.Format {
+						format.SetSelected(label)
+					}
+				}
+				dialog.ShowCustomConfirm("Settings", "Save", "Cancel", widget.NewForm(widget.NewFormItem("Document Format", format)), func(b bool) {
+					if !b {
+						return
+					}
+					if f, ok := labels[format.Selected]; ok && f != e.Format {
+						go e.SetFormat(f)
+					}
+				}, e.Window)
 			})),
 		fyne.NewMenu("Edit",
+			fyne.NewMenuItem("Undo", func() {
+				ui.ShortcutFocused(&desktop.CustomShortcut{
+					KeyName:  fyne.KeyZ,
+					Modifier: desktop.ControlModifier,
+				}, e.Window)
+			}),
+			fyne.NewMenuItem("Redo", func() {
+				ui.ShortcutFocused(&desktop.CustomShortcut{
+					KeyName:  fyne.KeyZ,
+					Modifier: desktop.ControlModifier | desktop.ShiftModifier,
+				}, e.Window)
+			}),
+			fyne.NewMenuItemSeparator(),
 			fyne.NewMenuItem("Cut", func() {
 				ui.ShortcutFocused(&fyne.ShortcutCut{
 					Clipboard: e.Window.Clipboard(),
@@ -205,10 +875,16 @@ func (e *Experiment) MainMenu() *fyne.MainMenu {
 					Clipboard: e.Window.Clipboard(),
 				}, e.Window)
 			}),
+			fyne.NewMenuItem("Select All", func() {
+				ui.ShortcutFocused(&fyne.ShortcutSelectAll{}, e.Window)
+			}),
 			fyne.NewMenuItemSeparator(),
-			fyne.NewMenuItem("Find", func() {
-				fmt.Println("Menu Find")
-			})),
+			fyne.NewMenuItem("Find", e.ShowFind),
+			fyne.NewMenuItem("Find in Experiment", e.ShowSearch)),
+		fyne.NewMenu("View",
+			fyne.NewMenuItem("History", e.ToggleHistory),
+			fyne.NewMenuItem("Blame", e.ToggleBlame),
+			fyne.NewMenuItem("Line Numbers", e.ToggleLineNumbers)),
 		fyne.NewMenu("Help", fyne.NewMenuItem("Help", func() {
 			fmt.Println("Help Menu")
 		})),
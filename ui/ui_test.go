@@ -19,6 +19,7 @@ package ui_test
 import (
 	"fyne.io/fyne"
 	"fyne.io/fyne/canvas"
+	"fyne.io/fyne/driver/desktop"
 	"fyne.io/fyne/layout"
 	"fyne.io/fyne/test"
 	"fyne.io/fyne/theme"
@@ -43,6 +44,39 @@ func Test_UI(t *testing.T) {
 				return e
 			},
 		},
+		"edit/editor_typed": {
+			builder: func(w fyne.Window) fyne.CanvasObject {
+				e := edit.NewEditor()
+				for _, r := range "Tset" {
+					e.TypedRune(r)
+				}
+				e.TypedKey(&fyne.KeyEvent{Name: fyne.KeyLeft})
+				e.TypedKey(&fyne.KeyEvent{Name: fyne.KeyLeft})
+				e.TypedKey(&fyne.KeyEvent{Name: fyne.KeyBackspace})
+				e.TypedKey(&fyne.KeyEvent{Name: fyne.KeyDelete})
+				e.TypedRune('e')
+				e.TypedRune('s')
+				e.TypedKey(&fyne.KeyEvent{Name: fyne.KeyEnd})
+				e.TypedKey(&fyne.KeyEvent{Name: fyne.KeyReturn})
+				for _, r := range "Typed" {
+					e.TypedRune(r)
+				}
+				return e
+			},
+		},
+		"edit/editor_selected": {
+			builder: func(w fyne.Window) fyne.CanvasObject {
+				e := edit.NewEditor()
+				e.SetText("Test\nSelected")
+				e.TypedKey(&fyne.KeyEvent{Name: fyne.KeyRight})
+				e.KeyDown(&fyne.KeyEvent{Name: desktop.KeyShiftLeft})
+				for i := 0; i < 8; i++ {
+					e.TypedKey(&fyne.KeyEvent{Name: fyne.KeyRight})
+				}
+				e.KeyUp(&fyne.KeyEvent{Name: desktop.KeyShiftLeft})
+				return e
+			},
+		},
 		"edit/delta_editor": {
 			builder: func(w fyne.Window) fyne.CanvasObject {
 				e := edit.NewDeltaEditor(nil)
@@ -50,9 +84,22 @@ func Test_UI(t *testing.T) {
 				return e
 			},
 		},
+		"edit/delta_editor_typed": {
+			builder: func(w fyne.Window) fyne.CanvasObject {
+				e := edit.NewDeltaEditor(nil)
+				for _, r := range "Tesst" {
+					e.TypedRune(r)
+				}
+				e.TypedKey(&fyne.KeyEvent{Name: fyne.KeyBackspace})
+				e.TypedKey(&fyne.KeyEvent{Name: fyne.KeyBackspace})
+				e.TypedRune('t')
+				e.Flush()
+				return e
+			},
+		},
 		"edit/channel_editor": {
 			builder: func(w fyne.Window) fyne.CanvasObject {
-				e := edit.NewChannelEditor(nil, nil, nil)
+				e := edit.NewChannelEditor(nil, nil, nil, nil)
 				e.SetText("Test")
 				return e
 			},